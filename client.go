@@ -2,179 +2,656 @@
 package gcal
 
 import (
+	"container/heap"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"net/http"
+	"os"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"golang.org/x/oauth2"
 	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/googleapi"
 	"google.golang.org/api/option"
 )
 
 // Event status constants
 const (
-	eventStatusCancelled   = "cancelled"
-	responseStatusAccepted = "accepted"
+	eventStatusCancelled      = "cancelled"
+	eventStatusTentative      = "tentative"
+	responseStatusAccepted    = "accepted"
+	responseStatusDeclined    = "declined"
+	responseStatusTentative   = "tentative"
+	responseStatusNeedsAction = "needsAction"
+	eventTypeOutOfOffice      = "outOfOffice"
 )
 
-// Meeting URL patterns
-var meetingPatterns = []*regexp.Regexp{
-	regexp.MustCompile(`https://[a-z0-9.-]*zoom\.us/[^\s<>"]+`),
-	regexp.MustCompile(`https://meet\.google\.com/[a-z0-9-]+`),
-	regexp.MustCompile(`https://teams\.microsoft\.com/[^\s<>"]+`),
-	regexp.MustCompile(`https://[a-z0-9.-]*webex\.com/[^\s<>"]+`),
+// accessRoleFreeBusyReader is the access role of calendars shared with
+// free/busy access only, whose events come back without details
+const accessRoleFreeBusyReader = "freeBusyReader"
+
+// busyPlaceholderTitle is the title given to events from free/busy-only
+// calendars
+const busyPlaceholderTitle = "Busy"
+
+// FetchConcurrencyEnv names the environment variable that caps how many
+// calendars a fetch lists at once, see Client.FetchConcurrency
+const FetchConcurrencyEnv = "GCAL_FETCH_CONCURRENCY"
+
+// maxEventPages caps the result pages read per calendar or recurring event,
+// so a server that keeps returning page tokens can't hang a fetch. At the
+// API's default of 250 events a page this is far beyond any real calendar.
+const maxEventPages = 100
+
+// UpcomingHoursEnv names the environment variable that sets the default
+// window for upcoming events, see LoadDefaultUpcomingHours
+const UpcomingHoursEnv = "GCAL_UPCOMING_HOURS"
+
+// DefaultUpcomingHours is the upcoming events window, in hours, used when
+// GCAL_UPCOMING_HOURS is unset
+const DefaultUpcomingHours = 48
+
+// defaultFetchConcurrency is the fetch concurrency when neither
+// Client.FetchConcurrency nor GCAL_FETCH_CONCURRENCY is set
+const defaultFetchConcurrency = 4
+
+// Meeting URL patterns, with the provider each identifies
+var meetingPatterns = []struct {
+	provider string
+	pattern  *regexp.Regexp
+}{
+	{"zoom", regexp.MustCompile(`https://[a-z0-9.-]*zoom\.us/[^\s<>"]+`)},
+	{"meet", regexp.MustCompile(`https://meet\.google\.com/[a-z0-9-]+`)},
+	{"teams", regexp.MustCompile(`https://teams\.microsoft\.com/[^\s<>"]+`)},
+	{"webex", regexp.MustCompile(`https://[a-z0-9.-]*webex\.com/[^\s<>"]+`)},
+}
+
+// Client fetches calendar data through an authenticated Calendar API service
+type Client struct {
+	srv *calendar.Service
+
+	// Options controls filtering and annotation of fetched events
+	Options FetchOptions
+
+	// Retry controls retries of transient failures, shared across a fetch
+	Retry RetryPolicy
+
+	// CalendarOptions controls the order of ListCalendars results
+	CalendarOptions CalendarListOptions
+
+	// FetchConcurrency caps how many calendars a fetch lists at once. Zero
+	// uses GCAL_FETCH_CONCURRENCY, or 4 if that's unset or invalid.
+	FetchConcurrency int
+}
+
+// NewClient creates a Client from the saved credentials and token
+func NewClient(ctx context.Context) (*Client, error) {
+	c, _, err := newDefaultClient(ctx)
+	return c, err
+}
+
+// NewClientWithService creates a Client that uses an existing Calendar service
+func NewClientWithService(srv *calendar.Service) *Client {
+	return &Client{
+		srv:             srv,
+		Options:         DefaultFetchOptions(),
+		CalendarOptions: DefaultCalendarListOptions(),
+	}
+}
+
+// NewClientWithToken creates a Client from in-memory credentials and token,
+// without reading or writing the gcal config and data directories. This lets
+// applications that already hold OAuth tokens embed gcal. The token is
+// refreshed as needed; pass WithTokenPersister to store refreshed tokens,
+// otherwise they are kept in memory only.
+func NewClientWithToken(ctx context.Context, creds *Credentials, token *oauth2.Token, opts ...AuthOption) (*Client, error) {
+	return newClientWithToken(ctx, creds, token, opts)
+}
+
+// newClientWithToken is NewClientWithToken with extra service options, which
+// tests use to point the service at a fake endpoint
+func newClientWithToken(ctx context.Context, creds *Credentials, token *oauth2.Token, opts []AuthOption, svcOpts ...option.ClientOption) (*Client, error) {
+	if creds == nil || creds.ClientID == "" || creds.ClientSecret == "" {
+		return nil, fmt.Errorf("%s: credentials missing clientId or clientSecret", ErrNotConfigured)
+	}
+	if token == nil {
+		return nil, fmt.Errorf("%s: no token provided", ErrNotConfigured)
+	}
+
+	o := newAuthOptions(nil, opts)
+	config := o.oauthConfig(creds, DefaultCallbackPort)
+	httpClient := oauth2.NewClient(ctx, newPersistingTokenSource(ctx, config, token, o.persister))
+
+	svcOpts = append([]option.ClientOption{option.WithHTTPClient(httpClient)}, svcOpts...)
+	srv, err := calendar.NewService(ctx, svcOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create calendar service: %w", err)
+	}
+
+	return NewClientWithService(srv), nil
+}
+
+// newDefaultClient creates a Client from the saved credentials and token,
+// also returning the error code to report if that fails
+func newDefaultClient(ctx context.Context) (*Client, string, error) {
+	httpClient, err := GetClient(ctx)
+	if err != nil {
+		return nil, ErrNotConfigured, err
+	}
+
+	srv, err := calendar.NewService(ctx, option.WithHTTPClient(httpClient))
+	if err != nil {
+		return nil, ErrAPIError, fmt.Errorf("failed to create calendar service: %w", err)
+	}
+
+	return NewClientWithService(srv), "", nil
 }
 
 // FetchTodayEvents fetches today's calendar events and returns structured response
 func FetchTodayEvents(ctx context.Context, calendarIDs []string) Response {
-	client, err := GetClient(ctx)
+	c, code, err := newDefaultClient(ctx)
 	if err != nil {
-		return NewErrorResponse(ErrNotConfigured, err.Error())
+		return NewErrorResponse(code, err.Error())
+	}
+	return c.FetchTodayEvents(ctx, calendarIDs)
+}
+
+// LoadDefaultUpcomingHours returns the hours to pass to FetchUpcomingEvents
+// when none are given: GCAL_UPCOMING_HOURS if set, else
+// DefaultUpcomingHours. A value that isn't a positive integer is an
+// ErrInvalidConfig error rather than being silently ignored.
+func LoadDefaultUpcomingHours() (int, error) {
+	value := os.Getenv(UpcomingHoursEnv)
+	if value == "" {
+		return DefaultUpcomingHours, nil
+	}
+	hours, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || hours <= 0 {
+		return 0, fmt.Errorf("%s: %s=%q, want a positive number of hours", ErrInvalidConfig, UpcomingHoursEnv, value)
 	}
+	return hours, nil
+}
 
-	srv, err := calendar.NewService(ctx, option.WithHTTPClient(client))
+// FetchUpcomingEvents fetches events within the next N hours
+func FetchUpcomingEvents(ctx context.Context, calendarIDs []string, hours int) Response {
+	c, code, err := newDefaultClient(ctx)
 	if err != nil {
-		return NewErrorResponse(ErrAPIError, "failed to create calendar service: "+err.Error())
+		return NewErrorResponse(code, err.Error())
 	}
+	return c.FetchUpcomingEvents(ctx, calendarIDs, hours)
+}
 
+// FetchEventsInRange fetches events between start and end
+func FetchEventsInRange(ctx context.Context, calendarIDs []string, start, end time.Time) Response {
+	c, code, err := newDefaultClient(ctx)
+	if err != nil {
+		return NewErrorResponse(code, err.Error())
+	}
+	return c.FetchEventsInRange(ctx, calendarIDs, start, end)
+}
+
+// ListCalendars returns all calendars the user has access to
+func ListCalendars(ctx context.Context) CalendarsResponse {
+	c, code, err := newDefaultClient(ctx)
+	if err != nil {
+		return CalendarsResponse{
+			Success: false,
+			Error:   code,
+			Message: err.Error(),
+		}
+	}
+	return c.ListCalendars(ctx)
+}
+
+// FetchTodayEvents fetches today's calendar events and returns structured response
+func (c *Client) FetchTodayEvents(ctx context.Context, calendarIDs []string) Response {
+	// Get today's time range in the configured timezone
+	now := time.Now().In(c.Options.location())
+	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	endOfDay := startOfDay.AddDate(0, 0, 1)
+
+	return c.fetchEvents(ctx, calendarIDs, startOfDay, endOfDay)
+}
+
+// FetchUpcomingEvents fetches events within the next N hours
+func (c *Client) FetchUpcomingEvents(ctx context.Context, calendarIDs []string, hours int) Response {
+	now := time.Now()
+	endTime := now.Add(time.Duration(hours) * time.Hour)
+
+	return c.fetchEvents(ctx, calendarIDs, now, endTime)
+}
+
+// FetchEventsInRange fetches events overlapping [start, end), e.g. this
+// week or next Monday. end must be after start.
+func (c *Client) FetchEventsInRange(ctx context.Context, calendarIDs []string, start, end time.Time) Response {
+	if !end.After(start) {
+		return NewErrorResponse(ErrAPIError, fmt.Sprintf("invalid range: end %s is not after start %s",
+			end.Format(time.RFC3339), start.Format(time.RFC3339)))
+	}
+	return c.fetchEvents(ctx, calendarIDs, start, end)
+}
+
+// fetchEvents lists, converts, sorts and conflict-checks events between
+// timeMin and timeMax across the given calendars
+func (c *Client) fetchEvents(ctx context.Context, calendarIDs []string, timeMin, timeMax time.Time) Response {
 	// Default to primary calendar
 	if len(calendarIDs) == 0 {
+		if c.Options.StrictCalendarIDs {
+			return NewErrorResponse(ErrNotConfigured, "no calendar IDs given - pass calendar IDs explicitly (strict mode is on), or use \"primary\"")
+		}
 		calendarIDs = []string{"primary"}
 	}
 
-	// Get today's time range in local timezone
-	now := time.Now()
-	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
-	endOfDay := startOfDay.Add(24 * time.Hour)
+	normalized := make([]string, len(calendarIDs))
+	for i, id := range calendarIDs {
+		normalized[i] = NormalizeCalendarID(id)
+		if normalized[i] == "" {
+			return NewErrorResponse(ErrInvalidConfig, fmt.Sprintf("calendar ID %q is empty - pass an email-style ID or \"primary\"", id))
+		}
+	}
+	calendarIDs = normalized
+
+	orderBy, singleEvents, err := c.Options.listOrder()
+	if err != nil {
+		return NewErrorResponse(ErrInvalidConfig, err.Error())
+	}
+	if err := c.Options.validateLunchWindow(); err != nil {
+		return NewErrorResponse(ErrInvalidConfig, err.Error())
+	}
 
 	var allEvents []Event
 	var errors []string
+	primaryNotFound := false
+	budget := c.Retry.newBudget(time.Now())
 
-	for _, calID := range calendarIDs {
-		events, err := srv.Events.List(calID).
-			TimeMin(startOfDay.Format(time.RFC3339)).
-			TimeMax(endOfDay.Format(time.RFC3339)).
-			SingleEvents(true).
-			OrderBy("startTime").
-			Do()
+	// Fetch calendars concurrently, keeping results in calendarIDs order
+	results := make([]calendarResult, len(calendarIDs))
+	sem := make(chan struct{}, c.fetchConcurrency())
+	var wg sync.WaitGroup
+	for i, calID := range calendarIDs {
+		wg.Add(1)
+		go func(i int, calID string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
 
-		if err != nil {
-			// Collect errors but continue with other calendars
-			errors = append(errors, fmt.Sprintf("calendar %s: %v", calID, err))
-			continue
-		}
+			results[i].events, results[i].err = c.fetchCalendar(ctx, budget, calID, timeMin, timeMax, orderBy, singleEvents)
+		}(i, calID)
+	}
+	wg.Wait()
 
-		if events.Items != nil {
-			for _, item := range events.Items {
-				event := convertEvent(item)
-				if event != nil {
-					allEvents = append(allEvents, *event)
-				}
+	for i, result := range results {
+		calID := calendarIDs[i]
+		if result.err != nil {
+			if calID == "primary" && isNotFound(result.err) {
+				primaryNotFound = true
 			}
+			// Collect errors but continue with other calendars
+			errors = append(errors, fmt.Sprintf("calendar %s: %v", calID, result.err))
+			continue
 		}
+		allEvents = append(allEvents, result.events...)
 	}
 
 	// Log errors if any occurred (but don't fail if we got some events)
 	if len(errors) > 0 && len(allEvents) == 0 {
+		// Accounts without a primary calendar (e.g. resource-only accounts)
+		// 404 on the default, which reads as a confusing API failure
+		if primaryNotFound {
+			return NewErrorResponse(ErrNotConfigured, "primary calendar is not accessible for this account - run 'gcal calendars' and pass an explicit calendar ID")
+		}
 		// If we got no events and had errors, return an error response
 		return NewErrorResponse(ErrAPIError, fmt.Sprintf("failed to fetch events: %s", strings.Join(errors, "; ")))
 	}
 
-	// Sort by start time (stable sort to preserve order of events with same start time)
-	sort.SliceStable(allEvents, func(i, j int) bool {
-		return allEvents[i].Start < allEvents[j].Start
-	})
+	annotateEvents(allEvents, c.Options)
+	if c.Options.CollapseRecurring {
+		allEvents = collapseRecurring(allEvents)
+	}
+	if c.Options.Descending {
+		// Only the output order changes; conflicts and gaps were computed
+		// in chronological order above
+		reverseEvents(allEvents)
+	}
 
-	// Detect conflicts
-	detectConflicts(allEvents)
+	if c.Options.IncludeCalendarNames && len(allEvents) > 0 {
+		// One list call per fetch, however many calendars were queried
+		names := c.calendarNames(ctx)
+		for i := range allEvents {
+			allEvents[i].CalendarName = names[allEvents[i].CalendarID]
+		}
+	}
+
+	if c.Options.AnonymizeCalendarIDs {
+		for i := range allEvents {
+			allEvents[i].CalendarID = anonymizeCalendarID(allEvents[i].CalendarID)
+		}
+	}
 
 	return NewSuccessResponse(allEvents)
 }
 
-// FetchUpcomingEvents fetches events within the next N hours
-func FetchUpcomingEvents(ctx context.Context, calendarIDs []string, hours int) Response {
-	client, err := GetClient(ctx)
-	if err != nil {
-		return NewErrorResponse(ErrNotConfigured, err.Error())
+// ListInstances returns the occurrences of the recurring event eventID that
+// fall between start and end, following all result pages. Unlike the fetch
+// methods it applies no filtering, so every occurrence is counted.
+func (c *Client) ListInstances(ctx context.Context, calendarID, eventID string, start, end time.Time) ([]Event, error) {
+	if calendarID == "" {
+		calendarID = "primary"
 	}
 
-	srv, err := calendar.NewService(ctx, option.WithHTTPClient(client))
-	if err != nil {
-		return NewErrorResponse(ErrAPIError, "failed to create calendar service: "+err.Error())
+	budget := c.Retry.newBudget(time.Now())
+	var events []Event
+	pageToken := ""
+	for pages := 0; ; pages++ {
+		if pages == maxEventPages {
+			return nil, fmt.Errorf("%s: more than %d pages of instances of %s, narrow the time range", ErrAPIError, maxEventPages, eventID)
+		}
+		call := c.srv.Events.Instances(calendarID, eventID).
+			TimeMin(start.Format(time.RFC3339)).
+			TimeMax(end.Format(time.RFC3339))
+		if pageToken != "" {
+			call = call.PageToken(pageToken)
+		}
+
+		var page *calendar.Events
+		err := budget.do(ctx, func() error {
+			var err error
+			page, err = call.Context(ctx).Do()
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%s: failed to list instances of %s: %w", ErrAPIError, eventID, err)
+		}
+
+		for _, item := range page.Items {
+			if item.Status == eventStatusCancelled {
+				continue
+			}
+			events = append(events, eventFromAPI(item, calendarID))
+		}
+
+		if page.NextPageToken == "" {
+			return events, nil
+		}
+		pageToken = page.NextPageToken
 	}
+}
 
-	if len(calendarIDs) == 0 {
-		calendarIDs = []string{"primary"}
+// annotateEvents sorts events by start time and sets HasConflict and
+// GapBeforeMinutes across the whole set
+func annotateEvents(events []Event, opts FetchOptions) {
+	// Sort by start time (stable sort to preserve order of events with same start time)
+	loc := opts.location()
+	sort.SliceStable(events, func(i, j int) bool {
+		return startsBefore(events[i], events[j], loc)
+	})
+
+	if opts.ZeroDurationMarkers {
+		markZeroDuration(events)
 	}
 
-	now := time.Now()
-	endTime := now.Add(time.Duration(hours) * time.Hour)
+	// Detect conflicts
+	detectConflicts(events, opts)
+	markDuringOOO(events, opts.location())
+	if opts.FlagLunchMeetings {
+		lunchStart, lunchEnd := opts.lunchWindow()
+		markDuringLunch(events, lunchStart, lunchEnd, opts.location())
+	}
+	computeGaps(events, opts.location())
+}
 
-	var allEvents []Event
-	var errors []string
+// startsBefore reports whether a starts before b. Times are compared as
+// instants, anchoring all-day dates in loc, since all-day dates and
+// RFC3339 times with different offsets don't sort correctly as strings.
+// Unparseable starts, such as tombstones', sort first, by string.
+func startsBefore(a, b Event, loc *time.Location) bool {
+	ta, errA := parseEventTime(a.Start, loc)
+	tb, errB := parseEventTime(b.Start, loc)
+	switch {
+	case errA != nil && errB != nil:
+		return a.Start < b.Start
+	case errA != nil || errB != nil:
+		return errA != nil
+	}
+	return ta.Before(tb)
+}
+
+// calendarResult is the outcome of fetching one calendar's events
+type calendarResult struct {
+	events []Event
+	err    error
+}
+
+// fetchCalendar lists and converts one calendar's events between timeMin
+// and timeMax, applying the per-event filters
+func (c *Client) fetchCalendar(ctx context.Context, budget *retryBudget, calID string, timeMin, timeMax time.Time, orderBy string, singleEvents bool) ([]Event, error) {
+	call := c.srv.Events.List(calID).
+		TimeMin(timeMin.Format(time.RFC3339)).
+		TimeMax(timeMax.Format(time.RFC3339)).
+		SingleEvents(singleEvents)
+	if orderBy != "" {
+		call = call.OrderBy(orderBy)
+	}
+	if len(c.Options.SharedExtendedPropertyFilters) > 0 {
+		call = call.SharedExtendedProperty(c.Options.SharedExtendedPropertyFilters...)
+	}
+	if c.Options.MaxAttendeesFetched > 0 {
+		call = call.MaxAttendees(int64(c.Options.MaxAttendeesFetched))
+	}
+	if c.Options.ShowDeleted {
+		call = call.ShowDeleted(true)
+	}
+	if c.Options.ICalUIDFilter != "" {
+		call = call.ICalUID(c.Options.ICalUIDFilter)
+	}
 
-	for _, calID := range calendarIDs {
-		events, err := srv.Events.List(calID).
-			TimeMin(now.Format(time.RFC3339)).
-			TimeMax(endTime.Format(time.RFC3339)).
-			SingleEvents(true).
-			OrderBy("startTime").
-			Do()
+	var items []*calendar.Event
+	var accessRole string
+	pageToken := ""
+	for pages := 0; ; pages++ {
+		if pages == maxEventPages {
+			return nil, fmt.Errorf("more than %d pages of events, narrow the time range", maxEventPages)
+		}
+		if pageToken != "" {
+			call = call.PageToken(pageToken)
+		}
 
+		var page *calendar.Events
+		err := budget.do(ctx, func() error {
+			var err error
+			page, err = call.Context(ctx).Do()
+			return err
+		})
 		if err != nil {
-			// Collect errors but continue with other calendars
-			errors = append(errors, fmt.Sprintf("calendar %s: %v", calID, err))
-			continue
+			return nil, err
 		}
 
-		if events.Items != nil {
-			for _, item := range events.Items {
-				event := convertEvent(item)
-				if event != nil {
-					allEvents = append(allEvents, *event)
-				}
-			}
+		items = append(items, page.Items...)
+		accessRole = page.AccessRole
+		if page.NextPageToken == "" {
+			break
 		}
+		pageToken = page.NextPageToken
 	}
 
-	// Log errors if any occurred (but don't fail if we got some events)
-	if len(errors) > 0 && len(allEvents) == 0 {
-		// If we got no events and had errors, return an error response
-		return NewErrorResponse(ErrAPIError, fmt.Sprintf("failed to fetch events: %s", strings.Join(errors, "; ")))
+	var converted []Event
+	for _, item := range items {
+		if c.Options.ShowDeleted && item.Status == eventStatusCancelled {
+			converted = append(converted, Event{
+				ID:         item.Id,
+				RawID:      item.Id,
+				ICalUID:    item.ICalUID,
+				CalendarID: calID,
+				Deleted:    true,
+			})
+			continue
+		}
+		if c.Options.ModifiedBy != "" && !strings.EqualFold(lastModifiedBy(item), c.Options.ModifiedBy) {
+			continue
+		}
+		var event *Event
+		if accessRole == accessRoleFreeBusyReader {
+			event = busyPlaceholder(item, c.Options)
+		} else {
+			event = convertEvent(item, c.Options)
+		}
+		if event != nil && c.Options.keepTitle(event.Title) {
+			event.CalendarID = calID
+			converted = append(converted, *event)
+		}
 	}
+	return converted, nil
+}
 
-	sort.SliceStable(allEvents, func(i, j int) bool {
-		return allEvents[i].Start < allEvents[j].Start
-	})
-
-	detectConflicts(allEvents)
+// fetchConcurrency returns how many calendars a fetch lists at once:
+// FetchConcurrency if set, else GCAL_FETCH_CONCURRENCY, else the default
+func (c *Client) fetchConcurrency() int {
+	if c.FetchConcurrency > 0 {
+		return c.FetchConcurrency
+	}
+	return envFetchConcurrency()
+}
 
-	return NewSuccessResponse(allEvents)
+// envFetchConcurrency reads GCAL_FETCH_CONCURRENCY, falling back to
+// defaultFetchConcurrency if it's unset or isn't a positive integer
+func envFetchConcurrency() int {
+	n, err := strconv.Atoi(strings.TrimSpace(os.Getenv(FetchConcurrencyEnv)))
+	if err != nil || n <= 0 {
+		return defaultFetchConcurrency
+	}
+	return n
 }
 
-// ListCalendars returns all calendars the user has access to
-func ListCalendars(ctx context.Context) CalendarsResponse {
-	client, err := GetClient(ctx)
-	if err != nil {
-		return CalendarsResponse{
-			Success: false,
-			Error:   ErrNotConfigured,
-			Message: err.Error(),
+// collapseRecurring keeps the first instance of each recurring series in
+// sorted events, counting the instances on it. One-off events are kept
+// as-is.
+func collapseRecurring(events []Event) []Event {
+	firstIndex := make(map[string]int)
+	collapsed := events[:0]
+	for _, e := range events {
+		if e.RecurringEventID == "" {
+			collapsed = append(collapsed, e)
+			continue
+		}
+		if i, ok := firstIndex[e.RecurringEventID]; ok {
+			collapsed[i].OccurrenceCount++
+			continue
 		}
+		firstIndex[e.RecurringEventID] = len(collapsed)
+		e.OccurrenceCount = 1
+		collapsed = append(collapsed, e)
 	}
+	return collapsed
+}
 
-	srv, err := calendar.NewService(ctx, option.WithHTTPClient(client))
-	if err != nil {
-		return CalendarsResponse{
-			Success: false,
-			Error:   ErrAPIError,
-			Message: "failed to create calendar service: " + err.Error(),
+// MergeResponses combines two responses, e.g. from separate profiles, into
+// one, recomputing annotations with DefaultFetchOptions. See
+// MergeResponsesWithOptions.
+func MergeResponses(a, b Response) Response {
+	return MergeResponsesWithOptions(a, b, DefaultFetchOptions())
+}
+
+// MergeResponsesWithOptions combines two responses into one. Events are
+// concatenated and every annotation (conflicts, gaps, and the out-of-office,
+// lunch and marker flags) is recomputed across the union with opts, which
+// should be the options both responses were fetched with. Events are in
+// start order, or latest first with opts.Descending. The result succeeds
+// only if both did; otherwise it carries the first error code, all error
+// messages, and the events of whichever response succeeded. LastSync is the
+// older of the two, since the merged data is only as fresh as its oldest
+// part. The inputs are not modified.
+func MergeResponsesWithOptions(a, b Response, opts FetchOptions) Response {
+	merged := Response{Success: a.Success && b.Success}
+
+	var messages []string
+	for _, r := range []Response{a, b} {
+		if r.Success {
+			merged.Events = append(merged.Events, r.Events...)
+			merged.LastSync = olderSync(merged.LastSync, r.LastSync)
+			continue
 		}
+		if merged.Error == "" {
+			merged.Error = r.Error
+		}
+		if r.Message != "" {
+			messages = append(messages, r.Message)
+		}
+	}
+	merged.Message = strings.Join(messages, "; ")
+
+	for i := range merged.Events {
+		clearAnnotations(&merged.Events[i])
+	}
+	annotateEvents(merged.Events, opts)
+	if opts.Descending {
+		reverseEvents(merged.Events)
+	}
+	return merged
+}
+
+// clearAnnotations resets the fields annotateEvents derives, so they can
+// be recomputed under different options
+func clearAnnotations(e *Event) {
+	e.HasConflict = false
+	e.ConflictsWith = nil
+	e.Marker = false
+	e.DuringOOO = false
+	e.DuringLunch = false
+	e.GapBeforeMinutes = 0
+}
+
+// reverseEvents reverses events in place
+func reverseEvents(events []Event) {
+	for i, j := 0, len(events)-1; i < j; i, j = i+1, j-1 {
+		events[i], events[j] = events[j], events[i]
+	}
+}
+
+// olderSync returns the earlier of two RFC3339 sync times, ignoring empty
+// or unparseable values
+func olderSync(a, b string) string {
+	ta, errA := time.Parse(time.RFC3339, a)
+	tb, errB := time.Parse(time.RFC3339, b)
+	switch {
+	case errA != nil:
+		return b
+	case errB != nil:
+		return a
+	case tb.Before(ta):
+		return b
+	default:
+		return a
+	}
+}
+
+// NormalizeCalendarID cleans up a pasted calendar ID, trimming whitespace
+// and a "mailto:" prefix. It returns "" if nothing is left.
+func NormalizeCalendarID(id string) string {
+	id = strings.TrimSpace(id)
+	if len(id) >= len("mailto:") && strings.EqualFold(id[:len("mailto:")], "mailto:") {
+		id = strings.TrimSpace(id[len("mailto:"):])
 	}
+	return id
+}
 
-	list, err := srv.CalendarList.List().Do()
+// anonymizeCalendarID returns a stable, non-reversible stand-in for a
+// calendar ID. The same ID always maps to the same value.
+func anonymizeCalendarID(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return "cal-" + hex.EncodeToString(sum[:4])
+}
+
+// ListCalendars returns all calendars the user has access to
+func (c *Client) ListCalendars(ctx context.Context) CalendarsResponse {
+	list, err := c.srv.CalendarList.List().Context(ctx).Do()
 	if err != nil {
 		return CalendarsResponse{
 			Success: false,
@@ -185,12 +662,9 @@ func ListCalendars(ctx context.Context) CalendarsResponse {
 
 	var calendars []CalendarInfo
 	for _, item := range list.Items {
-		calendars = append(calendars, CalendarInfo{
-			ID:      item.Id,
-			Summary: item.Summary,
-			Primary: item.Primary,
-		})
+		calendars = append(calendars, convertCalendar(item))
 	}
+	sortCalendars(calendars, c.CalendarOptions)
 
 	return CalendarsResponse{
 		Success:   true,
@@ -198,25 +672,108 @@ func ListCalendars(ctx context.Context) CalendarsResponse {
 	}
 }
 
+// calendarNames maps calendar IDs, and "primary", to display names. It's
+// best-effort: nil is returned if the calendar list can't be fetched.
+func (c *Client) calendarNames(ctx context.Context) map[string]string {
+	resp := c.ListCalendars(ctx)
+	if !resp.Success {
+		return nil
+	}
+
+	names := make(map[string]string, len(resp.Calendars)+1)
+	for _, cal := range resp.Calendars {
+		names[cal.ID] = cal.DisplayName()
+		if cal.Primary {
+			names["primary"] = cal.DisplayName()
+		}
+	}
+	return names
+}
+
+// sortCalendars orders calendars in place according to opts. The sort is
+// stable, so calendars that compare equal keep their API order.
+func sortCalendars(calendars []CalendarInfo, opts CalendarListOptions) {
+	sort.SliceStable(calendars, func(i, j int) bool {
+		a, b := calendars[i], calendars[j]
+		if opts.PrimaryFirst && a.Primary != b.Primary {
+			return a.Primary
+		}
+		if opts.SortBy == CalendarSortName {
+			return strings.ToLower(a.DisplayName()) < strings.ToLower(b.DisplayName())
+		}
+		return false
+	})
+}
+
+// lastModifiedBy approximates the email of whoever last modified an event.
+// The API doesn't expose the last modifier, so the organizer is used.
+func lastModifiedBy(item *calendar.Event) string {
+	if item.Organizer == nil {
+		return ""
+	}
+	return item.Organizer.Email
+}
+
+// isNotFound reports whether err is a Google API 404 or 410 response
+func isNotFound(err error) bool {
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Code == http.StatusNotFound || apiErr.Code == http.StatusGone
+	}
+	return false
+}
+
+// convertCalendar converts a calendar list entry to our CalendarInfo type
+func convertCalendar(item *calendar.CalendarListEntry) CalendarInfo {
+	return CalendarInfo{
+		ID:              item.Id,
+		Summary:         item.Summary,
+		SummaryOverride: item.SummaryOverride,
+		Description:     item.Description,
+		Primary:         item.Primary,
+	}
+}
+
 // convertEvent converts a Google Calendar event to our Event type.
 // It filters out cancelled events, all-day events, events without attendees,
-// and events not accepted by the user.
-func convertEvent(item *calendar.Event) *Event {
-	// Skip cancelled events
+// and events not accepted by the user, subject to opts.
+func convertEvent(item *calendar.Event, opts FetchOptions) *Event {
+	// Skip cancelled events, and tentative ones when asked
 	if item.Status == eventStatusCancelled {
 		return nil
 	}
+	if opts.ExcludeTentative && item.Status == eventStatusTentative {
+		return nil
+	}
 
-	// Skip all-day events (no dateTime, only date)
-	if item.Start.DateTime == "" {
+	// Skip all-day events (no dateTime, only date) unless asked for
+	allDay := item.Start.DateTime == ""
+	if allDay && !opts.IncludeAllDay {
 		return nil
 	}
 
 	event := &Event{
-		ID:    item.Id,
-		Title: item.Summary,
-		Start: item.Start.DateTime,
-		End:   item.End.DateTime,
+		ID:               item.Id,
+		RawID:            item.Id,
+		ICalUID:          item.ICalUID,
+		Title:            item.Summary,
+		Description:      item.Description,
+		Location:         item.Location,
+		Start:            item.Start.DateTime,
+		End:              item.End.DateTime,
+		Status:           item.Status,
+		RecurringEventID: item.RecurringEventId,
+	}
+	setOrganizer(event, item)
+
+	// Out-of-office blocks have no attendees; keep them, when asked, so
+	// meetings booked over them can be flagged
+	if item.EventType == eventTypeOutOfOffice {
+		if !opts.IncludeOutOfOffice {
+			return nil
+		}
+		event.EventType = eventTypeOutOfOffice
+		return event
 	}
 
 	// Extract attendees
@@ -224,79 +781,504 @@ func convertEvent(item *calendar.Event) *Event {
 		for _, attendee := range item.Attendees {
 			if attendee.Self {
 				event.ResponseStatus = attendee.ResponseStatus
+				event.SelfComment = attendee.Comment
 			} else if attendee.Email != "" {
 				event.Attendees = append(event.Attendees, attendee.DisplayName)
 				if event.Attendees[len(event.Attendees)-1] == "" {
 					event.Attendees[len(event.Attendees)-1] = attendee.Email
 				}
+				event.AttendeeDetails = append(event.AttendeeDetails, Attendee{
+					Name:           attendee.DisplayName,
+					Email:          attendee.Email,
+					ResponseStatus: attendee.ResponseStatus,
+					Comment:        attendee.Comment,
+				})
 			}
 		}
 	}
 	event.AttendeeCount = len(event.Attendees)
 
+	if allDay {
+		event.AllDay = true
+		event.Start = item.Start.Date
+		event.End = item.End.Date
+	}
+
 	// Skip events without attendees (personal events, focus time, etc.)
-	if event.AttendeeCount == 0 {
+	// unless asked for, though all-day blocks like vacations rarely have
+	// any. Bookings on a resource calendar are kept either way.
+	if event.AttendeeCount == 0 && !allDay && !opts.TreatAsResourceCalendar && !opts.IncludePersonal {
 		return nil
 	}
 
-	// Skip events not accepted by user
-	if event.ResponseStatus != responseStatusAccepted {
+	switch {
+	case opts.TreatAsResourceCalendar:
+		// A room has no RSVP of the user's to filter on
+	case event.AttendeeCount == 0:
+		// Personal events often have no self entry; only drop ones the
+		// user explicitly declined
+		if event.ResponseStatus != "" && !opts.keepResponseStatus(event.ResponseStatus) {
+			return nil
+		}
+	case opts.IncludeRemovedMeetings && wasRemovedFromMeeting(item):
+		// Other attendees but no self entry, on an event someone else
+		// organized, means the organizer removed the user from the guest list
+		event.RemovedFromMeeting = true
+	case !opts.keepResponseStatus(event.ResponseStatus):
+		// Skip events not accepted by user, unless asked for
+		return nil
+	}
+
+	// Extract meeting URLs, the first being the primary
+	event.AllMeetingURLs = extractAllMeetingURLs(item)
+	if len(event.AllMeetingURLs) > 0 {
+		event.MeetingURL = event.AllMeetingURLs[0]
+	}
+	if opts.RequireMeetingURL && event.MeetingURL == "" {
 		return nil
 	}
+	event.Provider = MeetingProvider(event.MeetingURL)
+	event.InternalMeeting = opts.isInternalMeetingURL(event.MeetingURL)
+
+	event.Reminders = itemReminders(item)
 
-	// Extract meeting URL
-	event.MeetingURL = extractMeetingURL(item)
+	if item.ExtendedProperties != nil && len(item.ExtendedProperties.Shared) > 0 {
+		event.SharedProperties = item.ExtendedProperties.Shared
+	}
+
+	event.LocationInfo = parseItemLocation(item)
+
+	return event
+}
+
+// setOrganizer copies the organizer of item, if any, onto event
+func setOrganizer(event *Event, item *calendar.Event) {
+	if item.Organizer == nil {
+		return
+	}
+	event.Organizer = item.Organizer.DisplayName
+	if event.Organizer == "" {
+		event.Organizer = item.Organizer.Email
+	}
+	event.OrganizerEmail = item.Organizer.Email
+	event.OrganizedByMe = item.Organizer.Self
+}
+
+// busyPlaceholder converts an event from a free/busy-only calendar, which
+// has times but no title, attendees or RSVP, so convertEvent would drop it.
+// Cancelled events are skipped, as are all-day ones unless opts include
+// them. With RequireMeetingURL every placeholder is skipped, since a
+// meeting URL can't be detected.
+func busyPlaceholder(item *calendar.Event, opts FetchOptions) *Event {
+	if item.Status == eventStatusCancelled || item.Start == nil || item.End == nil {
+		return nil
+	}
+	if opts.RequireMeetingURL {
+		return nil
+	}
+
+	event := &Event{
+		ID:              item.Id,
+		RawID:           item.Id,
+		Title:           item.Summary,
+		Start:           item.Start.DateTime,
+		End:             item.End.DateTime,
+		BusyPlaceholder: true,
+	}
+	if event.Title == "" {
+		event.Title = busyPlaceholderTitle
+	}
+	if item.Start.DateTime == "" {
+		if !opts.IncludeAllDay {
+			return nil
+		}
+		event.AllDay = true
+		event.Start = item.Start.Date
+		event.End = item.End.Date
+	}
+	return event
+}
+
+// wasRemovedFromMeeting reports whether an event with guests lists no self
+// attendee and wasn't organized by the user
+func wasRemovedFromMeeting(item *calendar.Event) bool {
+	if item.Organizer != nil && item.Organizer.Self {
+		return false
+	}
+	for _, attendee := range item.Attendees {
+		if attendee.Self {
+			return false
+		}
+	}
+	return len(item.Attendees) > 0
+}
+
+// parseItemLocation parses an API event's location, returning nil if unset
+func parseItemLocation(item *calendar.Event) *LocationInfo {
+	if strings.TrimSpace(item.Location) == "" {
+		return nil
+	}
+	info := ParseLocation(item.Location)
+	return &info
+}
+
+// itemReminders returns an API event's reminder overrides, or nil when the
+// calendar's defaults apply
+func itemReminders(item *calendar.Event) []Reminder {
+	if item.Reminders == nil || item.Reminders.UseDefault {
+		return nil
+	}
+	var reminders []Reminder
+	for _, r := range item.Reminders.Overrides {
+		reminders = append(reminders, Reminder{
+			Method:  r.Method,
+			Minutes: int(r.Minutes),
+		})
+	}
+	return reminders
+}
+
+// eventFromAPI converts an event the caller asked for by ID or just created.
+// Unlike convertEvent it applies no filtering, and all-day events keep their
+// bare dates.
+func eventFromAPI(item *calendar.Event, calendarID string) Event {
+	event := Event{
+		ID:               item.Id,
+		RawID:            item.Id,
+		ICalUID:          item.ICalUID,
+		Title:            item.Summary,
+		Description:      item.Description,
+		Location:         item.Location,
+		Status:           item.Status,
+		CalendarID:       calendarID,
+		AllMeetingURLs:   extractAllMeetingURLs(item),
+		RecurringEventID: item.RecurringEventId,
+	}
+	if item.Start != nil {
+		event.Start = item.Start.DateTime
+		if event.Start == "" {
+			event.Start = item.Start.Date
+			event.AllDay = true
+		}
+	}
+	if item.End != nil {
+		event.End = item.End.DateTime
+		if event.End == "" {
+			event.End = item.End.Date
+		}
+	}
+	if len(event.AllMeetingURLs) > 0 {
+		event.MeetingURL = event.AllMeetingURLs[0]
+	}
+	event.Provider = MeetingProvider(event.MeetingURL)
+	event.LocationInfo = parseItemLocation(item)
+	event.Reminders = itemReminders(item)
+	setOrganizer(&event, item)
+
+	for _, attendee := range item.Attendees {
+		if attendee.Self || attendee.Email == "" {
+			continue
+		}
+		name := attendee.DisplayName
+		if name == "" {
+			name = attendee.Email
+		}
+		event.Attendees = append(event.Attendees, name)
+		event.AttendeeDetails = append(event.AttendeeDetails, Attendee{
+			Name:           attendee.DisplayName,
+			Email:          attendee.Email,
+			ResponseStatus: attendee.ResponseStatus,
+			Comment:        attendee.Comment,
+		})
+	}
+	event.AttendeeCount = len(event.Attendees)
 
 	return event
 }
 
-// extractMeetingURL finds meeting URL from event
+// extractMeetingURL returns the event's primary meeting URL, or "" if it
+// has none
 func extractMeetingURL(item *calendar.Event) string {
-	// Check hangout link first (Google Meet)
-	if item.HangoutLink != "" {
-		return item.HangoutLink
+	if urls := extractAllMeetingURLs(item); len(urls) > 0 {
+		return urls[0]
+	}
+	return ""
+}
+
+// extractAllMeetingURLs returns every distinct meeting URL on the event, in
+// priority order: the Meet hangout link, video conference entry points, then
+// provider links found in the description and location
+func extractAllMeetingURLs(item *calendar.Event) []string {
+	var urls []string
+	seen := make(map[string]bool)
+	add := func(url string) {
+		url = strings.TrimSpace(url)
+		if url != "" && !seen[url] {
+			seen[url] = true
+			urls = append(urls, url)
+		}
 	}
 
-	// Check conference data
+	add(item.HangoutLink)
+
 	if item.ConferenceData != nil {
 		for _, ep := range item.ConferenceData.EntryPoints {
-			if ep.EntryPointType == "video" && ep.Uri != "" {
-				return ep.Uri
+			if ep.EntryPointType == "video" {
+				add(ep.Uri)
 			}
 		}
 	}
 
 	// Search in description and location
 	searchIn := item.Description + " " + item.Location
-
-	for _, pattern := range meetingPatterns {
-		if match := pattern.FindString(searchIn); match != "" {
-			return strings.TrimSpace(match)
+	for _, mp := range meetingPatterns {
+		for _, match := range mp.pattern.FindAllString(searchIn, -1) {
+			add(match)
 		}
 	}
 
+	return urls
+}
+
+// MeetingProvider returns the video provider for a meeting URL: "zoom",
+// "meet", "teams", "webex", or "" if the URL isn't recognized
+func MeetingProvider(url string) string {
+	for _, mp := range meetingPatterns {
+		if mp.pattern.MatchString(url) {
+			return mp.provider
+		}
+	}
 	return ""
 }
 
-// detectConflicts marks events that overlap with each other
-func detectConflicts(events []Event) {
+// detectConflicts marks events that overlap with each other. All-day dates
+// are anchored in the configured location.
+//
+// It sweeps the events in start order, keeping the ones still in progress
+// in a heap ordered by end time, so it runs in O(n log n) plus the number
+// of conflicting pairs. Events that don't end after they start, which are
+// rare, are checked against every other event instead.
+func detectConflicts(events []Event, opts FetchOptions) {
+	loc := opts.location()
+
+	// Collect the events that can conflict, with their parsed times
+	var timed, degenerate []conflictCandidate
+	for i, e := range events {
+		if !blocksTime(e, opts) {
+			continue
+		}
+		start, errStart := parseEventTime(e.Start, loc)
+		end, errEnd := parseEventTime(e.End, loc)
+		if errStart != nil || errEnd != nil {
+			continue
+		}
+
+		c := conflictCandidate{index: i, start: start, end: end}
+		if end.After(start) {
+			timed = append(timed, c)
+		} else {
+			degenerate = append(degenerate, c)
+		}
+	}
+
+	neighbors := make(map[int][]int)
+	addPair := func(a, b conflictCandidate) {
+		if opts.ConflictsWithinCalendarOnly && events[a.index].CalendarID != events[b.index].CalendarID {
+			return
+		}
+		neighbors[a.index] = append(neighbors[a.index], b.index)
+		neighbors[b.index] = append(neighbors[b.index], a.index)
+	}
+
+	sort.SliceStable(timed, func(i, j int) bool {
+		return timed[i].start.Before(timed[j].start)
+	})
+	var active conflictHeap
+	for _, c := range timed {
+		// Drop events that ended by the time this one starts; everything
+		// left started no later than c and is still running, so overlaps it
+		for len(active) > 0 && !active[0].end.After(c.start) {
+			heap.Pop(&active)
+		}
+		for _, other := range active {
+			addPair(other, c)
+		}
+		heap.Push(&active, c)
+	}
+
+	// An event that doesn't end after it starts only overlaps events that
+	// strictly contain its span; two such events never overlap each other
+	for _, d := range degenerate {
+		for _, c := range timed {
+			if c.end.After(d.start) && c.start.Before(d.end) {
+				addPair(c, d)
+			}
+		}
+	}
+
+	// Record conflicts in input order, which is start order after sorting
+	for i, others := range neighbors {
+		sort.Ints(others)
+		events[i].HasConflict = true
+		for _, j := range others {
+			events[i].ConflictsWith = append(events[i].ConflictsWith, events[j].ID)
+		}
+	}
+}
+
+// conflictCandidate is an event that can conflict, with its parsed times
+type conflictCandidate struct {
+	index      int // position in the events being checked
+	start, end time.Time
+}
+
+// conflictHeap is a min-heap of candidates ordered by end time
+type conflictHeap []conflictCandidate
+
+func (h conflictHeap) Len() int           { return len(h) }
+func (h conflictHeap) Less(i, j int) bool { return h[i].end.Before(h[j].end) }
+func (h conflictHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *conflictHeap) Push(x interface{}) { *h = append(*h, x.(conflictCandidate)) }
+
+func (h *conflictHeap) Pop() interface{} {
+	old := *h
+	c := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return c
+}
+
+// markZeroDuration sets Marker on timed events that start and end at the
+// same instant
+func markZeroDuration(events []Event) {
 	for i := range events {
-		for j := i + 1; j < len(events); j++ {
-			// Parse times
-			startI, errI := time.Parse(time.RFC3339, events[i].Start)
-			endI, errIEnd := time.Parse(time.RFC3339, events[i].End)
-			startJ, errJ := time.Parse(time.RFC3339, events[j].Start)
-			endJ, errJEnd := time.Parse(time.RFC3339, events[j].End)
-
-			if errI != nil || errIEnd != nil || errJ != nil || errJEnd != nil {
-				continue
+		start, errStart := time.Parse(time.RFC3339, events[i].Start)
+		end, errEnd := time.Parse(time.RFC3339, events[i].End)
+		if errStart == nil && errEnd == nil && start.Equal(end) {
+			events[i].Marker = true
+		}
+	}
+}
+
+// blocksTime reports whether an event occupies its time for conflict
+// detection and free-slot searches. All-day events don't when
+// AllDayNeverConflicts is set, and neither do markers or out-of-office
+// blocks; meetings over the latter are flagged DuringOOO instead.
+func blocksTime(e Event, opts FetchOptions) bool {
+	if opts.AllDayNeverConflicts && isAllDay(e) {
+		return false
+	}
+	return !isOutOfOffice(e) && !e.Marker
+}
+
+// isOutOfOffice reports whether an event is an out-of-office block
+func isOutOfOffice(e Event) bool {
+	return e.EventType == eventTypeOutOfOffice
+}
+
+// markDuringOOO sets DuringOOO on timed events that overlap an
+// out-of-office block
+func markDuringOOO(events []Event, loc *time.Location) {
+	var ooo []BusyInterval
+	for _, e := range events {
+		if !isOutOfOffice(e) {
+			continue
+		}
+		if iv, ok := eventInterval(e, loc); ok {
+			ooo = append(ooo, iv)
+		}
+	}
+	if len(ooo) == 0 {
+		return
+	}
+
+	for i := range events {
+		if isOutOfOffice(events[i]) || isAllDay(events[i]) {
+			continue
+		}
+		iv, ok := eventInterval(events[i], loc)
+		if !ok {
+			continue
+		}
+		for _, block := range ooo {
+			if iv.Start.Before(block.End) && block.Start.Before(iv.End) {
+				events[i].DuringOOO = true
+				break
 			}
+		}
+	}
+}
 
-			// Check for overlap: event i ends after event j starts AND event i starts before event j ends
-			if endI.After(startJ) && startI.Before(endJ) {
-				events[i].HasConflict = true
-				events[j].HasConflict = true
+// markDuringLunch sets DuringLunch on timed events overlapping the
+// lunchStart-lunchEnd clock window on any day they span in loc
+func markDuringLunch(events []Event, lunchStart, lunchEnd time.Duration, loc *time.Location) {
+	if lunchEnd <= lunchStart {
+		return
+	}
+	for i := range events {
+		if isOutOfOffice(events[i]) || isAllDay(events[i]) {
+			continue
+		}
+		iv, ok := eventInterval(events[i], loc)
+		if !ok {
+			continue
+		}
+		start, end := iv.Start.In(loc), iv.End.In(loc)
+		for day := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, loc); day.Before(end); day = day.AddDate(0, 0, 1) {
+			lunchFrom := clockTime(day, lunchStart, loc)
+			lunchTo := clockTime(day, lunchEnd, loc)
+			if start.Before(lunchTo) && lunchFrom.Before(end) {
+				events[i].DuringLunch = true
+				break
 			}
 		}
 	}
 }
+
+// clockTime returns offset into date's calendar day in loc, resolved with
+// time.Date so DST transitions don't shift it
+func clockTime(date time.Time, offset time.Duration, loc *time.Location) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(),
+		int(offset/time.Hour), int(offset%time.Hour/time.Minute), 0, 0, loc)
+}
+
+// isAllDay reports whether an event starts on a bare date rather than a time
+func isAllDay(e Event) bool {
+	_, err := time.Parse(allDayLayout, e.Start)
+	return err == nil
+}
+
+// computeGaps sets GapBeforeMinutes on events sorted by start time, measuring
+// from the latest end among all earlier events. Zero-duration markers get a
+// gap like any other event. All-day events and events with unparseable or
+// reversed times get -1 and don't affect their neighbors.
+func computeGaps(events []Event, loc *time.Location) {
+	var latestEnd time.Time
+	for i := range events {
+		events[i].GapBeforeMinutes = -1
+
+		// All-day blocks don't occupy the day's schedule
+		if isAllDay(events[i]) {
+			continue
+		}
+		// Parsed directly rather than with eventInterval, which rejects
+		// the zero-duration intervals of markers
+		start, errStart := parseEventTime(events[i].Start, loc)
+		end, errEnd := parseEventTime(events[i].End, loc)
+		if errStart != nil || errEnd != nil || end.Before(start) {
+			continue
+		}
+		iv := BusyInterval{Start: start, End: end}
+
+		if !latestEnd.IsZero() {
+			gap := iv.Start.Sub(latestEnd)
+			if gap < 0 {
+				gap = 0
+			}
+			events[i].GapBeforeMinutes = int(gap / time.Minute)
+		}
+		if iv.End.After(latestEnd) {
+			latestEnd = iv.End
+		}
+	}
+}
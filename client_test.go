@@ -1,11 +1,24 @@
 package gcal
 
 import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"golang.org/x/oauth2"
 	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/option"
 )
 
 func TestConvertEvent(t *testing.T) {
@@ -65,6 +78,119 @@ func TestConvertEvent(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "event with organizer",
+			item: &calendar.Event{
+				Id:        "organized",
+				Summary:   "Planning",
+				Start:     &calendar.EventDateTime{DateTime: startTime},
+				End:       &calendar.EventDateTime{DateTime: endTime},
+				Organizer: &calendar.EventOrganizer{DisplayName: "Alice", Email: "alice@example.com"},
+				Attendees: []*calendar.EventAttendee{
+					{Self: true, ResponseStatus: "accepted"},
+					{Email: "alice@example.com", DisplayName: "Alice", Organizer: true},
+				},
+			},
+			checkFn: func(t *testing.T, e *Event) {
+				if e.Organizer != "Alice" {
+					t.Errorf("convertEvent() Organizer = %q, want Alice", e.Organizer)
+				}
+				if e.OrganizerEmail != "alice@example.com" {
+					t.Errorf("convertEvent() OrganizerEmail = %q, want alice@example.com", e.OrganizerEmail)
+				}
+				if e.OrganizedByMe != false {
+					t.Errorf("convertEvent() OrganizedByMe = %v, want false", e.OrganizedByMe)
+				}
+			},
+		},
+		{
+			name: "event organized by me without display name",
+			item: &calendar.Event{
+				Id:        "mine",
+				Summary:   "1:1",
+				Start:     &calendar.EventDateTime{DateTime: startTime},
+				End:       &calendar.EventDateTime{DateTime: endTime},
+				Organizer: &calendar.EventOrganizer{Email: "me@example.com", Self: true},
+				Attendees: []*calendar.EventAttendee{
+					{Self: true, Organizer: true, ResponseStatus: "accepted"},
+					{Email: "bob@example.com", DisplayName: "Bob"},
+				},
+			},
+			checkFn: func(t *testing.T, e *Event) {
+				if e.Organizer != "me@example.com" {
+					t.Errorf("convertEvent() Organizer = %q, want me@example.com", e.Organizer)
+				}
+				if e.OrganizerEmail != "me@example.com" {
+					t.Errorf("convertEvent() OrganizerEmail = %q, want me@example.com", e.OrganizerEmail)
+				}
+				if e.OrganizedByMe != true {
+					t.Errorf("convertEvent() OrganizedByMe = %v, want true", e.OrganizedByMe)
+				}
+			},
+		},
+		{
+			name: "event with room location",
+			item: &calendar.Event{
+				Id:       "in-office",
+				Summary:  "Design Review",
+				Location: "Building 4 / Room 301",
+				Start:    &calendar.EventDateTime{DateTime: startTime},
+				End:      &calendar.EventDateTime{DateTime: endTime},
+				Attendees: []*calendar.EventAttendee{
+					{Self: true, ResponseStatus: "accepted"},
+					{Email: "bob@example.com", DisplayName: "Bob"},
+				},
+			},
+			checkFn: func(t *testing.T, e *Event) {
+				if e.Location != "Building 4 / Room 301" {
+					t.Errorf("convertEvent() Location = %q, want %q", e.Location, "Building 4 / Room 301")
+				}
+				if e.MeetingURL != "" {
+					t.Errorf("convertEvent() MeetingURL = %q, want empty", e.MeetingURL)
+				}
+			},
+		},
+		{
+			name: "event with meeting URL location",
+			item: &calendar.Event{
+				Id:       "remote",
+				Summary:  "Sync",
+				Location: "https://zoom.us/j/123456789",
+				Start:    &calendar.EventDateTime{DateTime: startTime},
+				End:      &calendar.EventDateTime{DateTime: endTime},
+				Attendees: []*calendar.EventAttendee{
+					{Self: true, ResponseStatus: "accepted"},
+					{Email: "bob@example.com", DisplayName: "Bob"},
+				},
+			},
+			checkFn: func(t *testing.T, e *Event) {
+				if e.Location != "https://zoom.us/j/123456789" {
+					t.Errorf("convertEvent() Location = %q, want %q", e.Location, "https://zoom.us/j/123456789")
+				}
+				if e.MeetingURL != "https://zoom.us/j/123456789" {
+					t.Errorf("convertEvent() MeetingURL = %q, want %q", e.MeetingURL, "https://zoom.us/j/123456789")
+				}
+			},
+		},
+		{
+			name: "event without organizer",
+			item: &calendar.Event{
+				Id:      "unorganized",
+				Summary: "Imported",
+				Start:   &calendar.EventDateTime{DateTime: startTime},
+				End:     &calendar.EventDateTime{DateTime: endTime},
+				Attendees: []*calendar.EventAttendee{
+					{Self: true, ResponseStatus: "accepted"},
+					{Email: "bob@example.com", DisplayName: "Bob"},
+				},
+			},
+			checkFn: func(t *testing.T, e *Event) {
+				if e.Organizer != "" || e.OrganizerEmail != "" || e.OrganizedByMe {
+					t.Errorf("convertEvent() Organizer, OrganizerEmail, OrganizedByMe = %q, %q, %v, want empty",
+						e.Organizer, e.OrganizerEmail, e.OrganizedByMe)
+				}
+			},
+		},
 		{
 			name: "cancelled event",
 			item: &calendar.Event{
@@ -200,6 +326,116 @@ func TestConvertEvent(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "attendee comments are preserved",
+			item: &calendar.Event{
+				Id:      "event9",
+				Summary: "Roadmap Review",
+				Start: &calendar.EventDateTime{
+					DateTime: startTime,
+				},
+				End: &calendar.EventDateTime{
+					DateTime: endTime,
+				},
+				Attendees: []*calendar.EventAttendee{
+					{
+						Self:           true,
+						ResponseStatus: "accepted",
+						Comment:        "Joining 5 minutes late",
+					},
+					{
+						Email:          "alice@example.com",
+						DisplayName:    "Alice",
+						ResponseStatus: "declined",
+						Comment:        "Out sick today",
+					},
+					{
+						Email:          "bob@example.com",
+						ResponseStatus: "accepted",
+					},
+				},
+			},
+			wantNil: false,
+			checkFn: func(t *testing.T, e *Event) {
+				if e.SelfComment != "Joining 5 minutes late" {
+					t.Errorf("convertEvent() SelfComment = %q, want Joining 5 minutes late", e.SelfComment)
+				}
+				want := []Attendee{
+					{Name: "Alice", Email: "alice@example.com", ResponseStatus: "declined", Comment: "Out sick today"},
+					{Email: "bob@example.com", ResponseStatus: "accepted"},
+				}
+				if diff := cmp.Diff(e.AttendeeDetails, want); diff != "" {
+					t.Errorf("convertEvent() AttendeeDetails mismatch (-got +want):\n%s", diff)
+				}
+			},
+		},
+		{
+			name: "reminder overrides",
+			item: &calendar.Event{
+				Id:      "event10",
+				Summary: "Board Meeting",
+				Start: &calendar.EventDateTime{
+					DateTime: startTime,
+				},
+				End: &calendar.EventDateTime{
+					DateTime: endTime,
+				},
+				Attendees: []*calendar.EventAttendee{
+					{
+						Self:           true,
+						ResponseStatus: "accepted",
+					},
+					{
+						Email: "alice@example.com",
+					},
+				},
+				Reminders: &calendar.EventReminders{
+					Overrides: []*calendar.EventReminder{
+						{Method: "popup", Minutes: 10},
+						{Method: "email", Minutes: 60},
+					},
+				},
+			},
+			wantNil: false,
+			checkFn: func(t *testing.T, e *Event) {
+				want := []Reminder{
+					{Method: "popup", Minutes: 10},
+					{Method: "email", Minutes: 60},
+				}
+				if diff := cmp.Diff(e.Reminders, want); diff != "" {
+					t.Errorf("convertEvent() Reminders mismatch (-got +want):\n%s", diff)
+				}
+			},
+		},
+		{
+			name: "description passes through",
+			item: &calendar.Event{
+				Id:          "event11",
+				Summary:     "Design Review",
+				Description: "Agenda: walk through the API proposal",
+				Start: &calendar.EventDateTime{
+					DateTime: startTime,
+				},
+				End: &calendar.EventDateTime{
+					DateTime: endTime,
+				},
+				Attendees: []*calendar.EventAttendee{
+					{
+						Self:           true,
+						ResponseStatus: "accepted",
+					},
+					{
+						Email: "alice@example.com",
+					},
+				},
+			},
+			wantNil: false,
+			checkFn: func(t *testing.T, e *Event) {
+				if e.Description != "Agenda: walk through the API proposal" {
+					t.Errorf("convertEvent() Description = %q, want the event description", e.Description)
+				}
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -207,7 +443,7 @@ func TestConvertEvent(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
-			got := convertEvent(tt.item)
+			got := convertEvent(tt.item, FetchOptions{})
 			if (got == nil) != tt.wantNil {
 				t.Errorf("convertEvent() returned nil = %v, want nil = %v", got == nil, tt.wantNil)
 				return
@@ -305,6 +541,88 @@ func TestExtractMeetingURL(t *testing.T) {
 	}
 }
 
+func TestExtractAllMeetingURLs(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		item *calendar.Event
+		want []string
+	}{
+		{
+			name: "Meet with Zoom backup",
+			item: &calendar.Event{
+				HangoutLink: "https://meet.google.com/abc-defg-hij",
+				Description: "Backup: https://zoom.us/j/123456789\nOr rejoin https://meet.google.com/abc-defg-hij",
+			},
+			want: []string{"https://meet.google.com/abc-defg-hij", "https://zoom.us/j/123456789"},
+		},
+		{
+			name: "Meet and Teams in text",
+			item: &calendar.Event{
+				Description: "Teams: https://teams.microsoft.com/l/meetup-join/19%3ameeting_abc",
+				Location:    "https://meet.google.com/xyz-wxyz-xyz",
+			},
+			want: []string{"https://meet.google.com/xyz-wxyz-xyz", "https://teams.microsoft.com/l/meetup-join/19%3ameeting_abc"},
+		},
+		{
+			name: "conference entry duplicated in description",
+			item: &calendar.Event{
+				ConferenceData: &calendar.ConferenceData{
+					EntryPoints: []*calendar.EntryPoint{
+						{EntryPointType: "video", Uri: "https://zoom.us/j/123456789"},
+						{EntryPointType: "phone", Uri: "tel:+1-555-0100"},
+					},
+				},
+				Description: "Join: https://zoom.us/j/123456789",
+			},
+			want: []string{"https://zoom.us/j/123456789"},
+		},
+		{
+			name: "none",
+			item: &calendar.Event{Description: "In person"},
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt // Capture loop variable
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := extractAllMeetingURLs(tt.item)
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("extractAllMeetingURLs() mismatch (-got +want):\n%s", diff)
+			}
+			if primary := extractMeetingURL(tt.item); len(got) > 0 && primary != got[0] {
+				t.Errorf("extractMeetingURL() = %q, want first of all URLs %q", primary, got[0])
+			}
+		})
+	}
+}
+
+func TestMeetingProvider(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{url: "https://us02web.zoom.us/j/123456789?pwd=abc", want: "zoom"},
+		{url: "https://zoom.us/j/987654321", want: "zoom"},
+		{url: "https://meet.google.com/abc-defg-hij", want: "meet"},
+		{url: "https://teams.microsoft.com/l/meetup-join/19%3ameeting", want: "teams"},
+		{url: "https://acme.webex.com/meet/alice", want: "webex"},
+		{url: "https://example.com/meeting", want: ""},
+		{url: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := MeetingProvider(tt.url); got != tt.want {
+			t.Errorf("MeetingProvider(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
 func TestDetectConflicts(t *testing.T) {
 	t.Parallel()
 	baseTime := time.Date(2024, 1, 15, 14, 0, 0, 0, time.UTC)
@@ -442,7 +760,7 @@ func TestDetectConflicts(t *testing.T) {
 			events := make([]Event, len(tt.events))
 			copy(events, tt.events)
 
-			detectConflicts(events)
+			detectConflicts(events, FetchOptions{})
 
 			if len(events) != len(tt.want) {
 				t.Fatalf("detectConflicts() events length = %v, want %v", len(events), len(tt.want))
@@ -459,6 +777,169 @@ func TestDetectConflicts(t *testing.T) {
 	}
 }
 
+func TestDetectConflicts_Pairs(t *testing.T) {
+	t.Parallel()
+	baseTime := time.Date(2024, 1, 15, 14, 0, 0, 0, time.UTC)
+	event := func(id string, start, end time.Duration) Event {
+		return Event{
+			ID:    id,
+			Start: baseTime.Add(start).Format(time.RFC3339),
+			End:   baseTime.Add(end).Format(time.RFC3339),
+		}
+	}
+
+	// The three-way conflict, plus a later event overlapping only event3
+	events := []Event{
+		event("event1", 0, time.Hour),
+		event("event2", 30*time.Minute, 90*time.Minute),
+		event("event3", 45*time.Minute, 2*time.Hour),
+		event("event4", 105*time.Minute, 3*time.Hour),
+		event("event5", 4*time.Hour, 5*time.Hour),
+	}
+	detectConflicts(events, FetchOptions{})
+
+	got := make(map[string][]string)
+	for _, e := range events {
+		got[e.ID] = e.ConflictsWith
+	}
+	want := map[string][]string{
+		"event1": {"event2", "event3"},
+		"event2": {"event1", "event3"},
+		"event3": {"event1", "event2", "event4"},
+		"event4": {"event3"},
+		"event5": nil,
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("detectConflicts() ConflictsWith mismatch (-got +want):\n%s", diff)
+	}
+}
+
+// detectConflictsBruteForce is the original pairwise conflict check, kept
+// as the reference detectConflicts must agree with
+func detectConflictsBruteForce(events []Event, opts FetchOptions) {
+	loc := opts.location()
+	for i := range events {
+		for j := i + 1; j < len(events); j++ {
+			if opts.ConflictsWithinCalendarOnly && events[i].CalendarID != events[j].CalendarID {
+				continue
+			}
+			if opts.AllDayNeverConflicts && (isAllDay(events[i]) || isAllDay(events[j])) {
+				continue
+			}
+			if isOutOfOffice(events[i]) || isOutOfOffice(events[j]) {
+				continue
+			}
+			if events[i].Marker || events[j].Marker {
+				continue
+			}
+
+			startI, errI := parseEventTime(events[i].Start, loc)
+			endI, errIEnd := parseEventTime(events[i].End, loc)
+			startJ, errJ := parseEventTime(events[j].Start, loc)
+			endJ, errJEnd := parseEventTime(events[j].End, loc)
+			if errI != nil || errIEnd != nil || errJ != nil || errJEnd != nil {
+				continue
+			}
+
+			if endI.After(startJ) && startI.Before(endJ) {
+				events[i].HasConflict = true
+				events[j].HasConflict = true
+				events[i].ConflictsWith = append(events[i].ConflictsWith, events[j].ID)
+				events[j].ConflictsWith = append(events[j].ConflictsWith, events[i].ID)
+			}
+		}
+	}
+}
+
+// randomConflictEvents returns n events on a single day with random times,
+// durations (including zero and negative ones), calendars and kinds
+func randomConflictEvents(rng *rand.Rand, n int) []Event {
+	base := time.Date(2024, 1, 15, 8, 0, 0, 0, time.UTC)
+	at := func(minutes int) string {
+		return base.Add(time.Duration(minutes) * time.Minute).Format(time.RFC3339)
+	}
+
+	events := make([]Event, n)
+	for i := range events {
+		start := rng.Intn(60) * 10
+		e := Event{
+			ID:         fmt.Sprintf("event%d", i),
+			CalendarID: fmt.Sprintf("cal%d", rng.Intn(3)),
+			Start:      at(start),
+			End:        at(start + (rng.Intn(14)-2)*10),
+		}
+		switch rng.Intn(20) {
+		case 0:
+			e.Start, e.End = "2024-01-15", "2024-01-16"
+		case 1:
+			e.EventType = eventTypeOutOfOffice
+		case 2:
+			e.Start = "not a time"
+		case 3:
+			e.End = ""
+		case 4:
+			e.End = e.Start
+			e.Marker = true
+		}
+		events[i] = e
+	}
+	return events
+}
+
+func TestDetectConflicts_MatchesBruteForce(t *testing.T) {
+	t.Parallel()
+	rng := rand.New(rand.NewSource(1))
+
+	for iter := 0; iter < 500; iter++ {
+		events := randomConflictEvents(rng, rng.Intn(40))
+		opts := FetchOptions{
+			ConflictsWithinCalendarOnly: rng.Intn(2) == 0,
+			AllDayNeverConflicts:        rng.Intn(2) == 0,
+		}
+		// Conflicts are detected on events sorted by start, as
+		// annotateEvents does
+		sort.SliceStable(events, func(i, j int) bool {
+			return startsBefore(events[i], events[j], opts.location())
+		})
+
+		got := make([]Event, len(events))
+		copy(got, events)
+		detectConflicts(got, opts)
+
+		want := make([]Event, len(events))
+		copy(want, events)
+		detectConflictsBruteForce(want, opts)
+
+		if diff := cmp.Diff(got, want); diff != "" {
+			t.Fatalf("iteration %d with %+v: detectConflicts() mismatch with brute force (-got +want):\n%s", iter, opts, diff)
+		}
+	}
+}
+
+func BenchmarkDetectConflicts(b *testing.B) {
+	events := randomConflictEvents(rand.New(rand.NewSource(1)), 500)
+	opts := DefaultFetchOptions()
+
+	b.Run("sweep", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			batch := make([]Event, len(events))
+			copy(batch, events)
+			b.StartTimer()
+			detectConflicts(batch, opts)
+		}
+	})
+	b.Run("brute force", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			batch := make([]Event, len(events))
+			copy(batch, events)
+			b.StartTimer()
+			detectConflictsBruteForce(batch, opts)
+		}
+	})
+}
+
 func TestDetectConflicts_InvalidTimeFormat(t *testing.T) {
 	t.Parallel()
 
@@ -477,10 +958,2589 @@ func TestDetectConflicts_InvalidTimeFormat(t *testing.T) {
 	}
 
 	// Should not panic
-	detectConflicts(events)
+	detectConflicts(events, FetchOptions{})
 
 	// Events should not be marked as conflicting due to parse errors
 	if events[0].HasConflict || events[1].HasConflict {
 		t.Error("detectConflicts() should not mark events as conflicting when times are invalid")
 	}
 }
+
+func TestConvertCalendar(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name            string
+		item            *calendar.CalendarListEntry
+		want            CalendarInfo
+		wantDisplayName string
+	}{
+		{
+			name: "shared calendar with override",
+			item: &calendar.CalendarListEntry{
+				Id:              "team@group.calendar.google.com",
+				Summary:         "Engineering Team",
+				SummaryOverride: "Eng",
+				Description:     "Team-wide events and on-call",
+			},
+			want: CalendarInfo{
+				ID:              "team@group.calendar.google.com",
+				Summary:         "Engineering Team",
+				SummaryOverride: "Eng",
+				Description:     "Team-wide events and on-call",
+			},
+			wantDisplayName: "Eng",
+		},
+		{
+			name: "no override falls back to summary",
+			item: &calendar.CalendarListEntry{
+				Id:      "primary",
+				Summary: "Your Name",
+				Primary: true,
+			},
+			want: CalendarInfo{
+				ID:      "primary",
+				Summary: "Your Name",
+				Primary: true,
+			},
+			wantDisplayName: "Your Name",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt // Capture loop variable
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := convertCalendar(tt.item)
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("convertCalendar() mismatch (-got +want):\n%s", diff)
+			}
+			if got.DisplayName() != tt.wantDisplayName {
+				t.Errorf("DisplayName() = %q, want %q", got.DisplayName(), tt.wantDisplayName)
+			}
+		})
+	}
+}
+
+func TestFetchEvents_PrimaryNotFound(t *testing.T) {
+	t.Parallel()
+
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/calendars/primary/events" {
+			t.Errorf("unexpected request path %s", r.URL.Path)
+		}
+		writeTestAPIError(w, http.StatusNotFound, "Not Found")
+	}))
+
+	got := client.FetchUpcomingEvents(context.Background(), nil, 24)
+
+	if got.Success {
+		t.Fatal("FetchUpcomingEvents() Success = true, want false")
+	}
+	if got.Error != ErrNotConfigured {
+		t.Errorf("FetchUpcomingEvents() Error = %v, want %v", got.Error, ErrNotConfigured)
+	}
+	if !strings.Contains(got.Message, "primary calendar is not accessible") {
+		t.Errorf("FetchUpcomingEvents() Message = %q, want primary calendar hint", got.Message)
+	}
+}
+
+func TestFetchEvents_OtherCalendarNotFound(t *testing.T) {
+	t.Parallel()
+
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeTestAPIError(w, http.StatusNotFound, "Not Found")
+	}))
+
+	got := client.FetchUpcomingEvents(context.Background(), []string{"missing@example.com"}, 24)
+
+	if got.Error != ErrAPIError {
+		t.Errorf("FetchUpcomingEvents() Error = %v, want %v", got.Error, ErrAPIError)
+	}
+}
+
+func TestDetectConflicts_WithinCalendarOnly(t *testing.T) {
+	t.Parallel()
+	baseTime := time.Date(2024, 1, 15, 14, 0, 0, 0, time.UTC)
+
+	newEvents := func() []Event {
+		return []Event{
+			{
+				ID:         "work1",
+				CalendarID: "work@example.com",
+				Start:      baseTime.Format(time.RFC3339),
+				End:        baseTime.Add(time.Hour).Format(time.RFC3339),
+			},
+			{
+				ID:         "personal1",
+				CalendarID: "personal@example.com",
+				Start:      baseTime.Add(15 * time.Minute).Format(time.RFC3339),
+				End:        baseTime.Add(45 * time.Minute).Format(time.RFC3339),
+			},
+			{
+				ID:         "work2",
+				CalendarID: "work@example.com",
+				Start:      baseTime.Add(2 * time.Hour).Format(time.RFC3339),
+				End:        baseTime.Add(3 * time.Hour).Format(time.RFC3339),
+			},
+			{
+				ID:         "work3",
+				CalendarID: "work@example.com",
+				Start:      baseTime.Add(150 * time.Minute).Format(time.RFC3339),
+				End:        baseTime.Add(4 * time.Hour).Format(time.RFC3339),
+			},
+		}
+	}
+
+	tests := []struct {
+		name string
+		opts FetchOptions
+		want []bool
+	}{
+		{
+			name: "all calendars",
+			opts: FetchOptions{},
+			want: []bool{true, true, true, true},
+		},
+		{
+			name: "within calendar only",
+			opts: FetchOptions{ConflictsWithinCalendarOnly: true},
+			want: []bool{false, false, true, true},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt // Capture loop variable
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			events := newEvents()
+			detectConflicts(events, tt.opts)
+
+			got := make([]bool, len(events))
+			for i := range events {
+				got[i] = events[i].HasConflict
+			}
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("detectConflicts() HasConflict mismatch (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFetchEvents_SetsCalendarID(t *testing.T) {
+	t.Parallel()
+	start := time.Date(2024, 1, 15, 14, 0, 0, 0, time.UTC)
+
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calID := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/calendars/"), "/events")
+		writeTestJSON(t, w, &calendar.Events{
+			Items: []*calendar.Event{
+				testAcceptedEvent(calID+"-event", start, start.Add(time.Hour)),
+			},
+		})
+	}))
+	client.Options.ConflictsWithinCalendarOnly = true
+
+	got := client.FetchUpcomingEvents(context.Background(), []string{"work@example.com", "personal@example.com"}, 24)
+	if !got.Success {
+		t.Fatalf("FetchUpcomingEvents() failed: %s", got.Message)
+	}
+
+	gotIDs := map[string]string{}
+	for _, e := range got.Events {
+		gotIDs[e.ID] = e.CalendarID
+		if e.HasConflict {
+			t.Errorf("event %s HasConflict = true, want false for cross-calendar overlap", e.ID)
+		}
+	}
+	want := map[string]string{
+		"work@example.com-event":     "work@example.com",
+		"personal@example.com-event": "personal@example.com",
+	}
+	if diff := cmp.Diff(gotIDs, want); diff != "" {
+		t.Errorf("FetchUpcomingEvents() CalendarID mismatch (-got +want):\n%s", diff)
+	}
+}
+
+func TestFetchEvents_SharedExtendedPropertyFilter(t *testing.T) {
+	t.Parallel()
+	start := time.Date(2024, 1, 15, 14, 0, 0, 0, time.UTC)
+
+	var gotFilters []string
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotFilters = r.URL.Query()["sharedExtendedProperty"]
+		item := testAcceptedEvent("event1", start, start.Add(time.Hour))
+		item.ExtendedProperties = &calendar.EventExtendedProperties{
+			Shared: map[string]string{"project": "apollo"},
+		}
+		writeTestJSON(t, w, &calendar.Events{Items: []*calendar.Event{item}})
+	}))
+	client.Options.SharedExtendedPropertyFilters = []string{"project=apollo", "team=infra"}
+
+	got := client.FetchUpcomingEvents(context.Background(), nil, 24)
+	if !got.Success {
+		t.Fatalf("FetchUpcomingEvents() failed: %s", got.Message)
+	}
+
+	if diff := cmp.Diff(gotFilters, []string{"project=apollo", "team=infra"}); diff != "" {
+		t.Errorf("sharedExtendedProperty mismatch (-got +want):\n%s", diff)
+	}
+	if len(got.Events) != 1 {
+		t.Fatalf("FetchUpcomingEvents() returned %d events, want 1", len(got.Events))
+	}
+	if diff := cmp.Diff(got.Events[0].SharedProperties, map[string]string{"project": "apollo"}); diff != "" {
+		t.Errorf("SharedProperties mismatch (-got +want):\n%s", diff)
+	}
+}
+
+func TestFetchEvents_NoSharedExtendedPropertyFilterByDefault(t *testing.T) {
+	t.Parallel()
+
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.URL.Query()["sharedExtendedProperty"]; ok {
+			t.Errorf("sharedExtendedProperty sent without a filter configured")
+		}
+		writeTestJSON(t, w, &calendar.Events{})
+	}))
+
+	if got := client.FetchUpcomingEvents(context.Background(), nil, 24); !got.Success {
+		t.Fatalf("FetchUpcomingEvents() failed: %s", got.Message)
+	}
+}
+
+func TestFetchEvents_MaxAttendeesFetched(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		max  int
+		want string
+	}{
+		{name: "forwarded when set", max: 25, want: "25"},
+		{name: "omitted by default", max: 0, want: ""},
+	}
+
+	for _, tt := range tests {
+		tt := tt // Capture loop variable
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var got string
+			client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.URL.Query().Get("maxAttendees")
+				writeTestJSON(t, w, &calendar.Events{})
+			}))
+			client.Options.MaxAttendeesFetched = tt.max
+
+			if resp := client.FetchUpcomingEvents(context.Background(), nil, 24); !resp.Success {
+				t.Fatalf("FetchUpcomingEvents() failed: %s", resp.Message)
+			}
+			if got != tt.want {
+				t.Errorf("maxAttendees = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFetchEvents_ICalUIDFilter(t *testing.T) {
+	t.Parallel()
+	start := time.Date(2024, 1, 15, 14, 0, 0, 0, time.UTC)
+
+	var gotUIDs []string
+	var mu sync.Mutex
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		gotUIDs = append(gotUIDs, r.URL.Query().Get("iCalUID"))
+		mu.Unlock()
+		item := testAcceptedEvent("copy-"+strings.TrimPrefix(r.URL.Path, "/calendars/"), start, start.Add(time.Hour))
+		item.ICalUID = "sync@google.com"
+		writeTestJSON(t, w, &calendar.Events{Items: []*calendar.Event{item}})
+	}))
+	client.Options.ICalUIDFilter = "sync@google.com"
+
+	got := client.FetchUpcomingEvents(context.Background(), []string{"work@example.com", "team@example.com"}, 24)
+	if !got.Success {
+		t.Fatalf("FetchUpcomingEvents() failed: %s", got.Message)
+	}
+
+	if diff := cmp.Diff(gotUIDs, []string{"sync@google.com", "sync@google.com"}); diff != "" {
+		t.Errorf("iCalUID mismatch (-got +want):\n%s", diff)
+	}
+	if len(got.Events) != 2 {
+		t.Fatalf("FetchUpcomingEvents() returned %d events, want 2", len(got.Events))
+	}
+	for _, e := range got.Events {
+		if e.ICalUID != "sync@google.com" || e.CalendarID == "" {
+			t.Errorf("event %s ICalUID = %q CalendarID = %q, want converted copy", e.ID, e.ICalUID, e.CalendarID)
+		}
+	}
+}
+
+func TestFetchEvents_StrictCalendarIDs(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		strict      bool
+		calendarIDs []string
+		wantErr     string
+		wantPaths   []string
+	}{
+		{
+			name:      "defaults to primary",
+			wantPaths: []string{"/calendars/primary/events"},
+		},
+		{
+			name:    "strict fails without IDs",
+			strict:  true,
+			wantErr: ErrNotConfigured,
+		},
+		{
+			name:        "strict with explicit IDs",
+			strict:      true,
+			calendarIDs: []string{"work@example.com"},
+			wantPaths:   []string{"/calendars/work@example.com/events"},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt // Capture loop variable
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var gotPaths []string
+			client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotPaths = append(gotPaths, r.URL.Path)
+				writeTestJSON(t, w, &calendar.Events{})
+			}))
+			client.Options.StrictCalendarIDs = tt.strict
+
+			got := client.FetchUpcomingEvents(context.Background(), tt.calendarIDs, 24)
+			if got.Error != tt.wantErr {
+				t.Errorf("FetchUpcomingEvents() Error = %q, want %q (message: %s)", got.Error, tt.wantErr, got.Message)
+			}
+			if diff := cmp.Diff(gotPaths, tt.wantPaths); diff != "" {
+				t.Errorf("requested paths mismatch (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNewClientWithToken(t *testing.T) {
+	// Not parallel: points the XDG directories at an empty temp dir to
+	// assert nothing is read from or written to disk
+	tmpDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", tmpDir)
+	t.Setenv("XDG_DATA_HOME", tmpDir)
+
+	var gotAuth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		writeTestJSON(t, w, &calendar.Events{})
+	}))
+	defer server.Close()
+
+	creds := &Credentials{
+		ClientID:     "embedded-client-id",
+		ClientSecret: "embedded-client-secret",
+	}
+	token := &oauth2.Token{
+		AccessToken:  "in-memory-access-token",
+		RefreshToken: "in-memory-refresh-token",
+		TokenType:    "Bearer",
+		Expiry:       time.Now().Add(time.Hour),
+	}
+
+	client, err := newClientWithToken(context.Background(), creds, token, nil, option.WithEndpoint(server.URL+"/"))
+	if err != nil {
+		t.Fatalf("newClientWithToken() error = %v", err)
+	}
+
+	if got := client.FetchUpcomingEvents(context.Background(), nil, 24); !got.Success {
+		t.Fatalf("FetchUpcomingEvents() failed: %s", got.Message)
+	}
+	if gotAuth != "Bearer in-memory-access-token" {
+		t.Errorf("Authorization header = %q, want in-memory token", gotAuth)
+	}
+
+	entries, err := os.ReadDir(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to read temp dir: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("NewClientWithToken() touched disk: found %d entries in XDG dir", len(entries))
+	}
+}
+
+func TestNewClientWithToken_InvalidInput(t *testing.T) {
+	t.Parallel()
+
+	token := &oauth2.Token{AccessToken: "token"}
+	tests := []struct {
+		name  string
+		creds *Credentials
+		token *oauth2.Token
+	}{
+		{name: "nil credentials", creds: nil, token: token},
+		{name: "missing client secret", creds: &Credentials{ClientID: "id"}, token: token},
+		{name: "nil token", creds: &Credentials{ClientID: "id", ClientSecret: "secret"}, token: nil},
+	}
+
+	for _, tt := range tests {
+		tt := tt // Capture loop variable
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := NewClientWithToken(context.Background(), tt.creds, tt.token)
+			if err == nil {
+				t.Fatal("NewClientWithToken() error = nil, want error")
+			}
+			if !strings.Contains(err.Error(), ErrNotConfigured) {
+				t.Errorf("NewClientWithToken() error = %v, want %s", err, ErrNotConfigured)
+			}
+		})
+	}
+}
+
+func TestNormalizeCalendarID(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		id   string
+		want string
+	}{
+		{name: "already clean", id: "team@example.com", want: "team@example.com"},
+		{name: "primary", id: "primary", want: "primary"},
+		{name: "surrounding whitespace", id: "  team@example.com\n", want: "team@example.com"},
+		{name: "mailto prefix", id: "mailto:team@example.com", want: "team@example.com"},
+		{name: "uppercase mailto with spaces", id: " MAILTO: team@example.com ", want: "team@example.com"},
+		{name: "only whitespace", id: " \t ", want: ""},
+		{name: "only mailto", id: "mailto:", want: ""},
+	}
+
+	for _, tt := range tests {
+		tt := tt // Capture loop variable
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := NormalizeCalendarID(tt.id); got != tt.want {
+				t.Errorf("NormalizeCalendarID(%q) = %q, want %q", tt.id, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFetchEvents_NormalizesCalendarIDs(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var gotPaths []string
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		gotPaths = append(gotPaths, r.URL.Path)
+		mu.Unlock()
+		writeTestJSON(t, w, &calendar.Events{})
+	}))
+
+	got := client.FetchUpcomingEvents(context.Background(), []string{" mailto:team@example.com", "primary\n"}, 24)
+	if !got.Success {
+		t.Fatalf("FetchUpcomingEvents() failed: %s", got.Message)
+	}
+	sort.Strings(gotPaths)
+	if diff := cmp.Diff(gotPaths, []string{"/calendars/primary/events", "/calendars/team@example.com/events"}); diff != "" {
+		t.Errorf("requested paths mismatch (-got +want):\n%s", diff)
+	}
+
+	got = client.FetchUpcomingEvents(context.Background(), []string{"primary", "  "}, 24)
+	if got.Success || got.Error != ErrInvalidConfig {
+		t.Errorf("FetchUpcomingEvents() with a blank ID = %+v, want %s error", got, ErrInvalidConfig)
+	}
+}
+
+func TestAnonymizeCalendarID(t *testing.T) {
+	t.Parallel()
+
+	work := anonymizeCalendarID("alice@example.com")
+	if work != anonymizeCalendarID("alice@example.com") {
+		t.Error("anonymizeCalendarID() is not stable for the same ID")
+	}
+	if work == anonymizeCalendarID("bob@example.com") {
+		t.Error("anonymizeCalendarID() maps different IDs to the same value")
+	}
+	if !regexp.MustCompile(`^cal-[0-9a-f]{8}$`).MatchString(work) {
+		t.Errorf("anonymizeCalendarID() = %q, want cal-<8 hex chars>", work)
+	}
+	if strings.Contains(work, "alice") {
+		t.Errorf("anonymizeCalendarID() = %q leaks the original ID", work)
+	}
+}
+
+func TestFetchEvents_AnonymizeCalendarIDs(t *testing.T) {
+	t.Parallel()
+	start := time.Date(2024, 1, 15, 14, 0, 0, 0, time.UTC)
+
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeTestJSON(t, w, &calendar.Events{
+			Items: []*calendar.Event{
+				testAcceptedEvent("event1", start, start.Add(time.Hour)),
+				testAcceptedEvent("event2", start.Add(2*time.Hour), start.Add(3*time.Hour)),
+			},
+		})
+	}))
+	client.Options.AnonymizeCalendarIDs = true
+
+	got := client.FetchUpcomingEvents(context.Background(), []string{"alice@example.com", "bob@example.com"}, 24)
+	if !got.Success {
+		t.Fatalf("FetchUpcomingEvents() failed: %s", got.Message)
+	}
+
+	ids := map[string]bool{}
+	for _, e := range got.Events {
+		if strings.Contains(e.CalendarID, "@") {
+			t.Errorf("event %s CalendarID = %q, want anonymized", e.ID, e.CalendarID)
+		}
+		ids[e.CalendarID] = true
+	}
+	if len(ids) != 2 {
+		t.Errorf("got %d distinct anonymized calendar IDs, want 2", len(ids))
+	}
+}
+
+func TestFetchEvents_ModifiedBy(t *testing.T) {
+	t.Parallel()
+	start := time.Date(2024, 1, 15, 14, 0, 0, 0, time.UTC)
+
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		byAlice := testAcceptedEvent("by-alice", start, start.Add(time.Hour))
+		byAlice.Organizer = &calendar.EventOrganizer{Email: "alice@example.com"}
+		byBob := testAcceptedEvent("by-bob", start, start.Add(time.Hour))
+		byBob.Organizer = &calendar.EventOrganizer{Email: "bob@example.com"}
+		noOrganizer := testAcceptedEvent("no-organizer", start, start.Add(time.Hour))
+
+		writeTestJSON(t, w, &calendar.Events{
+			Items: []*calendar.Event{byAlice, byBob, noOrganizer},
+		})
+	}))
+
+	tests := []struct {
+		name       string
+		modifiedBy string
+		want       []string
+	}{
+		{
+			name:       "no filter",
+			modifiedBy: "",
+			want:       []string{"by-alice", "by-bob", "no-organizer"},
+		},
+		{
+			name:       "matches organizer case-insensitively",
+			modifiedBy: "Alice@Example.com",
+			want:       []string{"by-alice"},
+		},
+		{
+			name:       "no matches",
+			modifiedBy: "carol@example.com",
+			want:       nil,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt // Capture loop variable
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := *client
+			c.Options.ModifiedBy = tt.modifiedBy
+
+			got := c.FetchUpcomingEvents(context.Background(), nil, 24)
+			if !got.Success {
+				t.Fatalf("FetchUpcomingEvents() failed: %s", got.Message)
+			}
+
+			var gotIDs []string
+			for _, e := range got.Events {
+				gotIDs = append(gotIDs, e.ID)
+			}
+			if diff := cmp.Diff(gotIDs, tt.want); diff != "" {
+				t.Errorf("FetchUpcomingEvents() IDs mismatch (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestComputeGaps(t *testing.T) {
+	t.Parallel()
+	baseTime := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+
+	at := func(id string, startMin, endMin int) Event {
+		return Event{
+			ID:    id,
+			Start: baseTime.Add(time.Duration(startMin) * time.Minute).Format(time.RFC3339),
+			End:   baseTime.Add(time.Duration(endMin) * time.Minute).Format(time.RFC3339),
+		}
+	}
+
+	tests := []struct {
+		name   string
+		events []Event
+		want   []int
+	}{
+		{
+			name: "varying gaps with back-to-back",
+			events: []Event{
+				at("standup", 0, 15),
+				at("design", 30, 90),
+				at("review", 90, 120),
+				at("lunch", 180, 240),
+			},
+			want: []int{-1, 15, 0, 60},
+		},
+		{
+			name: "overlap measures from the latest end",
+			events: []Event{
+				at("long", 0, 120),
+				at("inside", 30, 60),
+				at("after", 135, 150),
+			},
+			want: []int{-1, 0, 15},
+		},
+		{
+			name: "unparseable events are skipped",
+			events: []Event{
+				at("first", 0, 30),
+				{ID: "bad", Start: "not-a-time", End: "not-a-time"},
+				at("second", 45, 60),
+			},
+			want: []int{-1, -1, 15},
+		},
+		{
+			name: "marker has a gap and ends at its instant",
+			events: []Event{
+				at("first", 0, 30),
+				at("deadline", 60, 60),
+				at("second", 75, 90),
+			},
+			want: []int{-1, 30, 15},
+		},
+		{
+			name: "reversed times are skipped",
+			events: []Event{
+				at("first", 0, 30),
+				at("reversed", 60, 45),
+				at("second", 45, 60),
+			},
+			want: []int{-1, -1, 15},
+		},
+		{
+			name:   "empty",
+			events: []Event{},
+			want:   []int{},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt // Capture loop variable
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			events := make([]Event, len(tt.events))
+			copy(events, tt.events)
+
+			computeGaps(events, time.UTC)
+
+			got := make([]int, len(events))
+			for i := range events {
+				got[i] = events[i].GapBeforeMinutes
+			}
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("computeGaps() GapBeforeMinutes mismatch (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDetectConflicts_AllDay(t *testing.T) {
+	t.Parallel()
+
+	newEvents := func() []Event {
+		return []Event{
+			{ID: "sprint-week", Title: "Sprint week", Start: "2024-01-15", End: "2024-01-20"},
+			{ID: "standup", Start: "2024-01-15T09:00:00Z", End: "2024-01-15T09:15:00Z"},
+			{ID: "review", Start: "2024-01-16T14:00:00Z", End: "2024-01-16T15:00:00Z"},
+		}
+	}
+
+	tests := []struct {
+		name string
+		opts FetchOptions
+		want []bool
+	}{
+		{
+			name: "all-day never conflicts by default",
+			opts: DefaultFetchOptions(),
+			want: []bool{false, false, false},
+		},
+		{
+			name: "all-day conflicts when disabled",
+			opts: FetchOptions{Location: time.UTC},
+			want: []bool{true, true, true},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt // Capture loop variable
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			events := newEvents()
+			detectConflicts(events, tt.opts)
+
+			got := make([]bool, len(events))
+			for i := range events {
+				got[i] = events[i].HasConflict
+			}
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("detectConflicts() HasConflict mismatch (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestListInstances(t *testing.T) {
+	t.Parallel()
+	start := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 21)
+
+	instance := func(week int) *calendar.Event {
+		s := start.AddDate(0, 0, 7*week)
+		return &calendar.Event{
+			Id:               fmt.Sprintf("standup_%d", week),
+			RecurringEventId: "standup",
+			Summary:          "Weekly standup",
+			Start:            &calendar.EventDateTime{DateTime: s.Format(time.RFC3339)},
+			End:              &calendar.EventDateTime{DateTime: s.Add(15 * time.Minute).Format(time.RFC3339)},
+		}
+	}
+
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/calendars/primary/events/standup/instances" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if got := r.URL.Query().Get("timeMin"); got != start.Format(time.RFC3339) {
+			t.Errorf("timeMin = %q, want %q", got, start.Format(time.RFC3339))
+		}
+		if got := r.URL.Query().Get("timeMax"); got != end.Format(time.RFC3339) {
+			t.Errorf("timeMax = %q, want %q", got, end.Format(time.RFC3339))
+		}
+
+		if r.URL.Query().Get("pageToken") == "" {
+			writeTestJSON(t, w, &calendar.Events{
+				Items:         []*calendar.Event{instance(0), instance(1)},
+				NextPageToken: "page2",
+			})
+			return
+		}
+		writeTestJSON(t, w, &calendar.Events{Items: []*calendar.Event{instance(2)}})
+	}))
+
+	got, err := client.ListInstances(context.Background(), "", "standup", start, end)
+	if err != nil {
+		t.Fatalf("ListInstances() error = %v", err)
+	}
+
+	var gotIDs []string
+	for _, e := range got {
+		gotIDs = append(gotIDs, e.ID)
+	}
+	if diff := cmp.Diff(gotIDs, []string{"standup_0", "standup_1", "standup_2"}); diff != "" {
+		t.Errorf("ListInstances() IDs mismatch (-got +want):\n%s", diff)
+	}
+	if got[2].Start != "2024-01-29T09:00:00Z" {
+		t.Errorf("ListInstances() last Start = %q, want %q", got[2].Start, "2024-01-29T09:00:00Z")
+	}
+}
+
+func TestListInstances_Error(t *testing.T) {
+	t.Parallel()
+
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeTestAPIError(w, http.StatusNotFound, "Not Found")
+	}))
+
+	now := time.Now()
+	if _, err := client.ListInstances(context.Background(), "primary", "missing", now, now.Add(time.Hour)); err == nil {
+		t.Error("ListInstances() error = nil, want error")
+	}
+}
+
+func TestFetchEvents_ShowDeletedTombstones(t *testing.T) {
+	t.Parallel()
+	start := time.Date(2024, 1, 15, 14, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name            string
+		showDeleted     bool
+		wantQuery       string
+		wantIDs         []string
+		wantTombstoneID string
+	}{
+		{
+			name:      "cancelled events dropped by default",
+			wantQuery: "",
+			wantIDs:   []string{"live"},
+		},
+		{
+			name:            "cancelled events returned as tombstones",
+			showDeleted:     true,
+			wantQuery:       "true",
+			wantIDs:         []string{"gone", "live"},
+			wantTombstoneID: "gone",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt // Capture loop variable
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var gotQuery string
+			client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotQuery = r.URL.Query().Get("showDeleted")
+				writeTestJSON(t, w, &calendar.Events{Items: []*calendar.Event{
+					testAcceptedEvent("live", start, start.Add(time.Hour)),
+					{Id: "gone", ICalUID: "gone@google.com", Status: "cancelled"},
+				}})
+			}))
+			client.Options.ShowDeleted = tt.showDeleted
+
+			got := client.FetchUpcomingEvents(context.Background(), nil, 24)
+			if !got.Success {
+				t.Fatalf("FetchUpcomingEvents() failed: %s", got.Message)
+			}
+			if gotQuery != tt.wantQuery {
+				t.Errorf("showDeleted = %q, want %q", gotQuery, tt.wantQuery)
+			}
+
+			var gotIDs []string
+			for _, e := range got.Events {
+				gotIDs = append(gotIDs, e.ID)
+				if e.Deleted != (e.ID == tt.wantTombstoneID) {
+					t.Errorf("event %s Deleted = %v", e.ID, e.Deleted)
+				}
+				if e.Deleted {
+					want := Event{ID: "gone", RawID: "gone", ICalUID: "gone@google.com", CalendarID: "primary", Deleted: true, GapBeforeMinutes: -1}
+					if diff := cmp.Diff(e, want); diff != "" {
+						t.Errorf("tombstone mismatch (-got +want):\n%s", diff)
+					}
+				}
+			}
+			if diff := cmp.Diff(gotIDs, tt.wantIDs); diff != "" {
+				t.Errorf("event IDs mismatch (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestConvertEvent_LocationInfo(t *testing.T) {
+	t.Parallel()
+	start := time.Date(2024, 1, 15, 14, 0, 0, 0, time.UTC)
+
+	item := testAcceptedEvent("event1", start, start.Add(time.Hour))
+	if got := convertEvent(item, FetchOptions{}); got.LocationInfo != nil {
+		t.Errorf("convertEvent() LocationInfo = %+v, want nil without a location", got.LocationInfo)
+	}
+
+	item.Location = "Building A - Room 201"
+	got := convertEvent(item, FetchOptions{})
+	if diff := cmp.Diff(got.LocationInfo, &LocationInfo{Building: "Building A", Room: "Room 201"}); diff != "" {
+		t.Errorf("convertEvent() LocationInfo mismatch (-got +want):\n%s", diff)
+	}
+}
+
+func TestListCalendars_Sort(t *testing.T) {
+	t.Parallel()
+
+	items := []*calendar.CalendarListEntry{
+		{Id: "team@example.com", Summary: "team"},
+		{Id: "holidays@example.com", Summary: "Holidays"},
+		{Id: "me@example.com", Summary: "Me", Primary: true},
+		{Id: "shared@example.com", Summary: "Zeta", SummaryOverride: "Alpha (shared)"},
+	}
+
+	tests := []struct {
+		name string
+		opts CalendarListOptions
+		want []string
+	}{
+		{
+			name: "primary first then alphabetical by default",
+			opts: DefaultCalendarListOptions(),
+			want: []string{"me@example.com", "shared@example.com", "holidays@example.com", "team@example.com"},
+		},
+		{
+			name: "alphabetical only",
+			opts: CalendarListOptions{SortBy: CalendarSortName},
+			want: []string{"shared@example.com", "holidays@example.com", "me@example.com", "team@example.com"},
+		},
+		{
+			name: "API order with primary first",
+			opts: CalendarListOptions{PrimaryFirst: true},
+			want: []string{"me@example.com", "team@example.com", "holidays@example.com", "shared@example.com"},
+		},
+		{
+			name: "API order",
+			opts: CalendarListOptions{},
+			want: []string{"team@example.com", "holidays@example.com", "me@example.com", "shared@example.com"},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt // Capture loop variable
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				writeTestJSON(t, w, &calendar.CalendarList{Items: items})
+			}))
+			client.CalendarOptions = tt.opts
+
+			got := client.ListCalendars(context.Background())
+			if !got.Success {
+				t.Fatalf("ListCalendars() failed: %s", got.Message)
+			}
+
+			var gotIDs []string
+			for _, c := range got.Calendars {
+				gotIDs = append(gotIDs, c.ID)
+			}
+			if diff := cmp.Diff(gotIDs, tt.want); diff != "" {
+				t.Errorf("ListCalendars() order mismatch (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestConvertEvent_RawID(t *testing.T) {
+	t.Parallel()
+	start := time.Date(2024, 1, 15, 14, 0, 0, 0, time.UTC)
+
+	item := testAcceptedEvent("abc123_20240115T140000Z", start, start.Add(time.Hour))
+	event := convertEvent(item, FetchOptions{})
+	if event.RawID != item.Id {
+		t.Fatalf("convertEvent() RawID = %q, want %q", event.RawID, item.Id)
+	}
+
+	// RawID survives merging and collapsing recurring instances
+	later := testAcceptedEvent("abc123_20240116T140000Z", start.AddDate(0, 0, 1), start.AddDate(0, 0, 1).Add(time.Hour))
+	item.RecurringEventId, later.RecurringEventId = "abc123", "abc123"
+	merged := MergeResponses(
+		Response{Success: true, Events: []Event{*convertEvent(later, FetchOptions{})}},
+		Response{Success: true, Events: []Event{*convertEvent(item, FetchOptions{})}},
+	)
+	collapsed := collapseRecurring(merged.Events)
+	if len(collapsed) != 1 || collapsed[0].RawID != item.Id {
+		t.Errorf("RawID after merging and collapsing = %+v, want one event with RawID %q", collapsed, item.Id)
+	}
+
+	if got := eventFromAPI(item, "primary"); got.RawID != item.Id {
+		t.Errorf("eventFromAPI() RawID = %q, want %q", got.RawID, item.Id)
+	}
+}
+
+func TestConvertEvent_Status(t *testing.T) {
+	t.Parallel()
+	start := time.Date(2024, 1, 15, 14, 0, 0, 0, time.UTC)
+
+	withStatus := func(status string) *calendar.Event {
+		item := testAcceptedEvent(status, start, start.Add(time.Hour))
+		item.Status = status
+		return item
+	}
+
+	tests := []struct {
+		name       string
+		item       *calendar.Event
+		opts       FetchOptions
+		wantStatus string
+		wantNil    bool
+	}{
+		{
+			name:       "confirmed",
+			item:       withStatus("confirmed"),
+			wantStatus: "confirmed",
+		},
+		{
+			name:       "tentative kept by default",
+			item:       withStatus("tentative"),
+			wantStatus: "tentative",
+		},
+		{
+			name:    "tentative excluded",
+			item:    withStatus("tentative"),
+			opts:    FetchOptions{ExcludeTentative: true},
+			wantNil: true,
+		},
+		{
+			name:       "confirmed kept when excluding tentative",
+			item:       withStatus("confirmed"),
+			opts:       FetchOptions{ExcludeTentative: true},
+			wantStatus: "confirmed",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt // Capture loop variable
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := convertEvent(tt.item, tt.opts)
+			if (got == nil) != tt.wantNil {
+				t.Fatalf("convertEvent() returned nil = %v, want nil = %v", got == nil, tt.wantNil)
+			}
+			if got != nil && got.Status != tt.wantStatus {
+				t.Errorf("convertEvent() Status = %q, want %q", got.Status, tt.wantStatus)
+			}
+		})
+	}
+
+	if got := eventFromAPI(withStatus("tentative"), "primary"); got.Status != "tentative" {
+		t.Errorf("eventFromAPI() Status = %q, want %q", got.Status, "tentative")
+	}
+}
+
+func TestConvertEvent_IncludePersonal(t *testing.T) {
+	t.Parallel()
+	start := time.Date(2024, 1, 15, 14, 0, 0, 0, time.UTC)
+
+	personal := func(self *calendar.EventAttendee) *calendar.Event {
+		item := &calendar.Event{
+			Id:          "focus",
+			Summary:     "Focus time",
+			Description: "Pairing on https://meet.google.com/abc-defg-hij",
+			Start:       &calendar.EventDateTime{DateTime: start.Format(time.RFC3339)},
+			End:         &calendar.EventDateTime{DateTime: start.Add(time.Hour).Format(time.RFC3339)},
+		}
+		if self != nil {
+			item.Attendees = []*calendar.EventAttendee{self}
+		}
+		return item
+	}
+
+	tests := []struct {
+		name    string
+		item    *calendar.Event
+		opts    FetchOptions
+		wantNil bool
+	}{
+		{
+			name:    "no attendees excluded by default",
+			item:    personal(nil),
+			wantNil: true,
+		},
+		{
+			name:    "self-only excluded by default",
+			item:    personal(&calendar.EventAttendee{Self: true, ResponseStatus: "accepted"}),
+			wantNil: true,
+		},
+		{
+			name: "no attendees included",
+			item: personal(nil),
+			opts: FetchOptions{IncludePersonal: true},
+		},
+		{
+			name: "self-only accepted included",
+			item: personal(&calendar.EventAttendee{Self: true, ResponseStatus: "accepted"}),
+			opts: FetchOptions{IncludePersonal: true},
+		},
+		{
+			name:    "self-only declined excluded",
+			item:    personal(&calendar.EventAttendee{Self: true, ResponseStatus: "declined"}),
+			opts:    FetchOptions{IncludePersonal: true},
+			wantNil: true,
+		},
+		{
+			name: "self-only declined included with IncludeDeclined",
+			item: personal(&calendar.EventAttendee{Self: true, ResponseStatus: "declined"}),
+			opts: FetchOptions{IncludePersonal: true, IncludeDeclined: true},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt // Capture loop variable
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := convertEvent(tt.item, tt.opts)
+			if (got == nil) != tt.wantNil {
+				t.Fatalf("convertEvent() returned nil = %v, want nil = %v", got == nil, tt.wantNil)
+			}
+			if got == nil {
+				return
+			}
+			if got.AttendeeCount != 0 {
+				t.Errorf("convertEvent() AttendeeCount = %d, want 0", got.AttendeeCount)
+			}
+			if got.MeetingURL != "https://meet.google.com/abc-defg-hij" {
+				t.Errorf("convertEvent() MeetingURL = %q, want the Meet link", got.MeetingURL)
+			}
+		})
+	}
+}
+
+func TestFetchEvents_PersonalConflicts(t *testing.T) {
+	t.Parallel()
+	start := time.Date(2024, 1, 15, 14, 0, 0, 0, time.UTC)
+
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeTestJSON(t, w, &calendar.Events{Items: []*calendar.Event{
+			testAcceptedEvent("meeting", start, start.Add(time.Hour)),
+			{
+				Id:      "focus",
+				Summary: "Focus time",
+				Start:   &calendar.EventDateTime{DateTime: start.Add(30 * time.Minute).Format(time.RFC3339)},
+				End:     &calendar.EventDateTime{DateTime: start.Add(2 * time.Hour).Format(time.RFC3339)},
+			},
+		}})
+	}))
+	client.Options = FetchOptions{IncludePersonal: true}
+
+	got := client.FetchUpcomingEvents(context.Background(), nil, 24)
+	if !got.Success {
+		t.Fatalf("FetchUpcomingEvents() failed: %s", got.Message)
+	}
+	if len(got.Events) != 2 || !got.Events[0].HasConflict || !got.Events[1].HasConflict {
+		t.Errorf("FetchUpcomingEvents() = %+v, want meeting and focus time both conflicting", got.Events)
+	}
+}
+
+func TestConvertEvent_ResponseStatusFilters(t *testing.T) {
+	t.Parallel()
+	start := time.Date(2024, 1, 15, 14, 0, 0, 0, time.UTC)
+
+	statuses := []string{"accepted", "declined", "tentative", "needsAction"}
+	tests := []struct {
+		name string
+		opts FetchOptions
+		want []string // statuses kept
+	}{
+		{
+			name: "accepted only by default",
+			want: []string{"accepted"},
+		},
+		{
+			name: "declined",
+			opts: FetchOptions{IncludeDeclined: true},
+			want: []string{"accepted", "declined"},
+		},
+		{
+			name: "tentative",
+			opts: FetchOptions{IncludeTentative: true},
+			want: []string{"accepted", "tentative"},
+		},
+		{
+			name: "needs action",
+			opts: FetchOptions{IncludeNeedsAction: true},
+			want: []string{"accepted", "needsAction"},
+		},
+		{
+			name: "declined and tentative",
+			opts: FetchOptions{IncludeDeclined: true, IncludeTentative: true},
+			want: []string{"accepted", "declined", "tentative"},
+		},
+		{
+			name: "declined and needs action",
+			opts: FetchOptions{IncludeDeclined: true, IncludeNeedsAction: true},
+			want: []string{"accepted", "declined", "needsAction"},
+		},
+		{
+			name: "tentative and needs action",
+			opts: FetchOptions{IncludeTentative: true, IncludeNeedsAction: true},
+			want: []string{"accepted", "tentative", "needsAction"},
+		},
+		{
+			name: "all",
+			opts: FetchOptions{IncludeDeclined: true, IncludeTentative: true, IncludeNeedsAction: true},
+			want: statuses,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt // Capture loop variable
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var kept []string
+			for _, status := range statuses {
+				item := testAcceptedEvent(status, start, start.Add(time.Hour))
+				for _, a := range item.Attendees {
+					if a.Self {
+						a.ResponseStatus = status
+					}
+				}
+				if got := convertEvent(item, tt.opts); got != nil {
+					if got.ResponseStatus != status {
+						t.Errorf("convertEvent() ResponseStatus = %q, want %q", got.ResponseStatus, status)
+					}
+					kept = append(kept, got.ResponseStatus)
+				}
+			}
+			if diff := cmp.Diff(kept, tt.want); diff != "" {
+				t.Errorf("kept statuses mismatch (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestConvertEvent_RemovedFromMeeting(t *testing.T) {
+	t.Parallel()
+	start := time.Date(2024, 1, 15, 14, 0, 0, 0, time.UTC)
+
+	removed := func() *calendar.Event {
+		return &calendar.Event{
+			Id:        "removed",
+			Summary:   "Planning",
+			Start:     &calendar.EventDateTime{DateTime: start.Format(time.RFC3339)},
+			End:       &calendar.EventDateTime{DateTime: start.Add(time.Hour).Format(time.RFC3339)},
+			Organizer: &calendar.EventOrganizer{Email: "alice@example.com"},
+			Attendees: []*calendar.EventAttendee{
+				{Email: "alice@example.com", DisplayName: "Alice", Organizer: true, ResponseStatus: "accepted"},
+				{Email: "bob@example.com", DisplayName: "Bob"},
+			},
+		}
+	}
+
+	tests := []struct {
+		name        string
+		item        *calendar.Event
+		opts        FetchOptions
+		wantNil     bool
+		wantRemoved bool
+	}{
+		{
+			name:    "skipped by default",
+			item:    removed(),
+			wantNil: true,
+		},
+		{
+			name:        "flagged when enabled",
+			item:        removed(),
+			opts:        FetchOptions{IncludeRemovedMeetings: true},
+			wantRemoved: true,
+		},
+		{
+			name: "own event without self attendee is not flagged",
+			item: func() *calendar.Event {
+				item := removed()
+				item.Organizer = &calendar.EventOrganizer{Email: "me@example.com", Self: true}
+				return item
+			}(),
+			opts:    FetchOptions{IncludeRemovedMeetings: true},
+			wantNil: true,
+		},
+		{
+			name:        "accepted meeting is not flagged",
+			item:        testAcceptedEvent("accepted", start, start.Add(time.Hour)),
+			opts:        FetchOptions{IncludeRemovedMeetings: true},
+			wantRemoved: false,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt // Capture loop variable
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := convertEvent(tt.item, tt.opts)
+			if (got == nil) != tt.wantNil {
+				t.Fatalf("convertEvent() returned nil = %v, want nil = %v", got == nil, tt.wantNil)
+			}
+			if got != nil && got.RemovedFromMeeting != tt.wantRemoved {
+				t.Errorf("convertEvent() RemovedFromMeeting = %v, want %v", got.RemovedFromMeeting, tt.wantRemoved)
+			}
+		})
+	}
+}
+
+func TestMergeResponses(t *testing.T) {
+	t.Parallel()
+
+	work := Response{
+		Success:  true,
+		LastSync: "2024-01-15T08:05:00Z",
+		Events: []Event{
+			{ID: "standup", CalendarID: "work", Start: "2024-01-15T09:00:00Z", End: "2024-01-15T09:30:00Z"},
+			{ID: "review", CalendarID: "work", Start: "2024-01-15T14:00:00Z", End: "2024-01-15T15:00:00Z"},
+		},
+	}
+	personal := Response{
+		Success:  true,
+		LastSync: "2024-01-15T08:00:00Z",
+		Events: []Event{
+			{ID: "dentist", CalendarID: "personal", Start: "2024-01-15T14:30:00Z", End: "2024-01-15T15:30:00Z"},
+		},
+	}
+	failed := NewErrorResponse(ErrTokenExpired, "token expired for profile personal")
+
+	t.Run("two successes", func(t *testing.T) {
+		t.Parallel()
+
+		got := MergeResponses(work, personal)
+		if !got.Success || got.Error != "" {
+			t.Fatalf("MergeResponses() = %+v, want success", got)
+		}
+		if got.LastSync != "2024-01-15T08:00:00Z" {
+			t.Errorf("MergeResponses() LastSync = %q, want the older sync", got.LastSync)
+		}
+
+		type summary struct {
+			ID          string
+			HasConflict bool
+		}
+		var gotEvents []summary
+		for _, e := range got.Events {
+			gotEvents = append(gotEvents, summary{e.ID, e.HasConflict})
+		}
+		want := []summary{{"standup", false}, {"review", true}, {"dentist", true}}
+		if diff := cmp.Diff(gotEvents, want); diff != "" {
+			t.Errorf("MergeResponses() events mismatch (-got +want):\n%s", diff)
+		}
+
+		if work.Events[1].HasConflict {
+			t.Error("MergeResponses() modified its input")
+		}
+	})
+
+	t.Run("success and error", func(t *testing.T) {
+		t.Parallel()
+
+		got := MergeResponses(work, failed)
+		if got.Success {
+			t.Error("MergeResponses() Success = true, want false when one response failed")
+		}
+		if got.Error != ErrTokenExpired || got.Message != failed.Message {
+			t.Errorf("MergeResponses() error = %q %q, want %q %q", got.Error, got.Message, ErrTokenExpired, failed.Message)
+		}
+		if len(got.Events) != len(work.Events) {
+			t.Errorf("MergeResponses() kept %d events, want the %d from the successful response", len(got.Events), len(work.Events))
+		}
+	})
+}
+
+func TestMergeResponsesWithOptions(t *testing.T) {
+	t.Parallel()
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("LoadLocation() error = %v", err)
+	}
+
+	// Annotated under other options: lunch flagged, gaps from a stale order
+	work := Response{
+		Success: true,
+		Events: []Event{
+			{ID: "lunch-sync", Start: "2024-01-15T12:00:00-05:00", End: "2024-01-15T12:30:00-05:00", DuringLunch: true, GapBeforeMinutes: 42},
+			{ID: "reminder", Start: "2024-01-15T12:15:00-05:00", End: "2024-01-15T12:15:00-05:00", Marker: true},
+		},
+	}
+	personal := Response{
+		Success: true,
+		Events: []Event{
+			{ID: "gym", Start: "2024-01-15T07:00:00-05:00", End: "2024-01-15T08:00:00-05:00", DuringLunch: true},
+		},
+	}
+
+	type summary struct {
+		ID          string
+		HasConflict bool
+		Marker      bool
+		DuringLunch bool
+		Gap         int
+	}
+	tests := []struct {
+		name string
+		opts FetchOptions
+		want []summary
+	}{
+		{
+			name: "stale annotations cleared",
+			opts: FetchOptions{Location: ny},
+			want: []summary{
+				{ID: "gym", Gap: -1},
+				{ID: "lunch-sync", HasConflict: true, Gap: 240},
+				{ID: "reminder", HasConflict: true, Gap: 0},
+			},
+		},
+		{
+			name: "recomputed with the given options, latest first",
+			opts: FetchOptions{Location: ny, FlagLunchMeetings: true, ZeroDurationMarkers: true, Descending: true},
+			want: []summary{
+				{ID: "reminder", Marker: true, Gap: 0},
+				{ID: "lunch-sync", DuringLunch: true, Gap: 240},
+				{ID: "gym", Gap: -1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt // Capture loop variable
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := MergeResponsesWithOptions(work, personal, tt.opts)
+			var gotEvents []summary
+			for _, e := range got.Events {
+				gotEvents = append(gotEvents, summary{e.ID, e.HasConflict, e.Marker, e.DuringLunch, e.GapBeforeMinutes})
+			}
+			if diff := cmp.Diff(gotEvents, tt.want); diff != "" {
+				t.Errorf("MergeResponsesWithOptions() events mismatch (-got +want):\n%s", diff)
+			}
+		})
+	}
+
+	if !work.Events[0].DuringLunch || work.Events[0].GapBeforeMinutes != 42 {
+		t.Error("MergeResponsesWithOptions() modified its input")
+	}
+}
+
+func TestFetchEvents_TitlePatterns(t *testing.T) {
+	t.Parallel()
+	start := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+
+	titles := []string{"Team Lunch", "Sprint planning", "lunch & learn", "Focus time"}
+
+	tests := []struct {
+		name       string
+		opts       FetchOptions
+		wantTitles []string
+	}{
+		{
+			name:       "no patterns",
+			wantTitles: titles,
+		},
+		{
+			name:       "exclude lunch",
+			opts:       FetchOptions{ExcludeTitlePatterns: []*regexp.Regexp{regexp.MustCompile(`(?i)lunch`)}},
+			wantTitles: []string{"Sprint planning", "Focus time"},
+		},
+		{
+			name:       "nil patterns ignored",
+			opts:       FetchOptions{ExcludeTitlePatterns: []*regexp.Regexp{nil, regexp.MustCompile(`^Focus`)}},
+			wantTitles: []string{"Team Lunch", "Sprint planning", "lunch & learn"},
+		},
+		{
+			name:       "include only",
+			opts:       FetchOptions{IncludeTitlePatterns: []*regexp.Regexp{regexp.MustCompile(`(?i)lunch`)}},
+			wantTitles: []string{"Team Lunch", "lunch & learn"},
+		},
+		{
+			name: "exclude wins over include",
+			opts: FetchOptions{
+				IncludeTitlePatterns: []*regexp.Regexp{regexp.MustCompile(`(?i)lunch`), regexp.MustCompile(`^Sprint`)},
+				ExcludeTitlePatterns: []*regexp.Regexp{regexp.MustCompile(`learn`)},
+			},
+			wantTitles: []string{"Team Lunch", "Sprint planning"},
+		},
+		{
+			name:       "only nil include patterns match nothing",
+			opts:       FetchOptions{IncludeTitlePatterns: []*regexp.Regexp{nil}},
+			wantTitles: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt // Capture loop variable
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var items []*calendar.Event
+				for i, title := range titles {
+					s := start.Add(time.Duration(i) * time.Hour)
+					item := testAcceptedEvent(title, s, s.Add(30*time.Minute))
+					items = append(items, item)
+				}
+				writeTestJSON(t, w, &calendar.Events{Items: items})
+			}))
+			client.Options = tt.opts
+
+			got := client.FetchUpcomingEvents(context.Background(), nil, 24)
+			if !got.Success {
+				t.Fatalf("FetchUpcomingEvents() failed: %s", got.Message)
+			}
+
+			var gotTitles []string
+			for _, e := range got.Events {
+				gotTitles = append(gotTitles, e.Title)
+			}
+			if diff := cmp.Diff(gotTitles, tt.wantTitles); diff != "" {
+				t.Errorf("FetchUpcomingEvents() titles mismatch (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFetchEvents_CollapseRecurring(t *testing.T) {
+	t.Parallel()
+	start := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var items []*calendar.Event
+		for day := 0; day < 3; day++ {
+			s := start.AddDate(0, 0, day)
+			item := testAcceptedEvent(fmt.Sprintf("standup_%d", day), s, s.Add(15*time.Minute))
+			item.RecurringEventId = "standup"
+			items = append(items, item)
+		}
+		oneOff := start.Add(2 * time.Hour)
+		items = append(items, testAcceptedEvent("offsite", oneOff, oneOff.Add(time.Hour)))
+		writeTestJSON(t, w, &calendar.Events{Items: items})
+	}))
+	client.Options.CollapseRecurring = true
+
+	got := client.FetchUpcomingEvents(context.Background(), nil, 24)
+	if !got.Success {
+		t.Fatalf("FetchUpcomingEvents() failed: %s", got.Message)
+	}
+
+	type collapsed struct {
+		ID              string
+		OccurrenceCount int
+	}
+	var gotEvents []collapsed
+	for _, e := range got.Events {
+		gotEvents = append(gotEvents, collapsed{e.ID, e.OccurrenceCount})
+	}
+	want := []collapsed{
+		{ID: "standup_0", OccurrenceCount: 3},
+		{ID: "offsite", OccurrenceCount: 0},
+	}
+	if diff := cmp.Diff(gotEvents, want); diff != "" {
+		t.Errorf("FetchUpcomingEvents() mismatch (-got +want):\n%s", diff)
+	}
+}
+
+func TestConvertEvent_IncludeAllDay(t *testing.T) {
+	t.Parallel()
+
+	vacation := &calendar.Event{
+		Id:      "vacation",
+		Summary: "Vacation",
+		Start:   &calendar.EventDateTime{Date: "2024-01-15"},
+		End:     &calendar.EventDateTime{Date: "2024-01-17"},
+	}
+
+	if got := convertEvent(vacation, FetchOptions{}); got != nil {
+		t.Errorf("convertEvent() without IncludeAllDay = %+v, want nil", got)
+	}
+
+	got := convertEvent(vacation, FetchOptions{IncludeAllDay: true})
+	if got == nil {
+		t.Fatal("convertEvent() with IncludeAllDay = nil, want event")
+	}
+	if !got.AllDay || got.Start != "2024-01-15" || got.End != "2024-01-17" {
+		t.Errorf("convertEvent() AllDay, Start, End = %v, %q, %q, want true, 2024-01-15, 2024-01-17", got.AllDay, got.Start, got.End)
+	}
+
+	// All-day events without attendees still go through the URL filter
+	// and annotations
+	offsite := &calendar.Event{
+		Id:       "offsite",
+		Summary:  "Offsite",
+		Location: "https://meet.google.com/abc-defg-hij",
+		Start:    &calendar.EventDateTime{Date: "2024-01-15"},
+		End:      &calendar.EventDateTime{Date: "2024-01-16"},
+	}
+	requireURL := FetchOptions{IncludeAllDay: true, RequireMeetingURL: true}
+	if got := convertEvent(vacation, requireURL); got != nil {
+		t.Errorf("convertEvent() URL-less all-day event with RequireMeetingURL = %+v, want nil", got)
+	}
+	got = convertEvent(offsite, requireURL)
+	if got == nil {
+		t.Fatal("convertEvent() all-day event with a URL and RequireMeetingURL = nil, want event")
+	}
+	if got.MeetingURL != offsite.Location || got.Provider != "meet" || got.LocationInfo == nil {
+		t.Errorf("convertEvent() MeetingURL, Provider, LocationInfo = %q, %q, %v, want %q, meet, non-nil", got.MeetingURL, got.Provider, got.LocationInfo, offsite.Location)
+	}
+
+	declined := &calendar.Event{
+		Id:      "offsite",
+		Summary: "Offsite",
+		Start:   &calendar.EventDateTime{Date: "2024-01-15"},
+		End:     &calendar.EventDateTime{Date: "2024-01-16"},
+		Attendees: []*calendar.EventAttendee{
+			{Self: true, ResponseStatus: "declined"},
+			{Email: "alice@example.com"},
+		},
+	}
+	if got := convertEvent(declined, FetchOptions{IncludeAllDay: true}); got != nil {
+		t.Errorf("convertEvent() declined all-day invite = %+v, want nil", got)
+	}
+}
+
+func TestFetchEvents_IncludeAllDay(t *testing.T) {
+	t.Parallel()
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("LoadLocation() error = %v", err)
+	}
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeTestJSON(t, w, &calendar.Events{Items: []*calendar.Event{
+			{
+				Id:      "holiday",
+				Summary: "Holiday",
+				Start:   &calendar.EventDateTime{Date: "2024-01-15"},
+				End:     &calendar.EventDateTime{Date: "2024-01-16"},
+			},
+			// 22:00 on Jan 14 in New York, though its UTC string sorts
+			// after "2024-01-15"
+			testAcceptedEvent("evening-before", time.Date(2024, 1, 15, 3, 0, 0, 0, time.UTC), time.Date(2024, 1, 15, 4, 0, 0, 0, time.UTC)),
+			testAcceptedEvent("morning", time.Date(2024, 1, 15, 9, 0, 0, 0, ny), time.Date(2024, 1, 15, 10, 0, 0, 0, ny)),
+		}})
+	})
+
+	type flags struct {
+		ID          string
+		AllDay      bool
+		HasConflict bool
+		Gap         int
+	}
+
+	tests := []struct {
+		name string
+		opts FetchOptions
+		want []flags
+	}{
+		{
+			name: "all-day excluded by default",
+			opts: FetchOptions{Location: ny, AllDayNeverConflicts: true},
+			want: []flags{
+				{ID: "evening-before", Gap: -1},
+				{ID: "morning", Gap: 10 * 60},
+			},
+		},
+		{
+			name: "included without conflicts",
+			opts: FetchOptions{Location: ny, AllDayNeverConflicts: true, IncludeAllDay: true},
+			want: []flags{
+				{ID: "evening-before", Gap: -1},
+				{ID: "holiday", AllDay: true, Gap: -1},
+				{ID: "morning", Gap: 10 * 60},
+			},
+		},
+		{
+			name: "included with conflicts anchored in Location",
+			opts: FetchOptions{Location: ny, IncludeAllDay: true},
+			want: []flags{
+				{ID: "evening-before", Gap: -1},
+				{ID: "holiday", AllDay: true, HasConflict: true, Gap: -1},
+				{ID: "morning", HasConflict: true, Gap: 10 * 60},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt // Capture loop variable
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			client := newTestClient(t, handler)
+			client.Options = tt.opts
+
+			got := client.FetchUpcomingEvents(context.Background(), nil, 24)
+			if !got.Success {
+				t.Fatalf("FetchUpcomingEvents() failed: %s", got.Message)
+			}
+
+			var gotFlags []flags
+			for _, e := range got.Events {
+				gotFlags = append(gotFlags, flags{e.ID, e.AllDay, e.HasConflict, e.GapBeforeMinutes})
+			}
+			if diff := cmp.Diff(gotFlags, tt.want); diff != "" {
+				t.Errorf("FetchUpcomingEvents() mismatch (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFetchEvents_FreeBusyPlaceholders(t *testing.T) {
+	t.Parallel()
+	start := time.Date(2024, 1, 15, 14, 0, 0, 0, time.UTC)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/calendars/boss@example.com/events":
+			// Free/busy access: times only, no title or attendees
+			writeTestJSON(t, w, &calendar.Events{
+				AccessRole: accessRoleFreeBusyReader,
+				Items: []*calendar.Event{
+					{
+						Id:    "busy1",
+						Start: &calendar.EventDateTime{DateTime: start.Format(time.RFC3339)},
+						End:   &calendar.EventDateTime{DateTime: start.Add(time.Hour).Format(time.RFC3339)},
+					},
+					{
+						Id:     "gone",
+						Status: "cancelled",
+						Start:  &calendar.EventDateTime{DateTime: start.Format(time.RFC3339)},
+						End:    &calendar.EventDateTime{DateTime: start.Add(time.Hour).Format(time.RFC3339)},
+					},
+				},
+			})
+		case "/calendars/team@example.com/events":
+			writeTestJSON(t, w, &calendar.Events{
+				AccessRole: "reader",
+				Items: []*calendar.Event{
+					{
+						Id:      "solo",
+						Summary: "Focus",
+						Start:   &calendar.EventDateTime{DateTime: start.Format(time.RFC3339)},
+						End:     &calendar.EventDateTime{DateTime: start.Add(time.Hour).Format(time.RFC3339)},
+					},
+				},
+			})
+		default:
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+	})
+
+	client := newTestClient(t, handler)
+	got := client.FetchUpcomingEvents(context.Background(), []string{"boss@example.com", "team@example.com"}, 24)
+	if !got.Success {
+		t.Fatalf("FetchUpcomingEvents() failed: %s", got.Message)
+	}
+
+	// Only the placeholder survives; events on readable calendars are
+	// still filtered as usual
+	want := []Event{{
+		ID:               "busy1",
+		RawID:            "busy1",
+		Title:            "Busy",
+		Start:            start.Format(time.RFC3339),
+		End:              start.Add(time.Hour).Format(time.RFC3339),
+		CalendarID:       "boss@example.com",
+		BusyPlaceholder:  true,
+		GapBeforeMinutes: -1,
+	}}
+	if diff := cmp.Diff(got.Events, want); diff != "" {
+		t.Errorf("FetchUpcomingEvents() mismatch (-got +want):\n%s", diff)
+	}
+}
+
+func TestFetchEvents_ResourceCalendar(t *testing.T) {
+	t.Parallel()
+	const room = "c_1888abc@resource.calendar.google.com"
+	start := time.Date(2024, 1, 15, 14, 0, 0, 0, time.UTC)
+	at := func(hours int) *calendar.EventDateTime {
+		return &calendar.EventDateTime{DateTime: start.Add(time.Duration(hours) * time.Hour).Format(time.RFC3339)}
+	}
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/calendars/"+room+"/events" {
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+		writeTestJSON(t, w, &calendar.Events{Items: []*calendar.Event{
+			{
+				// Booked by others; the room is an attendee, the user isn't
+				Id:      "booking",
+				Summary: "Design review",
+				Start:   at(0),
+				End:     at(1),
+				Attendees: []*calendar.EventAttendee{
+					{Email: "alice@example.com", DisplayName: "Alice", Organizer: true, ResponseStatus: "accepted"},
+					{Email: room, DisplayName: "Room 301", Resource: true, ResponseStatus: "accepted"},
+				},
+			},
+			{
+				// A hold with no attendees listed
+				Id:      "hold",
+				Summary: "Reserved",
+				Start:   at(2),
+				End:     at(3),
+			},
+			{
+				Id:      "cancelled",
+				Status:  "cancelled",
+				Summary: "Old booking",
+				Start:   at(4),
+				End:     at(5),
+			},
+		}})
+	})
+
+	tests := []struct {
+		name string
+		opts FetchOptions
+		want []string
+	}{
+		{
+			name: "dropped without the option",
+		},
+		{
+			name: "bookings kept",
+			opts: FetchOptions{TreatAsResourceCalendar: true},
+			want: []string{"booking", "hold"},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt // Capture loop variable
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			client := newTestClient(t, handler)
+			client.Options = tt.opts
+
+			got := client.FetchUpcomingEvents(context.Background(), []string{room}, 24)
+			if !got.Success {
+				t.Fatalf("FetchUpcomingEvents() failed: %s", got.Message)
+			}
+
+			var ids []string
+			for _, e := range got.Events {
+				ids = append(ids, e.ID)
+			}
+			if diff := cmp.Diff(ids, tt.want); diff != "" {
+				t.Errorf("FetchUpcomingEvents() IDs mismatch (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFetchEvents_DuringLunch(t *testing.T) {
+	t.Parallel()
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("LoadLocation() error = %v", err)
+	}
+	at := func(hour, min int) time.Time {
+		return time.Date(2024, 1, 15, hour, min, 0, 0, ny)
+	}
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeTestJSON(t, w, &calendar.Events{Items: []*calendar.Event{
+			// Noon in UTC is early morning in New York
+			testAcceptedEvent("utc-noon", time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC), time.Date(2024, 1, 15, 12, 30, 0, 0, time.UTC)),
+			testAcceptedEvent("eleven", at(11, 0), at(12, 0)),
+			testAcceptedEvent("noon", at(12, 0), at(12, 30)),
+			testAcceptedEvent("straddles-end", at(12, 45), at(13, 30)),
+			testAcceptedEvent("afternoon", at(13, 30), at(14, 0)),
+		}})
+	})
+
+	tests := []struct {
+		name string
+		opts FetchOptions
+		want []string
+	}{
+		{
+			name: "not flagged by default",
+			opts: FetchOptions{Location: ny},
+		},
+		{
+			name: "default window",
+			opts: FetchOptions{Location: ny, FlagLunchMeetings: true},
+			want: []string{"noon", "straddles-end"},
+		},
+		{
+			name: "custom window",
+			opts: FetchOptions{Location: ny, FlagLunchMeetings: true, LunchStart: 11*time.Hour + 30*time.Minute, LunchEnd: 12 * time.Hour},
+			want: []string{"eleven"},
+		},
+		{
+			name: "only start set",
+			opts: FetchOptions{Location: ny, FlagLunchMeetings: true, LunchStart: 12*time.Hour + 45*time.Minute},
+			want: []string{"straddles-end", "afternoon"},
+		},
+		{
+			name: "only end set",
+			opts: FetchOptions{Location: ny, FlagLunchMeetings: true, LunchEnd: 12 * time.Hour},
+			want: []string{"eleven"},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt // Capture loop variable
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			client := newTestClient(t, handler)
+			client.Options = tt.opts
+
+			got := client.FetchUpcomingEvents(context.Background(), nil, 24)
+			if !got.Success {
+				t.Fatalf("FetchUpcomingEvents() failed: %s", got.Message)
+			}
+
+			var flagged []string
+			for _, e := range got.Events {
+				if e.DuringLunch {
+					flagged = append(flagged, e.ID)
+				}
+			}
+			if diff := cmp.Diff(flagged, tt.want); diff != "" {
+				t.Errorf("DuringLunch events mismatch (-got +want):\n%s", diff)
+			}
+		})
+	}
+
+	t.Run("inverted window", func(t *testing.T) {
+		t.Parallel()
+
+		client := newTestClient(t, handler)
+		client.Options = FetchOptions{Location: ny, FlagLunchMeetings: true, LunchStart: 13 * time.Hour, LunchEnd: 12 * time.Hour}
+
+		got := client.FetchUpcomingEvents(context.Background(), nil, 24)
+		if got.Success || got.Error != ErrInvalidConfig {
+			t.Errorf("FetchUpcomingEvents() = %v %q, want %s", got.Success, got.Error, ErrInvalidConfig)
+		}
+	})
+}
+
+func TestFetchEvents_ZeroDurationMarkers(t *testing.T) {
+	t.Parallel()
+	start := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeTestJSON(t, w, &calendar.Events{Items: []*calendar.Event{
+			testAcceptedEvent("range", start, start.Add(time.Hour)),
+			testAcceptedEvent("marker", start.Add(30*time.Minute), start.Add(30*time.Minute)),
+		}})
+	})
+
+	type flags struct {
+		Marker      bool
+		HasConflict bool
+	}
+	tests := []struct {
+		name string
+		opts FetchOptions
+		want map[string]flags
+	}{
+		{
+			name: "conflicts by default",
+			want: map[string]flags{
+				"range":  {HasConflict: true},
+				"marker": {HasConflict: true},
+			},
+		},
+		{
+			name: "markers never conflict",
+			opts: FetchOptions{ZeroDurationMarkers: true},
+			want: map[string]flags{
+				"range":  {},
+				"marker": {Marker: true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt // Capture loop variable
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			client := newTestClient(t, handler)
+			client.Options = tt.opts
+
+			got := client.FetchUpcomingEvents(context.Background(), nil, 24)
+			if !got.Success {
+				t.Fatalf("FetchUpcomingEvents() failed: %s", got.Message)
+			}
+
+			gotFlags := make(map[string]flags)
+			for _, e := range got.Events {
+				gotFlags[e.ID] = flags{Marker: e.Marker, HasConflict: e.HasConflict}
+			}
+			if diff := cmp.Diff(gotFlags, tt.want); diff != "" {
+				t.Errorf("event flags mismatch (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFetchEvents_DuringOOO(t *testing.T) {
+	t.Parallel()
+	start := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeTestJSON(t, w, &calendar.Events{Items: []*calendar.Event{
+			testAcceptedEvent("before", start, start.Add(time.Hour)),
+			{
+				Id:        "vacation",
+				Summary:   "Out of office",
+				EventType: eventTypeOutOfOffice,
+				Start:     &calendar.EventDateTime{DateTime: start.Add(2 * time.Hour).Format(time.RFC3339)},
+				End:       &calendar.EventDateTime{DateTime: start.Add(6 * time.Hour).Format(time.RFC3339)},
+			},
+			testAcceptedEvent("inside", start.Add(3*time.Hour), start.Add(4*time.Hour)),
+			testAcceptedEvent("straddles-end", start.Add(5*time.Hour+30*time.Minute), start.Add(7*time.Hour)),
+			testAcceptedEvent("after", start.Add(6*time.Hour+30*time.Minute), start.Add(8*time.Hour)),
+		}})
+	})
+
+	type flags struct {
+		ID          string
+		EventType   string
+		DuringOOO   bool
+		HasConflict bool
+	}
+
+	tests := []struct {
+		name string
+		opts FetchOptions
+		want []flags
+	}{
+		{
+			name: "out-of-office excluded by default",
+			want: []flags{
+				{ID: "before"},
+				{ID: "inside"},
+				{ID: "straddles-end", HasConflict: true},
+				{ID: "after", HasConflict: true},
+			},
+		},
+		{
+			name: "included and flagged",
+			opts: FetchOptions{IncludeOutOfOffice: true},
+			want: []flags{
+				{ID: "before"},
+				{ID: "vacation", EventType: eventTypeOutOfOffice},
+				{ID: "inside", DuringOOO: true},
+				{ID: "straddles-end", DuringOOO: true, HasConflict: true},
+				{ID: "after", HasConflict: true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt // Capture loop variable
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			client := newTestClient(t, handler)
+			client.Options = tt.opts
+
+			got := client.FetchUpcomingEvents(context.Background(), nil, 24)
+			if !got.Success {
+				t.Fatalf("FetchUpcomingEvents() failed: %s", got.Message)
+			}
+
+			var gotFlags []flags
+			for _, e := range got.Events {
+				gotFlags = append(gotFlags, flags{e.ID, e.EventType, e.DuringOOO, e.HasConflict})
+			}
+			if diff := cmp.Diff(gotFlags, tt.want); diff != "" {
+				t.Errorf("FetchUpcomingEvents() mismatch (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFetchEvents_RequireMeetingURL(t *testing.T) {
+	t.Parallel()
+	start := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		meet := testAcceptedEvent("meet", start, start.Add(time.Hour))
+		meet.HangoutLink = "https://meet.google.com/abc-defg-hij"
+		zoom := testAcceptedEvent("zoom", start.Add(2*time.Hour), start.Add(3*time.Hour))
+		zoom.Location = "https://us02web.zoom.us/j/123456789"
+		inPerson := testAcceptedEvent("in-person", start.Add(4*time.Hour), start.Add(5*time.Hour))
+		inPerson.Location = "Room 4B, Building 2"
+		writeTestJSON(t, w, &calendar.Events{Items: []*calendar.Event{meet, zoom, inPerson}})
+	})
+
+	tests := []struct {
+		name    string
+		opts    FetchOptions
+		wantIDs []string
+	}{
+		{
+			name:    "all meetings by default",
+			wantIDs: []string{"meet", "zoom", "in-person"},
+		},
+		{
+			name:    "only joinable meetings",
+			opts:    FetchOptions{RequireMeetingURL: true},
+			wantIDs: []string{"meet", "zoom"},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt // Capture loop variable
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			client := newTestClient(t, handler)
+			client.Options = tt.opts
+
+			got := client.FetchUpcomingEvents(context.Background(), nil, 24)
+			if !got.Success {
+				t.Fatalf("FetchUpcomingEvents() failed: %s", got.Message)
+			}
+
+			var gotIDs []string
+			for _, e := range got.Events {
+				gotIDs = append(gotIDs, e.ID)
+			}
+			if diff := cmp.Diff(gotIDs, tt.wantIDs); diff != "" {
+				t.Errorf("FetchUpcomingEvents() IDs mismatch (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFetchEvents_Descending(t *testing.T) {
+	t.Parallel()
+	start := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeTestJSON(t, w, &calendar.Events{Items: []*calendar.Event{
+			testAcceptedEvent("first", start, start.Add(time.Hour)),
+			testAcceptedEvent("overlaps-first", start.Add(30*time.Minute), start.Add(90*time.Minute)),
+			testAcceptedEvent("last", start.Add(3*time.Hour), start.Add(4*time.Hour)),
+		}})
+	})
+
+	type result struct {
+		ID          string
+		HasConflict bool
+	}
+	fetch := func(opts FetchOptions) []result {
+		client := newTestClient(t, handler)
+		client.Options = opts
+		got := client.FetchUpcomingEvents(context.Background(), nil, 24)
+		if !got.Success {
+			t.Fatalf("FetchUpcomingEvents() failed: %s", got.Message)
+		}
+		var results []result
+		for _, e := range got.Events {
+			results = append(results, result{e.ID, e.HasConflict})
+		}
+		return results
+	}
+
+	ascending := fetch(DefaultFetchOptions())
+	descending := fetch(FetchOptions{AllDayNeverConflicts: true, Descending: true})
+
+	want := []result{
+		{ID: "last", HasConflict: false},
+		{ID: "overlaps-first", HasConflict: true},
+		{ID: "first", HasConflict: true},
+	}
+	if diff := cmp.Diff(descending, want); diff != "" {
+		t.Errorf("Descending mismatch (-got +want):\n%s", diff)
+	}
+	for i := range ascending {
+		if ascending[i] != descending[len(descending)-1-i] {
+			t.Errorf("Descending result %d = %v, want reverse of ascending %v", i, descending, ascending)
+			break
+		}
+	}
+}
+
+func TestFetchEvents_IncludeCalendarNames(t *testing.T) {
+	t.Parallel()
+	start := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+
+	var listCalls int32
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/users/me/calendarList":
+			atomic.AddInt32(&listCalls, 1)
+			writeTestJSON(t, w, &calendar.CalendarList{Items: []*calendar.CalendarListEntry{
+				{Id: "me@example.com", Summary: "me@example.com", SummaryOverride: "Me", Primary: true},
+				{Id: "team@example.com", Summary: "Team"},
+			}})
+		case "/calendars/primary/events":
+			writeTestJSON(t, w, &calendar.Events{Items: []*calendar.Event{
+				testAcceptedEvent("mine", start, start.Add(time.Hour)),
+			}})
+		case "/calendars/team@example.com/events":
+			writeTestJSON(t, w, &calendar.Events{Items: []*calendar.Event{
+				testAcceptedEvent("team-1", start.Add(2*time.Hour), start.Add(3*time.Hour)),
+				testAcceptedEvent("team-2", start.Add(4*time.Hour), start.Add(5*time.Hour)),
+			}})
+		default:
+			writeTestAPIError(w, http.StatusNotFound, "Not Found")
+		}
+	}))
+	client.Options.IncludeCalendarNames = true
+
+	got := client.FetchUpcomingEvents(context.Background(), []string{"primary", "team@example.com", "unknown@example.com"}, 24)
+	if !got.Success {
+		t.Fatalf("FetchUpcomingEvents() failed: %s", got.Message)
+	}
+
+	gotNames := make(map[string]string)
+	for _, e := range got.Events {
+		gotNames[e.ID] = e.CalendarName
+	}
+	want := map[string]string{"mine": "Me", "team-1": "Team", "team-2": "Team"}
+	if diff := cmp.Diff(gotNames, want); diff != "" {
+		t.Errorf("FetchUpcomingEvents() calendar names mismatch (-got +want):\n%s", diff)
+	}
+	if n := atomic.LoadInt32(&listCalls); n != 1 {
+		t.Errorf("calendar list requested %d times, want 1", n)
+	}
+}
+
+func TestFetchEvents_ListOrder(t *testing.T) {
+	t.Parallel()
+	start := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name             string
+		opts             FetchOptions
+		wantOrderBy      string
+		wantSingleEvents string
+		wantErr          string
+	}{
+		{
+			name:             "default orders expanded instances by start",
+			wantOrderBy:      "startTime",
+			wantSingleEvents: "true",
+		},
+		{
+			name:             "recurring masters without ordering",
+			opts:             FetchOptions{RecurringMasters: true},
+			wantSingleEvents: "false",
+		},
+		{
+			name:             "recurring masters by update time",
+			opts:             FetchOptions{RecurringMasters: true, OrderBy: OrderByUpdated},
+			wantOrderBy:      "updated",
+			wantSingleEvents: "false",
+		},
+		{
+			name:    "start time order needs expanded instances",
+			opts:    FetchOptions{RecurringMasters: true, OrderBy: OrderByStartTime},
+			wantErr: "requires expanded instances",
+		},
+		{
+			name:    "unknown order",
+			opts:    FetchOptions{OrderBy: "title"},
+			wantErr: `unknown OrderBy "title"`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt // Capture loop variable
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.wantErr != "" {
+					t.Errorf("unexpected request for invalid options: %s", r.URL)
+				}
+				if got := r.URL.Query().Get("orderBy"); got != tt.wantOrderBy {
+					t.Errorf("orderBy = %q, want %q", got, tt.wantOrderBy)
+				}
+				if got := r.URL.Query().Get("singleEvents"); got != tt.wantSingleEvents {
+					t.Errorf("singleEvents = %q, want %q", got, tt.wantSingleEvents)
+				}
+				writeTestJSON(t, w, &calendar.Events{Items: []*calendar.Event{
+					testAcceptedEvent("a", start, start.Add(time.Hour)),
+				}})
+			}))
+			client.Options = tt.opts
+
+			got := client.FetchUpcomingEvents(context.Background(), nil, 24)
+			if tt.wantErr == "" {
+				if !got.Success {
+					t.Errorf("FetchUpcomingEvents() failed: %s", got.Message)
+				}
+				return
+			}
+			if got.Success || got.Error != ErrInvalidConfig {
+				t.Fatalf("FetchUpcomingEvents() = success %v, error %q; want %q", got.Success, got.Error, ErrInvalidConfig)
+			}
+			if !strings.Contains(got.Message, tt.wantErr) {
+				t.Errorf("FetchUpcomingEvents() message = %q, want it to contain %q", got.Message, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFetchEvents_InternalMeeting(t *testing.T) {
+	t.Parallel()
+	start := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		internal := testAcceptedEvent("internal", start, start.Add(time.Hour))
+		internal.ConferenceData = &calendar.ConferenceData{EntryPoints: []*calendar.EntryPoint{
+			{EntryPointType: "video", Uri: "https://VC.Corp.Example.com/room/42"},
+		}}
+		subdomain := testAcceptedEvent("subdomain", start.Add(2*time.Hour), start.Add(3*time.Hour))
+		subdomain.Location = "https://corp-example.zoom.us/j/123"
+		external := testAcceptedEvent("external", start.Add(4*time.Hour), start.Add(5*time.Hour))
+		external.HangoutLink = "https://meet.google.com/abc-defg-hij"
+		lookalike := testAcceptedEvent("lookalike", start.Add(6*time.Hour), start.Add(7*time.Hour))
+		lookalike.ConferenceData = &calendar.ConferenceData{EntryPoints: []*calendar.EntryPoint{
+			{EntryPointType: "video", Uri: "https://notcorp.example.com/room/1"},
+		}}
+		writeTestJSON(t, w, &calendar.Events{Items: []*calendar.Event{internal, subdomain, external, lookalike}})
+	}))
+	client.Options.InternalMeetingDomains = []string{"corp.example.com", "*.corp-example.zoom.us"}
+
+	got := client.FetchUpcomingEvents(context.Background(), nil, 24)
+	if !got.Success {
+		t.Fatalf("FetchUpcomingEvents() failed: %s", got.Message)
+	}
+
+	gotInternal := make(map[string]bool)
+	for _, e := range got.Events {
+		gotInternal[e.ID] = e.InternalMeeting
+	}
+	want := map[string]bool{"internal": true, "subdomain": true, "external": false, "lookalike": false}
+	if diff := cmp.Diff(gotInternal, want); diff != "" {
+		t.Errorf("FetchUpcomingEvents() InternalMeeting mismatch (-got +want):\n%s", diff)
+	}
+}
+
+func TestFetchConcurrency(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      string
+		explicit int
+		want     int
+	}{
+		{name: "default", want: defaultFetchConcurrency},
+		{name: "env override", env: "8", want: 8},
+		{name: "env with spaces", env: " 2 ", want: 2},
+		{name: "explicit beats env", env: "8", explicit: 1, want: 1},
+		{name: "explicit beats invalid env", env: "lots", explicit: 1, want: 1},
+		{name: "zero falls back to default", env: "0", want: defaultFetchConcurrency},
+		{name: "negative falls back to default", env: "-3", want: defaultFetchConcurrency},
+		{name: "non-numeric falls back to default", env: "lots", want: defaultFetchConcurrency},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Not parallel: sets GCAL_FETCH_CONCURRENCY
+			t.Setenv(FetchConcurrencyEnv, tt.env)
+
+			c := &Client{FetchConcurrency: tt.explicit}
+			if got := c.fetchConcurrency(); got != tt.want {
+				t.Errorf("fetchConcurrency() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFetchEvents_InvalidFetchConcurrencyEnv(t *testing.T) {
+	// Not parallel: sets GCAL_FETCH_CONCURRENCY
+	t.Setenv(FetchConcurrencyEnv, "lots")
+
+	now := time.Now()
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeTestJSON(t, w, &calendar.Events{Items: []*calendar.Event{
+			testAcceptedEvent("standup", now.Add(time.Hour), now.Add(2*time.Hour)),
+		}})
+	}))
+	got := client.FetchUpcomingEvents(context.Background(), nil, 24)
+	if !got.Success || len(got.Events) != 1 {
+		t.Errorf("FetchUpcomingEvents() = %v %q with %d events, want success with 1 event", got.Success, got.Message, len(got.Events))
+	}
+}
+
+func TestLoadDefaultUpcomingHours(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     string
+		want    int
+		wantErr bool
+	}{
+		{name: "built-in fallback", want: DefaultUpcomingHours},
+		{name: "env override", env: "12", want: 12},
+		{name: "env with spaces", env: " 72 ", want: 72},
+		{name: "zero", env: "0", wantErr: true},
+		{name: "negative", env: "-6", wantErr: true},
+		{name: "non-numeric", env: "a day", wantErr: true},
+		{name: "fractional", env: "1.5", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Not parallel: sets GCAL_UPCOMING_HOURS
+			t.Setenv(UpcomingHoursEnv, tt.env)
+
+			got, err := LoadDefaultUpcomingHours()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadDefaultUpcomingHours() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if !strings.HasPrefix(err.Error(), ErrInvalidConfig+":") {
+					t.Errorf("LoadDefaultUpcomingHours() error = %v, want %s", err, ErrInvalidConfig)
+				}
+				return
+			}
+			if got != tt.want {
+				t.Errorf("LoadDefaultUpcomingHours() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFetchEvents_BoundedConcurrency(t *testing.T) {
+	t.Parallel()
+	start := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+
+	var inFlight, peak int32
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+
+		calID := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/calendars/"), "/events")
+		writeTestJSON(t, w, &calendar.Events{Items: []*calendar.Event{
+			testAcceptedEvent(calID, start, start.Add(time.Hour)),
+		}})
+	}))
+	client.FetchConcurrency = 2
+
+	calendarIDs := []string{"a", "b", "c", "d", "e"}
+	got := client.FetchUpcomingEvents(context.Background(), calendarIDs, 24)
+	if !got.Success {
+		t.Fatalf("FetchUpcomingEvents() failed: %s", got.Message)
+	}
+
+	var gotIDs []string
+	for _, e := range got.Events {
+		gotIDs = append(gotIDs, e.ID)
+	}
+	// Same start time, so the stable sort keeps calendarIDs order
+	if diff := cmp.Diff(gotIDs, calendarIDs); diff != "" {
+		t.Errorf("FetchUpcomingEvents() IDs mismatch (-got +want):\n%s", diff)
+	}
+	if p := atomic.LoadInt32(&peak); p > 2 {
+		t.Errorf("peak concurrent requests = %d, want at most 2", p)
+	}
+}
+
+func TestFetchEvents_Pagination(t *testing.T) {
+	t.Parallel()
+	start := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+
+	at := func(id string, hour int) *calendar.Event {
+		s := start.Add(time.Duration(hour) * time.Hour)
+		return testAcceptedEvent(id, s, s.Add(30*time.Minute))
+	}
+
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch token := r.URL.Query().Get("pageToken"); token {
+		case "":
+			writeTestJSON(t, w, &calendar.Events{
+				Items:         []*calendar.Event{at("a", 0), at("b", 1)},
+				NextPageToken: "page2",
+			})
+		case "page2":
+			writeTestJSON(t, w, &calendar.Events{
+				Items: []*calendar.Event{at("c", 2)},
+			})
+		default:
+			t.Errorf("unexpected pageToken %q", token)
+		}
+	}))
+
+	got := client.FetchUpcomingEvents(context.Background(), nil, 24)
+	if !got.Success {
+		t.Fatalf("FetchUpcomingEvents() failed: %s", got.Message)
+	}
+
+	var gotIDs []string
+	for _, e := range got.Events {
+		gotIDs = append(gotIDs, e.ID)
+	}
+	if diff := cmp.Diff(gotIDs, []string{"a", "b", "c"}); diff != "" {
+		t.Errorf("FetchUpcomingEvents() IDs mismatch (-got +want):\n%s", diff)
+	}
+}
+
+func TestFetchEvents_PageLimit(t *testing.T) {
+	t.Parallel()
+
+	var requests int32
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&requests, 1)
+		writeTestJSON(t, w, &calendar.Events{NextPageToken: fmt.Sprintf("page%d", n+1)})
+	}))
+
+	got := client.FetchUpcomingEvents(context.Background(), nil, 24)
+	if got.Success {
+		t.Fatal("FetchUpcomingEvents() Success = true, want page limit error")
+	}
+	if !strings.Contains(got.Message, fmt.Sprintf("more than %d pages", maxEventPages)) {
+		t.Errorf("FetchUpcomingEvents() message = %q, want page limit error", got.Message)
+	}
+	if n := atomic.LoadInt32(&requests); n != maxEventPages {
+		t.Errorf("requests = %d, want %d", n, maxEventPages)
+	}
+}
+
+func TestFetchEventsInRange(t *testing.T) {
+	t.Parallel()
+
+	la, err := time.LoadLocation("America/Los_Angeles")
+	if err != nil {
+		t.Fatalf("Failed to load location: %v", err)
+	}
+	// Friday to Tuesday across the start of DST on Sunday March 10 2024
+	start := time.Date(2024, 3, 8, 0, 0, 0, 0, la)
+	end := time.Date(2024, 3, 12, 0, 0, 0, 0, la)
+
+	var gotMin, gotMax string
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMin = r.URL.Query().Get("timeMin")
+		gotMax = r.URL.Query().Get("timeMax")
+		before := time.Date(2024, 3, 8, 10, 0, 0, 0, la)
+		after := time.Date(2024, 3, 11, 10, 0, 0, 0, la)
+		writeTestJSON(t, w, &calendar.Events{Items: []*calendar.Event{
+			testAcceptedEvent("before-dst", before, before.Add(time.Hour)),
+			testAcceptedEvent("after-dst", after, after.Add(time.Hour)),
+		}})
+	}))
+
+	got := client.FetchEventsInRange(context.Background(), nil, start, end)
+	if !got.Success {
+		t.Fatalf("FetchEventsInRange() failed: %s", got.Message)
+	}
+	if gotMin != "2024-03-08T00:00:00-08:00" || gotMax != "2024-03-12T00:00:00-07:00" {
+		t.Errorf("FetchEventsInRange() sent range %s to %s, want local midnights on both sides of DST", gotMin, gotMax)
+	}
+
+	var gotIDs []string
+	for _, e := range got.Events {
+		gotIDs = append(gotIDs, e.ID)
+	}
+	if diff := cmp.Diff(gotIDs, []string{"before-dst", "after-dst"}); diff != "" {
+		t.Errorf("FetchEventsInRange() IDs mismatch (-got +want):\n%s", diff)
+	}
+}
+
+func TestFetchEventsInRange_InvalidRange(t *testing.T) {
+	t.Parallel()
+	start := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request for invalid range: %s", r.URL)
+	}))
+
+	for name, end := range map[string]time.Time{
+		"empty":    start,
+		"reversed": start.Add(-time.Hour),
+	} {
+		got := client.FetchEventsInRange(context.Background(), nil, start, end)
+		if got.Success || got.Error != ErrAPIError {
+			t.Errorf("%s range: FetchEventsInRange() = success %v, error %q; want %q", name, got.Success, got.Error, ErrAPIError)
+		}
+	}
+}
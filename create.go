@@ -0,0 +1,410 @@
+// Package gcal provides event creation, deletion and conference regeneration through the Calendar API.
+package gcal
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/googleapi"
+)
+
+// createBatchConcurrency bounds the inserts CreateEventsBatch runs at once,
+// keeping large imports under the API's per-user rate limits
+const createBatchConcurrency = 4
+
+// EventInput describes an event to create
+type EventInput struct {
+	Title       string
+	Description string
+	Start       time.Time
+	End         time.Time
+	Attendees   []string // attendee email addresses
+
+	// Reminders overrides the calendar's default reminders. Leave it empty,
+	// with UseDefaultReminders unset, to let the API apply its defaults.
+	Reminders []Reminder
+
+	// UseDefaultReminders explicitly requests the calendar's default
+	// reminders. It can't be combined with Reminders.
+	UseDefaultReminders bool
+
+	// WorkingHours, when set, rejects events that fall outside these hours
+	// in Start's timezone, see ValidateBusinessHours
+	WorkingHours *WorkingHours
+}
+
+// validate checks the input has the fields the API requires, failing with
+// ErrInvalidConfig
+func (in EventInput) validate() error {
+	if in.Start.IsZero() || in.End.IsZero() {
+		return fmt.Errorf("%s: event start and end are required", ErrInvalidConfig)
+	}
+	if !in.End.After(in.Start) {
+		return fmt.Errorf("%s: event must end after it starts", ErrInvalidConfig)
+	}
+	if in.UseDefaultReminders && len(in.Reminders) > 0 {
+		return fmt.Errorf("%s: event can't set reminders and use the default reminders", ErrInvalidConfig)
+	}
+	for _, r := range in.Reminders {
+		if r.Method != "popup" && r.Method != "email" {
+			return fmt.Errorf("%s: unknown reminder method %q, want \"popup\" or \"email\"", ErrInvalidConfig, r.Method)
+		}
+		if r.Minutes < 0 {
+			return fmt.Errorf("%s: reminder minutes must not be negative", ErrInvalidConfig)
+		}
+	}
+	if in.WorkingHours != nil {
+		e := Event{
+			Title: in.Title,
+			Start: in.Start.Format(time.RFC3339),
+			End:   in.End.Format(time.RFC3339),
+		}
+		if err := ValidateBusinessHours(e, *in.WorkingHours, in.Start.Location()); err != nil {
+			return fmt.Errorf("%s: %w", ErrInvalidConfig, err)
+		}
+	}
+	return nil
+}
+
+// toAPI converts the input into a Calendar API event
+func (in EventInput) toAPI() *calendar.Event {
+	item := &calendar.Event{
+		Summary:     in.Title,
+		Description: in.Description,
+		Start:       &calendar.EventDateTime{DateTime: in.Start.Format(time.RFC3339)},
+		End:         &calendar.EventDateTime{DateTime: in.End.Format(time.RFC3339)},
+	}
+	for _, email := range in.Attendees {
+		item.Attendees = append(item.Attendees, &calendar.EventAttendee{Email: email})
+	}
+
+	switch {
+	case in.UseDefaultReminders:
+		item.Reminders = &calendar.EventReminders{UseDefault: true}
+	case len(in.Reminders) > 0:
+		// UseDefault false and Minutes 0 are zero values the client library
+		// would otherwise omit from the request
+		item.Reminders = &calendar.EventReminders{ForceSendFields: []string{"UseDefault"}}
+		for _, r := range in.Reminders {
+			item.Reminders.Overrides = append(item.Reminders.Overrides, &calendar.EventReminder{
+				Method:          r.Method,
+				Minutes:         int64(r.Minutes),
+				ForceSendFields: []string{"Minutes"},
+			})
+		}
+	}
+	return item
+}
+
+// CreateEvent creates e in calendarID ("primary" if empty) using the saved
+// credentials and token, returning the created event with its
+// assigned ID. The token must have been granted write access; a
+// read-only token fails with ErrNotConfigured before anything is sent.
+func CreateEvent(ctx context.Context, calendarID string, e Event) (Event, error) {
+	c, _, err := newDefaultClient(ctx)
+	if err != nil {
+		return Event{}, err
+	}
+
+	canWrite, err := tokenHasScope(ctx, http.DefaultClient, googleTokenInfoURL, calendar.CalendarEventsScope)
+	if err != nil {
+		return Event{}, err
+	}
+	if !canWrite {
+		return Event{}, fmt.Errorf("%s: saved token is read-only - re-run 'gcal auth' with write access to create events", ErrNotConfigured)
+	}
+
+	return c.CreateEvent(ctx, calendarID, e)
+}
+
+// CreateEvent creates e in calendarID ("primary" if empty), returning the
+// created event with its assigned ID. Title, Description, Start and
+// End are sent, with attendees taken from AttendeeDetails emails. Start and
+// End must be RFC3339 times, End after Start, or the call fails with
+// ErrInvalidConfig. A 403 because the token lacks write access is reported
+// as ErrNotConfigured.
+func (c *Client) CreateEvent(ctx context.Context, calendarID string, e Event) (Event, error) {
+	if calendarID == "" {
+		calendarID = "primary"
+	}
+
+	in, err := eventInput(e)
+	if err != nil {
+		return Event{}, err
+	}
+
+	created, err := c.createEvent(ctx, c.Retry.newBudget(time.Now()), calendarID, in)
+	var apiErr *googleapi.Error
+	if isInsufficientScope(err) && errors.As(err, &apiErr) {
+		// Report the API's error, not createEvent's api_error wrapping
+		return Event{}, fmt.Errorf("%s: token lacks write access - re-run 'gcal auth' with write access to create events: %w", ErrNotConfigured, apiErr)
+	}
+	return created, err
+}
+
+// DeleteEvent deletes eventID from calendarID ("primary" if empty) using
+// the saved credentials and token, which must have been granted write
+// access. See Client.DeleteEvent.
+func DeleteEvent(ctx context.Context, calendarID, eventID string) error {
+	c, _, err := newDefaultClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	canWrite, err := tokenHasScope(ctx, http.DefaultClient, googleTokenInfoURL, calendar.CalendarEventsScope)
+	if err != nil {
+		return err
+	}
+	if !canWrite {
+		return fmt.Errorf("%s: saved token is read-only - re-run 'gcal auth' with write access to delete events", ErrNotConfigured)
+	}
+
+	return c.DeleteEvent(ctx, calendarID, eventID)
+}
+
+// DeleteEvent deletes eventID from calendarID ("primary" if empty).
+// Deleting an event that was already deleted or cancelled succeeds, so
+// retries are safe. An event that never existed, or isn't visible to the
+// user, fails with ErrEventNotFound rather than ErrAPIError.
+func (c *Client) DeleteEvent(ctx context.Context, calendarID, eventID string) error {
+	if calendarID == "" {
+		calendarID = "primary"
+	}
+
+	err := c.Retry.newBudget(time.Now()).do(ctx, func() error {
+		return c.srv.Events.Delete(calendarID, eventID).Context(ctx).Do()
+	})
+
+	var apiErr *googleapi.Error
+	switch {
+	case err == nil:
+		return nil
+	case errors.As(err, &apiErr) && apiErr.Code == http.StatusGone:
+		// The API answers 410 for events that are already deleted
+		return nil
+	case errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound:
+		return fmt.Errorf("%s: event %s not found in calendar %s: %w", ErrEventNotFound, eventID, calendarID, err)
+	case isInsufficientScope(err):
+		return fmt.Errorf("%s: token lacks write access - re-run 'gcal auth' with write access to delete events: %w", ErrNotConfigured, err)
+	default:
+		return fmt.Errorf("%s: failed to delete event %s: %w", ErrAPIError, eventID, err)
+	}
+}
+
+// conferenceTypeMeet is the conference solution type of Google Meet
+const conferenceTypeMeet = "hangoutsMeet"
+
+// RegenerateConference replaces the conference of eventID in calendarID
+// ("primary" if empty) using the saved credentials and token, which must
+// have been granted write access. See Client.RegenerateConference.
+func RegenerateConference(ctx context.Context, calendarID, eventID string) (Event, error) {
+	c, _, err := newDefaultClient(ctx)
+	if err != nil {
+		return Event{}, err
+	}
+
+	canWrite, err := tokenHasScope(ctx, http.DefaultClient, googleTokenInfoURL, calendar.CalendarEventsScope)
+	if err != nil {
+		return Event{}, err
+	}
+	if !canWrite {
+		return Event{}, fmt.Errorf("%s: saved token is read-only - re-run 'gcal auth' with write access to change events", ErrNotConfigured)
+	}
+
+	return c.RegenerateConference(ctx, calendarID, eventID)
+}
+
+// RegenerateConference replaces the conference of eventID in calendarID
+// ("primary" if empty) with a new Google Meet conference, e.g. when its
+// link has gone stale, and returns the updated event. An event without a
+// conference gets one. Google can create the conference asynchronously, in
+// which case the returned MeetingURL is empty until the event is fetched
+// again. Errors are reported as for DeleteEvent.
+func (c *Client) RegenerateConference(ctx context.Context, calendarID, eventID string) (Event, error) {
+	if calendarID == "" {
+		calendarID = "primary"
+	}
+
+	requestID, err := newConferenceRequestID()
+	if err != nil {
+		return Event{}, err
+	}
+	patch := &calendar.Event{
+		ConferenceData: &calendar.ConferenceData{
+			CreateRequest: &calendar.CreateConferenceRequest{
+				RequestId:             requestID,
+				ConferenceSolutionKey: &calendar.ConferenceSolutionKey{Type: conferenceTypeMeet},
+			},
+			// Drop the old conference rather than merging into it
+			NullFields: []string{"ConferenceId", "EntryPoints"},
+		},
+	}
+
+	// The request ID makes a retried patch create a single conference
+	var updated *calendar.Event
+	err = c.Retry.newBudget(time.Now()).do(ctx, func() error {
+		var err error
+		updated, err = c.srv.Events.Patch(calendarID, eventID, patch).ConferenceDataVersion(1).Context(ctx).Do()
+		return err
+	})
+
+	var apiErr *googleapi.Error
+	switch {
+	case err == nil:
+		return eventFromAPI(updated, calendarID), nil
+	case errors.As(err, &apiErr) && (apiErr.Code == http.StatusNotFound || apiErr.Code == http.StatusGone):
+		return Event{}, fmt.Errorf("%s: event %s not found in calendar %s: %w", ErrEventNotFound, eventID, calendarID, err)
+	case isInsufficientScope(err):
+		return Event{}, fmt.Errorf("%s: token lacks write access - re-run 'gcal auth' with write access to change events: %w", ErrNotConfigured, err)
+	default:
+		return Event{}, fmt.Errorf("%s: failed to regenerate conference for event %s: %w", ErrAPIError, eventID, err)
+	}
+}
+
+// eventIDBytes is how much randomness goes into a client-assigned event ID
+const eventIDBytes = 20
+
+// newEventID returns a random event ID in the lowercase base32hex alphabet
+// the Calendar API requires for client-assigned IDs
+func newEventID() (string, error) {
+	b := make([]byte, eventIDBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate event ID: %w", err)
+	}
+	return strings.ToLower(base32.HexEncoding.WithPadding(base32.NoPadding).EncodeToString(b)), nil
+}
+
+// newConferenceRequestID returns a random ID for a conference create
+// request; each distinct ID asks for a new conference
+func newConferenceRequestID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate conference request ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// eventInput converts an Event into the input for creating it
+func eventInput(e Event) (EventInput, error) {
+	start, err := time.Parse(time.RFC3339, e.Start)
+	if err != nil {
+		return EventInput{}, fmt.Errorf("%s: event start %q is not an RFC3339 time", ErrInvalidConfig, e.Start)
+	}
+	end, err := time.Parse(time.RFC3339, e.End)
+	if err != nil {
+		return EventInput{}, fmt.Errorf("%s: event end %q is not an RFC3339 time", ErrInvalidConfig, e.End)
+	}
+
+	in := EventInput{
+		Title:       e.Title,
+		Description: e.Description,
+		Start:       start,
+		End:         end,
+	}
+	for _, a := range e.AttendeeDetails {
+		if a.Email != "" {
+			in.Attendees = append(in.Attendees, a.Email)
+		}
+	}
+	return in, nil
+}
+
+// isInsufficientScope reports whether err is a Google API 403 caused by the
+// token's scopes rather than the calendar's sharing settings
+func isInsufficientScope(err error) bool {
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) || apiErr.Code != http.StatusForbidden {
+		return false
+	}
+	for _, item := range apiErr.Errors {
+		if item.Reason == "insufficientPermissions" {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateEventsBatch inserts events into calendarID, running a few inserts
+// concurrently. The results are parallel to inputs: for each index exactly
+// one of the returned Event (non-zero ID) or error is set, so one failure
+// doesn't affect the others. Inputs not yet started when ctx is cancelled
+// fail with the context's error. The Client needs a token with write access.
+func (c *Client) CreateEventsBatch(ctx context.Context, calendarID string, inputs []EventInput) ([]Event, []error) {
+	if calendarID == "" {
+		calendarID = "primary"
+	}
+
+	events := make([]Event, len(inputs))
+	errs := make([]error, len(inputs))
+	budget := c.Retry.newBudget(time.Now())
+
+	sem := make(chan struct{}, createBatchConcurrency)
+	var wg sync.WaitGroup
+	for i, in := range inputs {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			errs[i] = ctx.Err()
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, in EventInput) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			events[i], errs[i] = c.createEvent(ctx, budget, calendarID, in)
+		}(i, in)
+	}
+	wg.Wait()
+
+	return events, errs
+}
+
+// createEvent validates and inserts a single event
+func (c *Client) createEvent(ctx context.Context, budget *retryBudget, calendarID string, in EventInput) (Event, error) {
+	if err := ctx.Err(); err != nil {
+		return Event{}, err
+	}
+	if err := in.validate(); err != nil {
+		return Event{}, err
+	}
+
+	// A client-assigned ID makes retries safe: if an attempt that failed
+	// with a server error had in fact inserted the event, the retry gets a
+	// 409 instead of creating a duplicate
+	eventID, err := newEventID()
+	if err != nil {
+		return Event{}, err
+	}
+	item := in.toAPI()
+	item.Id = eventID
+
+	var created *calendar.Event
+	attempts := 0
+	err = budget.do(ctx, func() error {
+		attempts++
+		var err error
+		created, err = c.srv.Events.Insert(calendarID, item).Context(ctx).Do()
+		var apiErr *googleapi.Error
+		if attempts > 1 && errors.As(err, &apiErr) && apiErr.Code == http.StatusConflict {
+			// An earlier attempt went through; return what it created
+			created, err = c.srv.Events.Get(calendarID, eventID).Context(ctx).Do()
+		}
+		return err
+	})
+	if err != nil {
+		return Event{}, fmt.Errorf("%s: failed to create event %q: %w", ErrAPIError, in.Title, err)
+	}
+
+	return eventFromAPI(created, calendarID), nil
+}
@@ -0,0 +1,504 @@
+package gcal
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/api/calendar/v3"
+)
+
+func TestCreateEventsBatch(t *testing.T) {
+	t.Parallel()
+	start := time.Date(2024, 1, 15, 14, 0, 0, 0, time.UTC)
+
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/calendars/team@example.com/events" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		var item calendar.Event
+		if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		if item.Summary == "Rejected" {
+			writeTestAPIError(w, http.StatusBadRequest, "invalid event")
+			return
+		}
+		item.Id = "id-" + item.Summary
+		writeTestJSON(t, w, &item)
+	}))
+
+	inputs := []EventInput{
+		{Title: "Kickoff", Start: start, End: start.Add(time.Hour), Attendees: []string{"alice@example.com"}},
+		{Title: "Rejected", Start: start, End: start.Add(time.Hour)},
+		{Title: "Retro", Start: start.Add(2 * time.Hour), End: start.Add(3 * time.Hour)},
+	}
+
+	events, errs := client.CreateEventsBatch(context.Background(), "team@example.com", inputs)
+	if len(events) != len(inputs) || len(errs) != len(inputs) {
+		t.Fatalf("CreateEventsBatch() returned %d events and %d errors, want %d each", len(events), len(errs), len(inputs))
+	}
+
+	if errs[0] != nil || errs[2] != nil {
+		t.Fatalf("CreateEventsBatch() unexpected errors: %v, %v", errs[0], errs[2])
+	}
+	if errs[1] == nil {
+		t.Error("CreateEventsBatch() error for rejected input = nil, want error")
+	}
+
+	var gotIDs []string
+	for _, e := range events {
+		gotIDs = append(gotIDs, e.ID)
+	}
+	if diff := cmp.Diff(gotIDs, []string{"id-Kickoff", "", "id-Retro"}); diff != "" {
+		t.Errorf("CreateEventsBatch() IDs mismatch (-got +want):\n%s", diff)
+	}
+	if diff := cmp.Diff(events[0].Attendees, []string{"alice@example.com"}); diff != "" {
+		t.Errorf("CreateEventsBatch() attendees mismatch (-got +want):\n%s", diff)
+	}
+	if events[0].CalendarID != "team@example.com" {
+		t.Errorf("CreateEventsBatch() CalendarID = %q, want %q", events[0].CalendarID, "team@example.com")
+	}
+}
+
+func TestCreateEventsBatch_InvalidInputNotSent(t *testing.T) {
+	t.Parallel()
+	start := time.Date(2024, 1, 15, 14, 0, 0, 0, time.UTC)
+
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+	}))
+
+	wh := DefaultWorkingHours()
+	inputs := []EventInput{
+		{Title: "Backwards", Start: start, End: start.Add(-time.Hour)},
+		{Title: "After hours", Start: start.Add(4 * time.Hour), End: start.Add(5 * time.Hour), WorkingHours: &wh},
+	}
+	_, errs := client.CreateEventsBatch(context.Background(), "", inputs)
+	for i, err := range errs {
+		if err == nil || !strings.HasPrefix(err.Error(), ErrInvalidConfig+":") {
+			t.Errorf("CreateEventsBatch() error for %s = %v, want %s", inputs[i].Title, err, ErrInvalidConfig)
+		}
+	}
+}
+
+func TestCreateEventsBatch_Cancelled(t *testing.T) {
+	t.Parallel()
+	start := time.Date(2024, 1, 15, 14, 0, 0, 0, time.UTC)
+
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, errs := client.CreateEventsBatch(ctx, "primary", []EventInput{
+		{Title: "One", Start: start, End: start.Add(time.Hour)},
+		{Title: "Two", Start: start, End: start.Add(time.Hour)},
+	})
+	for i, err := range errs {
+		if err != context.Canceled {
+			t.Errorf("CreateEventsBatch() errs[%d] = %v, want %v", i, err, context.Canceled)
+		}
+	}
+}
+
+func TestCreateEventsBatch_Reminders(t *testing.T) {
+	t.Parallel()
+	start := time.Date(2024, 1, 15, 14, 0, 0, 0, time.UTC)
+
+	type sentReminders struct {
+		UseDefault *bool `json:"useDefault"`
+		Overrides  []struct {
+			Method  string `json:"method"`
+			Minutes *int   `json:"minutes"`
+		} `json:"overrides"`
+	}
+
+	var mu sync.Mutex
+	sent := make(map[string]*sentReminders)
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Summary   string         `json:"summary"`
+			Reminders *sentReminders `json:"reminders"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		mu.Lock()
+		sent[body.Summary] = body.Reminders
+		mu.Unlock()
+
+		writeTestJSON(t, w, &calendar.Event{
+			Id:      "id-" + body.Summary,
+			Summary: body.Summary,
+			Start:   &calendar.EventDateTime{DateTime: start.Format(time.RFC3339)},
+			End:     &calendar.EventDateTime{DateTime: start.Add(time.Hour).Format(time.RFC3339)},
+			Reminders: &calendar.EventReminders{
+				Overrides: []*calendar.EventReminder{{Method: "popup", Minutes: 15}},
+			},
+		})
+	}))
+
+	inputs := []EventInput{
+		{Title: "Override", Start: start, End: start.Add(time.Hour), Reminders: []Reminder{{Method: "popup", Minutes: 15}}},
+		{Title: "Default", Start: start, End: start.Add(time.Hour), UseDefaultReminders: true},
+		{Title: "Unset", Start: start, End: start.Add(time.Hour)},
+		{Title: "Both", Start: start, End: start.Add(time.Hour), UseDefaultReminders: true, Reminders: []Reminder{{Method: "popup"}}},
+		{Title: "Bad method", Start: start, End: start.Add(time.Hour), Reminders: []Reminder{{Method: "sms", Minutes: 5}}},
+	}
+
+	events, errs := client.CreateEventsBatch(context.Background(), "", inputs)
+	for i, wantErr := range []bool{false, false, false, true, true} {
+		if (errs[i] != nil) != wantErr {
+			t.Errorf("CreateEventsBatch() %s error = %v, wantErr %v", inputs[i].Title, errs[i], wantErr)
+		}
+	}
+
+	override := sent["Override"]
+	if override == nil || override.UseDefault == nil || *override.UseDefault {
+		t.Fatalf("Override reminders = %+v, want useDefault false sent", override)
+	}
+	if len(override.Overrides) != 1 || override.Overrides[0].Method != "popup" ||
+		override.Overrides[0].Minutes == nil || *override.Overrides[0].Minutes != 15 {
+		t.Errorf("Override reminders = %+v, want one 15 minute popup", override.Overrides)
+	}
+	if d := sent["Default"]; d == nil || d.UseDefault == nil || !*d.UseDefault || len(d.Overrides) != 0 {
+		t.Errorf("Default reminders = %+v, want useDefault true", d)
+	}
+	if u, ok := sent["Unset"]; !ok || u != nil {
+		t.Errorf("Unset reminders = %+v, want none sent", u)
+	}
+	if _, ok := sent["Both"]; ok {
+		t.Error("invalid reminders input was sent to the API")
+	}
+
+	if diff := cmp.Diff(events[0].Reminders, []Reminder{{Method: "popup", Minutes: 15}}); diff != "" {
+		t.Errorf("created event reminders mismatch (-got +want):\n%s", diff)
+	}
+}
+
+func TestCreateEvent(t *testing.T) {
+	t.Parallel()
+
+	var sent calendar.Event
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/calendars/primary/events" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&sent); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		created := sent
+		created.Id = "server-id"
+		writeTestJSON(t, w, &created)
+	}))
+
+	got, err := client.CreateEvent(context.Background(), "", Event{
+		Title:           "Planning",
+		Description:     "Quarterly goals",
+		Start:           "2024-01-15T14:00:00Z",
+		End:             "2024-01-15T15:00:00-05:00",
+		Attendees:       []string{"Alice"},
+		AttendeeDetails: []Attendee{{Name: "Alice", Email: "alice@example.com"}},
+	})
+	if err != nil {
+		t.Fatalf("CreateEvent() error = %v", err)
+	}
+
+	if sent.Summary != "Planning" || sent.Description != "Quarterly goals" {
+		t.Errorf("sent summary, description = %q, %q, want %q, %q", sent.Summary, sent.Description, "Planning", "Quarterly goals")
+	}
+	if sent.Start == nil || sent.Start.DateTime != "2024-01-15T14:00:00Z" || sent.Start.Date != "" {
+		t.Errorf("sent start = %+v, want DateTime 2024-01-15T14:00:00Z", sent.Start)
+	}
+	if sent.End == nil || sent.End.DateTime != "2024-01-15T15:00:00-05:00" || sent.End.Date != "" {
+		t.Errorf("sent end = %+v, want DateTime 2024-01-15T15:00:00-05:00", sent.End)
+	}
+	if len(sent.Attendees) != 1 || sent.Attendees[0].Email != "alice@example.com" {
+		t.Errorf("sent attendees = %+v, want alice@example.com", sent.Attendees)
+	}
+
+	if got.ID != "server-id" {
+		t.Errorf("CreateEvent() ID = %q, want %q", got.ID, "server-id")
+	}
+	if got.Title != "Planning" || got.CalendarID != "primary" {
+		t.Errorf("CreateEvent() Title, CalendarID = %q, %q, want %q, %q", got.Title, got.CalendarID, "Planning", "primary")
+	}
+}
+
+func TestCreateEvent_RetryAfterCommittedInsert(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu       sync.Mutex
+		inserted *calendar.Event
+		inserts  []string // event ID sent with each insert
+	)
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch r.Method {
+		case http.MethodPost:
+			var item calendar.Event
+			if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
+				t.Errorf("Failed to decode request: %v", err)
+			}
+			inserts = append(inserts, item.Id)
+			if inserted != nil {
+				writeTestAPIError(w, http.StatusConflict, "The requested identifier already exists.")
+				return
+			}
+			// Commit the insert, then fail as if the response was lost
+			inserted = &item
+			writeTestAPIError(w, http.StatusServiceUnavailable, "backend error")
+		case http.MethodGet:
+			if inserted == nil || r.URL.Path != "/calendars/primary/events/"+inserted.Id {
+				t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+				writeTestAPIError(w, http.StatusNotFound, "Not Found")
+				return
+			}
+			writeTestJSON(t, w, inserted)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	client.Retry = RetryPolicy{MaxRetries: 2, Backoff: time.Millisecond}
+
+	got, err := client.CreateEvent(context.Background(), "", Event{
+		Title: "Planning",
+		Start: "2024-01-15T14:00:00Z",
+		End:   "2024-01-15T15:00:00Z",
+	})
+	if err != nil {
+		t.Fatalf("CreateEvent() error = %v", err)
+	}
+
+	if len(inserts) != 2 || inserts[0] == "" || inserts[0] != inserts[1] {
+		t.Fatalf("insert event IDs = %q, want the same client-assigned ID twice", inserts)
+	}
+	if strings.Trim(inserts[0], "0123456789abcdefghijklmnopqrstuv") != "" {
+		t.Errorf("event ID %q is not lowercase base32hex", inserts[0])
+	}
+	if got.ID != inserts[0] || got.Title != "Planning" {
+		t.Errorf("CreateEvent() ID, Title = %q, %q, want %q, %q", got.ID, got.Title, inserts[0], "Planning")
+	}
+}
+
+func TestCreateEvent_Invalid(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		event Event
+	}{
+		{
+			name:  "start not RFC3339",
+			event: Event{Title: "Bad", Start: "2024-01-15 14:00", End: "2024-01-15T15:00:00Z"},
+		},
+		{
+			name:  "all-day date",
+			event: Event{Title: "Bad", Start: "2024-01-15", End: "2024-01-16"},
+		},
+		{
+			name:  "missing end",
+			event: Event{Title: "Bad", Start: "2024-01-15T14:00:00Z"},
+		},
+		{
+			name:  "end before start",
+			event: Event{Title: "Bad", Start: "2024-01-15T15:00:00Z", End: "2024-01-15T14:00:00Z"},
+		},
+		{
+			name:  "zero duration",
+			event: Event{Title: "Bad", Start: "2024-01-15T14:00:00Z", End: "2024-01-15T14:00:00Z"},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt // Capture loop variable
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			}))
+			_, err := client.CreateEvent(context.Background(), "primary", tt.event)
+			if err == nil || !strings.HasPrefix(err.Error(), ErrInvalidConfig+":") {
+				t.Errorf("CreateEvent() error = %v, want %s", err, ErrInvalidConfig)
+			}
+		})
+	}
+}
+
+func TestCreateEvent_InsufficientScope(t *testing.T) {
+	t.Parallel()
+
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error": map[string]interface{}{
+				"code":    http.StatusForbidden,
+				"message": "Request had insufficient authentication scopes.",
+				"errors":  []map[string]string{{"reason": "insufficientPermissions"}},
+			},
+		})
+	}))
+
+	_, err := client.CreateEvent(context.Background(), "primary", Event{
+		Title: "Planning",
+		Start: "2024-01-15T14:00:00Z",
+		End:   "2024-01-15T15:00:00Z",
+	})
+	if err == nil || !strings.HasPrefix(err.Error(), ErrNotConfigured+":") {
+		t.Errorf("CreateEvent() error = %v, want %s", err, ErrNotConfigured)
+	}
+	if err != nil && strings.Contains(err.Error(), ErrAPIError) {
+		t.Errorf("CreateEvent() error = %q, want a single error code", err)
+	}
+}
+
+func TestCreateEvent_ReadOnlyToken(t *testing.T) {
+	// Not parallel: reads credentials and token from the XDG dirs
+	configDir, dataDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+	createTestCredentials(t, configDir, Credentials{ClientID: "id", ClientSecret: "secret"})
+	createTestToken(t, dataDir, TokenStore{
+		AccessToken: "access",
+		TokenType:   "Bearer",
+		Expiry:      time.Now().Add(time.Hour),
+		Scopes:      []string{calendar.CalendarReadonlyScope},
+	})
+
+	_, err := CreateEvent(context.Background(), "primary", Event{
+		Title: "Planning",
+		Start: "2024-01-15T14:00:00Z",
+		End:   "2024-01-15T15:00:00Z",
+	})
+	if err == nil || !strings.HasPrefix(err.Error(), ErrNotConfigured+":") {
+		t.Errorf("CreateEvent() error = %v, want %s", err, ErrNotConfigured)
+	}
+}
+
+func TestDeleteEvent(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		status   int
+		wantCode string // error code prefix, empty for success
+	}{
+		{name: "deleted", status: http.StatusNoContent},
+		{name: "already deleted", status: http.StatusGone},
+		{name: "not found", status: http.StatusNotFound, wantCode: ErrEventNotFound},
+		{name: "other failure", status: http.StatusBadRequest, wantCode: ErrAPIError},
+	}
+
+	for _, tt := range tests {
+		tt := tt // Capture loop variable
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodDelete || r.URL.Path != "/calendars/primary/events/evt-1" {
+					t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+				}
+				if tt.status == http.StatusNoContent {
+					w.WriteHeader(tt.status)
+					return
+				}
+				writeTestAPIError(w, tt.status, http.StatusText(tt.status))
+			}))
+
+			err := client.DeleteEvent(context.Background(), "", "evt-1")
+			if tt.wantCode == "" {
+				if err != nil {
+					t.Errorf("DeleteEvent() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.HasPrefix(err.Error(), tt.wantCode+":") {
+				t.Errorf("DeleteEvent() error = %v, want %s", err, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestRegenerateConference(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu         sync.Mutex
+		requestIDs []string
+	)
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if got := r.URL.Query().Get("conferenceDataVersion"); got != "1" {
+			t.Errorf("conferenceDataVersion = %q, want 1", got)
+		}
+		if r.URL.Path == "/calendars/primary/events/missing" {
+			writeTestAPIError(w, http.StatusNotFound, "Not Found")
+			return
+		}
+
+		var body struct {
+			ConferenceData map[string]json.RawMessage `json:"conferenceData"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		var create calendar.CreateConferenceRequest
+		if err := json.Unmarshal(body.ConferenceData["createRequest"], &create); err != nil {
+			t.Errorf("Failed to decode createRequest: %v", err)
+		}
+		if create.ConferenceSolutionKey == nil || create.ConferenceSolutionKey.Type != "hangoutsMeet" {
+			t.Errorf("createRequest solution key = %+v, want hangoutsMeet", create.ConferenceSolutionKey)
+		}
+		// The stale conference's entry points must be cleared, not merged
+		if got := string(body.ConferenceData["entryPoints"]); got != "null" {
+			t.Errorf("patched entryPoints = %s, want null", got)
+		}
+		mu.Lock()
+		requestIDs = append(requestIDs, create.RequestId)
+		mu.Unlock()
+
+		eventID := strings.TrimPrefix(r.URL.Path, "/calendars/primary/events/")
+		writeTestJSON(t, w, &calendar.Event{
+			Id:          eventID,
+			Summary:     "Standup",
+			HangoutLink: "https://meet.google.com/new-" + eventID,
+			Start:       &calendar.EventDateTime{DateTime: "2024-01-15T14:00:00Z"},
+			End:         &calendar.EventDateTime{DateTime: "2024-01-15T14:30:00Z"},
+		})
+	}))
+
+	// An event with a stale link and one that never had a conference send
+	// the same request, so both get a fresh link
+	for _, eventID := range []string{"stale-link", "no-conference"} {
+		got, err := client.RegenerateConference(context.Background(), "", eventID)
+		if err != nil {
+			t.Fatalf("RegenerateConference(%q) error = %v", eventID, err)
+		}
+		if want := "https://meet.google.com/new-" + eventID; got.MeetingURL != want {
+			t.Errorf("RegenerateConference(%q) MeetingURL = %q, want %q", eventID, got.MeetingURL, want)
+		}
+	}
+
+	if len(requestIDs) != 2 || requestIDs[0] == "" || requestIDs[0] == requestIDs[1] {
+		t.Errorf("create request IDs = %q, want a distinct ID per call", requestIDs)
+	}
+
+	_, err := client.RegenerateConference(context.Background(), "", "missing")
+	if err == nil || !strings.HasPrefix(err.Error(), ErrEventNotFound+":") {
+		t.Errorf("RegenerateConference() of a missing event error = %v, want %s", err, ErrEventNotFound)
+	}
+}
@@ -0,0 +1,287 @@
+// Package gcal provides helpers for querying and summarizing converted events.
+package gcal
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// DefaultSoonThreshold is how close to its start an event must be for
+// GetNextEvent to mark it as starting soon
+const DefaultSoonThreshold = 5 * time.Minute
+
+// minAgendaLength is the shortest description, in characters, that
+// MeetingsWithoutAgenda treats as an agenda
+const minAgendaLength = 20
+
+// GetNextEvent returns a copy of the earliest event starting at or after now,
+// with MinutesUntilStart and IsSoon populated. Events that already started or
+// have unparseable start times are skipped. A soonThreshold <= 0 uses
+// DefaultSoonThreshold. Returns nil if no event is upcoming.
+func GetNextEvent(events []Event, now time.Time, soonThreshold time.Duration) *Event {
+	if soonThreshold <= 0 {
+		soonThreshold = DefaultSoonThreshold
+	}
+
+	var next *Event
+	var nextStart time.Time
+	for i := range events {
+		start, err := time.Parse(time.RFC3339, events[i].Start)
+		if err != nil || start.Before(now) {
+			continue
+		}
+		if next == nil || start.Before(nextStart) {
+			next = &events[i]
+			nextStart = start
+		}
+	}
+
+	if next == nil {
+		return nil
+	}
+
+	event := *next
+	until := nextStart.Sub(now)
+	event.MinutesUntilStart = int(until / time.Minute)
+	event.IsSoon = until <= soonThreshold
+	return &event
+}
+
+// MeetingsWithoutAgenda returns meetings with other attendees whose
+// description is empty or too short to be an agenda. Solo blocks such as
+// focus time are never returned.
+func MeetingsWithoutAgenda(events []Event) []Event {
+	var missing []Event
+	for _, e := range events {
+		if e.AttendeeCount == 0 {
+			continue
+		}
+		if utf8.RuneCountInString(strings.TrimSpace(e.Description)) < minAgendaLength {
+			missing = append(missing, e)
+		}
+	}
+	return missing
+}
+
+// UniqueAttendees counts the distinct people across events. Attendees are
+// identified by email when AttendeeDetails is populated, falling back to
+// the display names in Attendees; matching is case-insensitive.
+func UniqueAttendees(events []Event) int {
+	seen := make(map[string]struct{})
+	for _, e := range events {
+		if len(e.AttendeeDetails) > 0 {
+			for _, a := range e.AttendeeDetails {
+				id := a.Email
+				if id == "" {
+					id = a.Name
+				}
+				addAttendeeIdentity(seen, id)
+			}
+			continue
+		}
+		for _, name := range e.Attendees {
+			addAttendeeIdentity(seen, name)
+		}
+	}
+	return len(seen)
+}
+
+// addAttendeeIdentity records a normalized attendee identity, ignoring blanks
+func addAttendeeIdentity(seen map[string]struct{}, id string) {
+	id = strings.ToLower(strings.TrimSpace(id))
+	if id != "" {
+		seen[id] = struct{}{}
+	}
+}
+
+// AcceptanceRate returns the fraction of attendees who accepted, from 0 to 1.
+// Returns 0 for an empty list.
+func AcceptanceRate(details []Attendee) float64 {
+	if len(details) == 0 {
+		return 0
+	}
+	accepted := 0
+	for _, a := range details {
+		if a.ResponseStatus == responseStatusAccepted {
+			accepted++
+		}
+	}
+	return float64(accepted) / float64(len(details))
+}
+
+// DeclinedByOthers returns the attendees in details who declined, by email
+// or by name if they have none, in input order, so an organizer can follow
+// up or cancel
+func DeclinedByOthers(details []Attendee) []string {
+	var declined []string
+	for _, a := range details {
+		if a.ResponseStatus != responseStatusDeclined {
+			continue
+		}
+		id := a.Email
+		if id == "" {
+			id = a.Name
+		}
+		if id != "" {
+			declined = append(declined, id)
+		}
+	}
+	return declined
+}
+
+// GroupByProvider groups events by the MeetingProvider of their meeting URL,
+// keeping each group in input order. Events without a URL, or with a URL
+// from an unrecognized provider, are grouped under "".
+func GroupByProvider(events []Event) map[string][]Event {
+	groups := make(map[string][]Event)
+	for _, e := range events {
+		provider := MeetingProvider(e.MeetingURL)
+		groups[provider] = append(groups[provider], e)
+	}
+	return groups
+}
+
+// BucketBySize groups events by meeting size, keeping each group in input
+// order: "1:1" with one other attendee, "small" with 2-5, "medium" with
+// 6-15 and "large" with 16 or more. Events without other attendees are
+// grouped under "".
+func BucketBySize(events []Event) map[string][]Event {
+	buckets := make(map[string][]Event)
+	for _, e := range events {
+		size := meetingSize(e.AttendeeCount)
+		buckets[size] = append(buckets[size], e)
+	}
+	return buckets
+}
+
+// meetingSize names the BucketBySize category for a count of other
+// attendees
+func meetingSize(attendees int) string {
+	switch {
+	case attendees < 1:
+		return ""
+	case attendees == 1:
+		return "1:1"
+	case attendees <= 5:
+		return "small"
+	case attendees <= 15:
+		return "medium"
+	default:
+		return "large"
+	}
+}
+
+// CountByCalendar tallies events per source CalendarID. Events without a
+// CalendarID are counted under "".
+func CountByCalendar(events []Event) map[string]int {
+	counts := make(map[string]int)
+	for _, e := range events {
+		counts[e.CalendarID]++
+	}
+	return counts
+}
+
+// DetectTimezoneMismatches reports whether events starting on the same
+// day carry different UTC offsets, e.g. after travel, which can make
+// displayed times confusing. Offsets are only compared within a day, each
+// event's date read in its own offset, so a multi-day range spanning a
+// daylight saving change isn't a mismatch. All-day and unparseable events
+// are ignored.
+func DetectTimezoneMismatches(events []Event) bool {
+	offsets := make(map[string]int) // date -> first offset seen that day
+	for _, e := range events {
+		start, err := time.Parse(time.RFC3339, e.Start)
+		if err != nil {
+			continue
+		}
+		_, offset := start.Zone()
+		day := start.Format("2006-01-02")
+		first, seen := offsets[day]
+		if !seen {
+			offsets[day] = offset
+			continue
+		}
+		if offset != first {
+			return true
+		}
+	}
+	return false
+}
+
+// LongestMeeting returns a copy of the event with the greatest duration,
+// preferring the earliest start on ties. All-day dates count as whole days.
+// Returns nil if no event has parseable times.
+func LongestMeeting(events []Event) *Event {
+	var longest *Event
+	var longestIV BusyInterval
+	for i := range events {
+		iv, ok := eventInterval(events[i], time.UTC)
+		if !ok {
+			continue
+		}
+		d, best := iv.End.Sub(iv.Start), longestIV.End.Sub(longestIV.Start)
+		if longest == nil || d > best || (d == best && iv.Start.Before(longestIV.Start)) {
+			longest = &events[i]
+			longestIV = iv
+		}
+	}
+
+	if longest == nil {
+		return nil
+	}
+	event := *longest
+	return &event
+}
+
+// DueNotifications returns events starting within [now, now+lead) whose
+// IDs aren't in alreadyNotified, and records their IDs there so the next
+// call, e.g. a minute later, doesn't ping again. A nil set records nothing.
+// Events with unparseable start times are skipped. To notify again when a
+// meeting is rescheduled, use DueNotificationsByKey with RescheduleKey.
+func DueNotifications(events []Event, now time.Time, lead time.Duration, alreadyNotified map[string]bool) []Event {
+	return DueNotificationsByKey(events, now, lead, alreadyNotified, func(e Event) string { return e.ID })
+}
+
+// DueNotificationsByKey is DueNotifications with alreadyNotified keyed by
+// key(e) instead of the event ID
+func DueNotificationsByKey(events []Event, now time.Time, lead time.Duration, alreadyNotified map[string]bool, key func(Event) string) []Event {
+	var due []Event
+	for _, e := range events {
+		start, err := time.Parse(time.RFC3339, e.Start)
+		if err != nil || start.Before(now) || !start.Before(now.Add(lead)) {
+			continue
+		}
+		k := key(e)
+		if alreadyNotified[k] {
+			continue
+		}
+		if alreadyNotified != nil {
+			alreadyNotified[k] = true
+		}
+		due = append(due, e)
+	}
+	return due
+}
+
+// RescheduleKey identifies an event by ID and start time, so that with
+// DueNotificationsByKey a rescheduled meeting is notified again
+func RescheduleKey(e Event) string {
+	return e.ID + "@" + e.Start
+}
+
+// EstimateCost returns a rough cost of e: its duration in hours times the
+// number of people, counting the user alongside AttendeeCount, times
+// hourlyRate. Returns 0 if the start or end isn't an RFC3339 time or the
+// event doesn't end after it starts.
+func EstimateCost(e Event, hourlyRate float64) float64 {
+	start, err := time.Parse(time.RFC3339, e.Start)
+	if err != nil {
+		return 0
+	}
+	end, err := time.Parse(time.RFC3339, e.End)
+	if err != nil || !end.After(start) {
+		return 0
+	}
+	return end.Sub(start).Hours() * float64(e.AttendeeCount+1) * hourlyRate
+}
@@ -0,0 +1,671 @@
+package gcal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestGetNextEvent(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+
+	eventAt := func(id string, offset time.Duration) Event {
+		return Event{
+			ID:    id,
+			Start: now.Add(offset).Format(time.RFC3339),
+			End:   now.Add(offset + 30*time.Minute).Format(time.RFC3339),
+		}
+	}
+
+	tests := []struct {
+		name        string
+		events      []Event
+		threshold   time.Duration
+		wantID      string
+		wantMinutes int
+		wantSoon    bool
+	}{
+		{
+			name:        "just inside default threshold",
+			events:      []Event{eventAt("soon", 4*time.Minute+59*time.Second)},
+			wantID:      "soon",
+			wantMinutes: 4,
+			wantSoon:    true,
+		},
+		{
+			name:        "exactly at default threshold",
+			events:      []Event{eventAt("edge", 5*time.Minute)},
+			wantID:      "edge",
+			wantMinutes: 5,
+			wantSoon:    true,
+		},
+		{
+			name:        "just outside default threshold",
+			events:      []Event{eventAt("later", 5*time.Minute+time.Second)},
+			wantID:      "later",
+			wantMinutes: 5,
+			wantSoon:    false,
+		},
+		{
+			name:        "custom threshold",
+			events:      []Event{eventAt("later", 12*time.Minute)},
+			threshold:   15 * time.Minute,
+			wantID:      "later",
+			wantMinutes: 12,
+			wantSoon:    true,
+		},
+		{
+			name: "skips started events and picks earliest upcoming",
+			events: []Event{
+				eventAt("started", -10*time.Minute),
+				eventAt("second", 2*time.Hour),
+				eventAt("first", time.Hour),
+			},
+			wantID:      "first",
+			wantMinutes: 60,
+			wantSoon:    false,
+		},
+		{
+			name:   "nothing upcoming",
+			events: []Event{eventAt("started", -time.Minute)},
+		},
+		{
+			name: "unparseable start is skipped",
+			events: []Event{
+				{ID: "bad", Start: "not-a-time"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt // Capture loop variable
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := GetNextEvent(tt.events, now, tt.threshold)
+			if tt.wantID == "" {
+				if got != nil {
+					t.Errorf("GetNextEvent() = %v, want nil", got.ID)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatalf("GetNextEvent() = nil, want %s", tt.wantID)
+			}
+			if got.ID != tt.wantID {
+				t.Errorf("GetNextEvent() ID = %v, want %v", got.ID, tt.wantID)
+			}
+			if got.MinutesUntilStart != tt.wantMinutes {
+				t.Errorf("GetNextEvent() MinutesUntilStart = %v, want %v", got.MinutesUntilStart, tt.wantMinutes)
+			}
+			if got.IsSoon != tt.wantSoon {
+				t.Errorf("GetNextEvent() IsSoon = %v, want %v", got.IsSoon, tt.wantSoon)
+			}
+		})
+	}
+}
+
+func TestGetNextEvent_DoesNotModifyInput(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+
+	events := []Event{
+		{ID: "event1", Start: now.Add(time.Minute).Format(time.RFC3339)},
+	}
+	if got := GetNextEvent(events, now, 0); got == nil || !got.IsSoon {
+		t.Fatalf("GetNextEvent() = %v, want soon event", got)
+	}
+	if events[0].IsSoon || events[0].MinutesUntilStart != 0 {
+		t.Error("GetNextEvent() should not modify the input slice")
+	}
+}
+
+func TestMeetingsWithoutAgenda(t *testing.T) {
+	t.Parallel()
+
+	events := []Event{
+		{
+			ID:            "one-on-one",
+			Title:         "1:1 with Alice",
+			Attendees:     []string{"Alice"},
+			AttendeeCount: 1,
+		},
+		{
+			ID:    "focus",
+			Title: "Focus time",
+		},
+		{
+			ID:            "planning",
+			Title:         "Sprint planning",
+			Description:   "1. Review last sprint\n2. Estimate backlog\n3. Commit to goals",
+			Attendees:     []string{"Alice", "Bob"},
+			AttendeeCount: 2,
+		},
+		{
+			ID:            "sync",
+			Title:         "Quick sync",
+			Description:   "  chat  ",
+			Attendees:     []string{"Bob"},
+			AttendeeCount: 1,
+		},
+	}
+
+	got := MeetingsWithoutAgenda(events)
+
+	var gotIDs []string
+	for _, e := range got {
+		gotIDs = append(gotIDs, e.ID)
+	}
+	if diff := cmp.Diff(gotIDs, []string{"one-on-one", "sync"}); diff != "" {
+		t.Errorf("MeetingsWithoutAgenda() mismatch (-got +want):\n%s", diff)
+	}
+}
+
+func TestUniqueAttendees(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		events []Event
+		want   int
+	}{
+		{
+			name: "no events",
+			want: 0,
+		},
+		{
+			name: "same person in multiple meetings counted once",
+			events: []Event{
+				{
+					ID: "standup",
+					AttendeeDetails: []Attendee{
+						{Name: "Alice", Email: "alice@example.com"},
+						{Name: "Bob", Email: "bob@example.com"},
+					},
+				},
+				{
+					ID: "1:1",
+					AttendeeDetails: []Attendee{
+						{Name: "Alice Smith", Email: "Alice@Example.com"},
+					},
+				},
+			},
+			want: 2,
+		},
+		{
+			name: "email preferred over differing display names",
+			events: []Event{
+				{ID: "a", AttendeeDetails: []Attendee{{Name: "Bob", Email: "bob@example.com"}}},
+				{ID: "b", AttendeeDetails: []Attendee{{Name: "Robert", Email: "bob@example.com"}}},
+			},
+			want: 1,
+		},
+		{
+			name: "falls back to Attendees without details",
+			events: []Event{
+				{ID: "a", Attendees: []string{"Alice", "carol@example.com"}},
+				{ID: "b", Attendees: []string{"alice", ""}},
+			},
+			want: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt // Capture loop variable
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := UniqueAttendees(tt.events); got != tt.want {
+				t.Errorf("UniqueAttendees() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAcceptanceRate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		details []Attendee
+		want    float64
+	}{
+		{
+			name: "all accepted",
+			details: []Attendee{
+				{Email: "alice@example.com", ResponseStatus: "accepted"},
+				{Email: "bob@example.com", ResponseStatus: "accepted"},
+			},
+			want: 1,
+		},
+		{
+			name: "half accepted",
+			details: []Attendee{
+				{Email: "alice@example.com", ResponseStatus: "accepted"},
+				{Email: "bob@example.com", ResponseStatus: "declined"},
+				{Email: "carol@example.com", ResponseStatus: "accepted"},
+				{Email: "dave@example.com", ResponseStatus: "needsAction"},
+			},
+			want: 0.5,
+		},
+		{
+			name: "empty",
+			want: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt // Capture loop variable
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := AcceptanceRate(tt.details); got != tt.want {
+				t.Errorf("AcceptanceRate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeclinedByOthers(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		details []Attendee
+		want    []string
+	}{
+		{
+			name: "mixed responses",
+			details: []Attendee{
+				{Name: "Alice", Email: "alice@example.com", ResponseStatus: "accepted"},
+				{Name: "Bob", Email: "bob@example.com", ResponseStatus: "declined"},
+				{Name: "Carol", Email: "carol@example.com", ResponseStatus: "tentative"},
+				{Name: "Dave", ResponseStatus: "declined"},
+				{Email: "erin@example.com", ResponseStatus: "needsAction"},
+			},
+			want: []string{"bob@example.com", "Dave"},
+		},
+		{
+			name: "all accepted",
+			details: []Attendee{
+				{Email: "alice@example.com", ResponseStatus: "accepted"},
+				{Email: "bob@example.com", ResponseStatus: "accepted"},
+			},
+		},
+		{
+			name: "empty",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt // Capture loop variable
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if diff := cmp.Diff(DeclinedByOthers(tt.details), tt.want); diff != "" {
+				t.Errorf("DeclinedByOthers() mismatch (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGroupByProvider(t *testing.T) {
+	t.Parallel()
+
+	events := []Event{
+		{ID: "zoom1", MeetingURL: "https://us02web.zoom.us/j/123"},
+		{ID: "meet1", MeetingURL: "https://meet.google.com/abc-defg-hij"},
+		{ID: "in-person"},
+		{ID: "zoom2", MeetingURL: "https://zoom.us/j/456"},
+		{ID: "phone"},
+	}
+
+	got := GroupByProvider(events)
+
+	gotIDs := make(map[string][]string)
+	for provider, group := range got {
+		for _, e := range group {
+			gotIDs[provider] = append(gotIDs[provider], e.ID)
+		}
+	}
+	want := map[string][]string{
+		"zoom": {"zoom1", "zoom2"},
+		"meet": {"meet1"},
+		"":     {"in-person", "phone"},
+	}
+	if diff := cmp.Diff(gotIDs, want); diff != "" {
+		t.Errorf("GroupByProvider() mismatch (-got +want):\n%s", diff)
+	}
+}
+
+func TestBucketBySize(t *testing.T) {
+	t.Parallel()
+
+	events := []Event{
+		{ID: "solo", AttendeeCount: 0},
+		{ID: "one-on-one", AttendeeCount: 1},
+		{ID: "pair", AttendeeCount: 2},
+		{ID: "five", AttendeeCount: 5},
+		{ID: "six", AttendeeCount: 6},
+		{ID: "fifteen", AttendeeCount: 15},
+		{ID: "sixteen", AttendeeCount: 16},
+		{ID: "all-hands", AttendeeCount: 200},
+		{ID: "another-one-on-one", AttendeeCount: 1},
+	}
+
+	got := BucketBySize(events)
+
+	gotIDs := make(map[string][]string)
+	for size, group := range got {
+		for _, e := range group {
+			gotIDs[size] = append(gotIDs[size], e.ID)
+		}
+	}
+	want := map[string][]string{
+		"":       {"solo"},
+		"1:1":    {"one-on-one", "another-one-on-one"},
+		"small":  {"pair", "five"},
+		"medium": {"six", "fifteen"},
+		"large":  {"sixteen", "all-hands"},
+	}
+	if diff := cmp.Diff(gotIDs, want); diff != "" {
+		t.Errorf("BucketBySize() mismatch (-got +want):\n%s", diff)
+	}
+}
+
+func TestCountByCalendar(t *testing.T) {
+	t.Parallel()
+
+	events := []Event{
+		{ID: "a", CalendarID: "work@example.com"},
+		{ID: "b", CalendarID: "team@example.com"},
+		{ID: "c", CalendarID: "work@example.com"},
+		{ID: "d", CalendarID: "personal@example.com"},
+		{ID: "e", CalendarID: "work@example.com"},
+	}
+
+	want := map[string]int{
+		"work@example.com":     3,
+		"team@example.com":     1,
+		"personal@example.com": 1,
+	}
+	if diff := cmp.Diff(CountByCalendar(events), want); diff != "" {
+		t.Errorf("CountByCalendar() mismatch (-got +want):\n%s", diff)
+	}
+
+	if diff := cmp.Diff(CountByCalendar(nil), map[string]int{}); diff != "" {
+		t.Errorf("CountByCalendar(nil) mismatch (-got +want):\n%s", diff)
+	}
+}
+
+func TestDetectTimezoneMismatches(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		events []Event
+		want   bool
+	}{
+		{
+			name: "mixed offsets",
+			events: []Event{
+				{ID: "london", Start: "2024-01-15T09:00:00+00:00"},
+				{ID: "new-york", Start: "2024-01-15T14:00:00-05:00"},
+			},
+			want: true,
+		},
+		{
+			name: "same offset",
+			events: []Event{
+				{ID: "a", Start: "2024-01-15T09:00:00-05:00"},
+				{ID: "b", Start: "2024-01-15T14:00:00-05:00"},
+			},
+			want: false,
+		},
+		{
+			name: "Z and +00:00 are the same offset",
+			events: []Event{
+				{ID: "a", Start: "2024-01-15T09:00:00Z"},
+				{ID: "b", Start: "2024-01-15T14:00:00+00:00"},
+			},
+			want: false,
+		},
+		{
+			name: "across a daylight saving change",
+			events: []Event{
+				{ID: "friday", Start: "2024-03-08T09:00:00-05:00"},
+				{ID: "monday", Start: "2024-03-11T09:00:00-04:00"},
+			},
+			want: false,
+		},
+		{
+			name: "mixed offsets on one day of a multi-day range",
+			events: []Event{
+				{ID: "friday", Start: "2024-03-08T09:00:00-05:00"},
+				{ID: "monday", Start: "2024-03-11T09:00:00-04:00"},
+				{ID: "monday-london", Start: "2024-03-11T16:00:00+00:00"},
+			},
+			want: true,
+		},
+		{
+			name: "all-day and invalid ignored",
+			events: []Event{
+				{ID: "a", Start: "2024-01-15T09:00:00-05:00"},
+				{ID: "holiday", Start: "2024-01-15"},
+				{ID: "bad", Start: "not a time"},
+			},
+			want: false,
+		},
+		{
+			name: "empty",
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt // Capture loop variable
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := DetectTimezoneMismatches(tt.events); got != tt.want {
+				t.Errorf("DetectTimezoneMismatches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLongestMeeting(t *testing.T) {
+	t.Parallel()
+	start := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+
+	at := func(id string, offset, duration time.Duration) Event {
+		return Event{
+			ID:    id,
+			Start: start.Add(offset).Format(time.RFC3339),
+			End:   start.Add(offset + duration).Format(time.RFC3339),
+		}
+	}
+
+	tests := []struct {
+		name   string
+		events []Event
+		wantID string
+	}{
+		{
+			name: "clear winner",
+			events: []Event{
+				at("standup", 0, 15*time.Minute),
+				at("planning", time.Hour, 2*time.Hour),
+				at("1:1", 4*time.Hour, 30*time.Minute),
+			},
+			wantID: "planning",
+		},
+		{
+			name: "tie goes to earliest start",
+			events: []Event{
+				at("afternoon", 5*time.Hour, time.Hour),
+				at("morning", time.Hour, time.Hour),
+				at("short", 0, 30*time.Minute),
+			},
+			wantID: "morning",
+		},
+		{
+			name: "unparseable skipped",
+			events: []Event{
+				{ID: "bad", Start: "not a time", End: "later"},
+				at("ok", 0, 10*time.Minute),
+			},
+			wantID: "ok",
+		},
+		{
+			name:   "all unparseable",
+			events: []Event{{ID: "bad", Start: "not a time"}},
+		},
+		{
+			name: "empty",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt // Capture loop variable
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := LongestMeeting(tt.events)
+			if tt.wantID == "" {
+				if got != nil {
+					t.Errorf("LongestMeeting() = %v, want nil", got.ID)
+				}
+				return
+			}
+			if got == nil || got.ID != tt.wantID {
+				t.Errorf("LongestMeeting() = %v, want %s", got, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestDueNotifications(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+
+	eventAt := func(id string, offset time.Duration) Event {
+		return Event{
+			ID:    id,
+			Start: now.Add(offset).Format(time.RFC3339),
+			End:   now.Add(offset + 30*time.Minute).Format(time.RFC3339),
+		}
+	}
+	ids := func(events []Event) []string {
+		var got []string
+		for _, e := range events {
+			got = append(got, e.ID)
+		}
+		return got
+	}
+
+	events := []Event{
+		eventAt("started", -time.Minute),
+		eventAt("now", 0),
+		eventAt("soon", 4*time.Minute),
+		eventAt("at-lead", 5*time.Minute),
+		eventAt("later", time.Hour),
+		{ID: "bad", Start: "not a time"},
+	}
+	notified := make(map[string]bool)
+
+	// First fire
+	if diff := cmp.Diff(ids(DueNotifications(events, now, 5*time.Minute, notified)), []string{"now", "soon"}); diff != "" {
+		t.Errorf("DueNotifications() first fire mismatch (-got +want):\n%s", diff)
+	}
+
+	// A minute later the same events are suppressed; at-lead is now due
+	next := now.Add(time.Minute)
+	if diff := cmp.Diff(ids(DueNotifications(events, next, 5*time.Minute, notified)), []string{"at-lead"}); diff != "" {
+		t.Errorf("DueNotifications() repeat mismatch (-got +want):\n%s", diff)
+	}
+
+	// The set holds plain event IDs, so callers can seed or persist it
+	if diff := cmp.Diff(notified, map[string]bool{"now": true, "soon": true, "at-lead": true}); diff != "" {
+		t.Errorf("DueNotifications() notified set mismatch (-got +want):\n%s", diff)
+	}
+	seeded := map[string]bool{"soon": true}
+	if diff := cmp.Diff(ids(DueNotifications(events, now, 5*time.Minute, seeded)), []string{"now"}); diff != "" {
+		t.Errorf("DueNotifications() with seeded IDs mismatch (-got +want):\n%s", diff)
+	}
+
+	// A rescheduled meeting keeps its ID, so isn't notified again...
+	moved := []Event{eventAt("soon", 3*time.Minute)}
+	if got := DueNotifications(moved, next, 5*time.Minute, notified); len(got) != 0 {
+		t.Errorf("DueNotifications() rescheduled = %v, want none", ids(got))
+	}
+
+	// ...unless keyed by start time too
+	byStart := make(map[string]bool)
+	DueNotificationsByKey(events, now, 5*time.Minute, byStart, RescheduleKey)
+	if diff := cmp.Diff(ids(DueNotificationsByKey(moved, next, 5*time.Minute, byStart, RescheduleKey)), []string{"soon"}); diff != "" {
+		t.Errorf("DueNotificationsByKey() rescheduled mismatch (-got +want):\n%s", diff)
+	}
+
+	if got := DueNotifications(events, now, 5*time.Minute, nil); len(got) != 2 {
+		t.Errorf("DueNotifications() with nil set returned %d events, want 2", len(got))
+	}
+}
+
+func TestEstimateCost(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		event Event
+		rate  float64
+		want  float64
+	}{
+		{
+			name:  "one hour with five people",
+			event: Event{Start: "2024-01-15T14:00:00Z", End: "2024-01-15T15:00:00Z", AttendeeCount: 4},
+			rate:  100,
+			want:  500,
+		},
+		{
+			name:  "half hour one-on-one",
+			event: Event{Start: "2024-01-15T14:00:00Z", End: "2024-01-15T14:30:00Z", AttendeeCount: 1},
+			rate:  80,
+			want:  80,
+		},
+		{
+			name:  "offsets are respected",
+			event: Event{Start: "2024-01-15T09:00:00-05:00", End: "2024-01-15T15:00:00Z", AttendeeCount: 1},
+			rate:  50,
+			want:  100,
+		},
+		{
+			name:  "unparseable start",
+			event: Event{Start: "not a time", End: "2024-01-15T15:00:00Z", AttendeeCount: 4},
+			rate:  100,
+		},
+		{
+			name:  "all-day dates",
+			event: Event{Start: "2024-01-15", End: "2024-01-16", AttendeeCount: 4},
+			rate:  100,
+		},
+		{
+			name:  "ends before it starts",
+			event: Event{Start: "2024-01-15T15:00:00Z", End: "2024-01-15T14:00:00Z", AttendeeCount: 4},
+			rate:  100,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt // Capture loop variable
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := EstimateCost(tt.event, tt.rate); got != tt.want {
+				t.Errorf("EstimateCost() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,384 @@
+// Package gcal provides exporters for writing events in spreadsheet and calendar formats.
+package gcal
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// tsvHeader lists the columns written by EventsToTSV
+var tsvHeader = []string{
+	"id",
+	"title",
+	"start",
+	"end",
+	"attendees",
+	"attendeeCount",
+	"meetingUrl",
+	"hasConflict",
+	"responseStatus",
+}
+
+// tsvFieldReplacer keeps each field on a single cell by replacing
+// characters that would split a TSV row or column
+var tsvFieldReplacer = strings.NewReplacer("\r\n", " ", "\t", " ", "\n", " ", "\r", " ")
+
+// EventsToTSV writes events as tab-separated values with a header row.
+// The output pastes cleanly into Google Sheets; tabs and newlines inside
+// fields are replaced by spaces so every event stays on one row.
+func EventsToTSV(events []Event, w io.Writer) error {
+	if err := writeTSVRow(w, tsvHeader); err != nil {
+		return err
+	}
+
+	for _, e := range events {
+		row := []string{
+			e.ID,
+			e.Title,
+			e.Start,
+			e.End,
+			strings.Join(e.Attendees, "; "),
+			strconv.Itoa(e.AttendeeCount),
+			e.MeetingURL,
+			strconv.FormatBool(e.HasConflict),
+			e.ResponseStatus,
+		}
+		if err := writeTSVRow(w, row); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeTSVRow sanitizes and writes a single TSV row
+func writeTSVRow(w io.Writer, fields []string) error {
+	sanitized := make([]string, len(fields))
+	for i, f := range fields {
+		sanitized[i] = tsvFieldReplacer.Replace(f)
+	}
+	if _, err := io.WriteString(w, strings.Join(sanitized, "\t")+"\n"); err != nil {
+		return fmt.Errorf("write tsv row: %w", err)
+	}
+	return nil
+}
+
+// icsTimeLayout is the UTC date-time format used in iCalendar output
+const icsTimeLayout = "20060102T150405Z"
+
+// icsTextEscaper escapes TEXT property values per RFC 5545
+var icsTextEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+// EventsToICS writes events as an iCalendar (RFC 5545) VCALENDAR. Events with
+// reminders get a VALARM per reminder so imported events keep them: popup
+// reminders become DISPLAY alarms and email reminders become EMAIL alarms
+// addressed to the organizer on events organized by the user. An EMAIL
+// alarm needs a recipient, so other email reminders become DISPLAY alarms.
+func EventsToICS(events []Event, w io.Writer) error {
+	return writeICS(events, w, time.Now())
+}
+
+// writeICS writes the calendar using now as the DTSTAMP of every event
+func writeICS(events []Event, w io.Writer, now time.Time) error {
+	iw := &icsWriter{w: w}
+
+	iw.line("BEGIN:VCALENDAR")
+	iw.line("VERSION:2.0")
+	iw.line("PRODID:-//gcal//gcal//EN")
+	iw.line("CALSCALE:GREGORIAN")
+
+	for _, e := range events {
+		iw.line("BEGIN:VEVENT")
+		iw.line("UID:" + icsText(e.ID))
+		iw.line("DTSTAMP:" + now.UTC().Format(icsTimeLayout))
+		iw.line(icsDateProperty("DTSTART", e.Start))
+		iw.line(icsDateProperty("DTEND", e.End))
+		iw.line("SUMMARY:" + icsText(e.Title))
+		if e.MeetingURL != "" {
+			iw.line("URL:" + e.MeetingURL)
+		}
+		for _, r := range e.Reminders {
+			iw.line("BEGIN:VALARM")
+			if r.Method == "email" && e.OrganizedByMe && e.OrganizerEmail != "" {
+				iw.line("ACTION:EMAIL")
+				iw.line("SUMMARY:" + icsText(e.Title))
+				iw.line("ATTENDEE:mailto:" + e.OrganizerEmail)
+			} else {
+				iw.line("ACTION:DISPLAY")
+			}
+			iw.line("DESCRIPTION:" + icsText(e.Title))
+			iw.line("TRIGGER:" + icsTrigger(r.Minutes))
+			iw.line("END:VALARM")
+		}
+		iw.line("END:VEVENT")
+	}
+
+	iw.line("END:VCALENDAR")
+	return iw.err
+}
+
+// FreeBusyToICS returns an iCalendar VCALENDAR holding a single VFREEBUSY
+// for [start, end), with a FREEBUSY period in UTC for each busy interval,
+// so availability can be shared without event details. Intervals are
+// clipped to the range and overlapping ones merged.
+func FreeBusyToICS(busy []BusyInterval, start, end time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := writeFreeBusyICS(busy, start, end, &buf, time.Now()); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeFreeBusyICS writes the free/busy calendar using now as its DTSTAMP
+func writeFreeBusyICS(busy []BusyInterval, start, end time.Time, w io.Writer, now time.Time) error {
+	if !end.After(start) {
+		return fmt.Errorf("free/busy end must be after start")
+	}
+
+	var clipped []BusyInterval
+	for _, iv := range busy {
+		if iv, ok := clipInterval(iv, start, end); ok {
+			clipped = append(clipped, iv)
+		}
+	}
+
+	from, to := start.UTC().Format(icsTimeLayout), end.UTC().Format(icsTimeLayout)
+	iw := &icsWriter{w: w}
+
+	iw.line("BEGIN:VCALENDAR")
+	iw.line("VERSION:2.0")
+	iw.line("PRODID:-//gcal//gcal//EN")
+	iw.line("METHOD:PUBLISH")
+	iw.line("BEGIN:VFREEBUSY")
+	iw.line("UID:freebusy-" + from + "-" + to + "@gcal")
+	iw.line("DTSTAMP:" + now.UTC().Format(icsTimeLayout))
+	iw.line("DTSTART:" + from)
+	iw.line("DTEND:" + to)
+	for _, iv := range mergeIntervals(clipped) {
+		iw.line("FREEBUSY;FBTYPE=BUSY:" + iv.Start.UTC().Format(icsTimeLayout) + "/" + iv.End.UTC().Format(icsTimeLayout))
+	}
+	iw.line("END:VFREEBUSY")
+	iw.line("END:VCALENDAR")
+	return iw.err
+}
+
+// icsWriter writes CRLF-terminated, folded content lines, remembering the
+// first write error
+type icsWriter struct {
+	w   io.Writer
+	err error
+}
+
+// line writes a content line, folding it at 75 octets as RFC 5545 requires
+func (iw *icsWriter) line(s string) {
+	if iw.err != nil {
+		return
+	}
+
+	var b strings.Builder
+	limit := 75
+	for len(s) > limit {
+		cut := limit
+		// Don't split a multi-byte UTF-8 sequence
+		for cut > 0 && s[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		b.WriteString(s[:cut])
+		b.WriteString("\r\n ")
+		s = s[cut:]
+		limit = 74 // continuation lines start with a space
+	}
+	b.WriteString(s)
+	b.WriteString("\r\n")
+
+	if _, err := io.WriteString(iw.w, b.String()); err != nil {
+		iw.err = fmt.Errorf("write ics: %w", err)
+	}
+}
+
+// icsText escapes a TEXT property value
+func icsText(s string) string {
+	return icsTextEscaper.Replace(s)
+}
+
+// icsDateProperty formats an RFC3339 timestamp as a UTC date-time, or a
+// bare all-day date as a DATE value
+func icsDateProperty(name, value string) string {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return name + ":" + t.UTC().Format(icsTimeLayout)
+	}
+	if t, err := time.Parse(allDayLayout, value); err == nil {
+		return name + ";VALUE=DATE:" + t.Format("20060102")
+	}
+	return name + ":" + value
+}
+
+// icsTrigger formats a reminder offset as a negative duration before start
+func icsTrigger(minutes int) string {
+	switch {
+	case minutes > 0 && minutes%(24*60) == 0:
+		return fmt.Sprintf("-P%dD", minutes/(24*60))
+	case minutes > 0 && minutes%60 == 0:
+		return fmt.Sprintf("-PT%dH", minutes/60)
+	default:
+		return fmt.Sprintf("-PT%dM", minutes)
+	}
+}
+
+// TimeFormat selects how clock times are rendered in table output
+type TimeFormat int
+
+const (
+	// TimeFormat24h renders times like 14:00 (the default)
+	TimeFormat24h TimeFormat = iota
+	// TimeFormat12h renders times like 2:00 PM
+	TimeFormat12h
+)
+
+// layout returns the time layout for the format
+func (f TimeFormat) layout() string {
+	if f == TimeFormat12h {
+		return "3:04 PM"
+	}
+	return "15:04"
+}
+
+// TableOptions controls EventsToTable output
+type TableOptions struct {
+	// TimeFormat selects 24h or 12h clock times. Defaults to 24h so output
+	// is deterministic regardless of locale.
+	TimeFormat TimeFormat
+
+	// Location is the timezone times are shown in. Defaults to local time.
+	Location *time.Location
+}
+
+// EventsToTable writes events as an aligned, human-readable table
+func EventsToTable(events []Event, w io.Writer, opts TableOptions) error {
+	loc := opts.Location
+	if loc == nil {
+		loc = time.Local
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TIME\tTITLE\tATTENDEES\tCONFLICT")
+	for _, e := range events {
+		conflict := ""
+		if e.HasConflict {
+			conflict = "yes"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\n",
+			formatTimeRange(e, loc, opts.TimeFormat),
+			tsvFieldReplacer.Replace(e.Title),
+			e.AttendeeCount,
+			conflict,
+		)
+	}
+
+	if err := tw.Flush(); err != nil {
+		return fmt.Errorf("write table: %w", err)
+	}
+	return nil
+}
+
+// formatTimeRange renders an event's start and end as clock times in loc,
+// falling back to the raw values when they can't be parsed
+func formatTimeRange(e Event, loc *time.Location, format TimeFormat) string {
+	start, errStart := time.Parse(time.RFC3339, e.Start)
+	end, errEnd := time.Parse(time.RFC3339, e.End)
+	if errStart != nil || errEnd != nil {
+		return e.Start + " - " + e.End
+	}
+	layout := format.layout()
+	return start.In(loc).Format(layout) + " - " + end.In(loc).Format(layout)
+}
+
+// Timeline glyphs, from free to fully booked, plus overlapping meetings
+const (
+	timelineFree     = ' '
+	timelinePartial  = '-' // busy under half the column
+	timelineMostly   = '=' // busy at least half the column
+	timelineFull     = '#'
+	timelineConflict = 'X'
+)
+
+// RenderTimeline draws [dayStart, dayEnd) as a bar width columns wide for
+// terminal dashboards. Each column covers an equal slice of the day and is
+// drawn by how busy that slice is: ' ' free, '-' under half, '=' at least
+// half, '#' fully booked, or 'X' where meetings flagged HasConflict
+// overlap. A second line labels the start and end times. All-day events,
+// out-of-office blocks and markers don't book any time, matching conflict
+// detection.
+func RenderTimeline(events []Event, dayStart, dayEnd time.Time, width int, w io.Writer) error {
+	if width <= 0 {
+		return fmt.Errorf("timeline width must be positive, got %d", width)
+	}
+	if !dayEnd.After(dayStart) {
+		return fmt.Errorf("timeline end must be after start")
+	}
+
+	var booked []Event
+	for _, e := range events {
+		if !e.AllDay && !e.Marker && !isOutOfOffice(e) {
+			booked = append(booked, e)
+		}
+	}
+	merged := mergeIntervals(busyIntervals(booked, dayStart.Location()))
+
+	var conflicting []BusyInterval
+	for _, e := range booked {
+		if iv, ok := eventInterval(e, dayStart.Location()); ok && e.HasConflict {
+			conflicting = append(conflicting, iv)
+		}
+	}
+	span := dayEnd.Sub(dayStart)
+
+	bar := make([]byte, width)
+	for col := range bar {
+		colStart := dayStart.Add(span * time.Duration(col) / time.Duration(width))
+		colEnd := dayStart.Add(span * time.Duration(col+1) / time.Duration(width))
+
+		var overlapping []BusyInterval
+		for _, iv := range conflicting {
+			if clipped, ok := clipInterval(iv, colStart, colEnd); ok {
+				overlapping = append(overlapping, clipped)
+			}
+		}
+		if peakConcurrency(overlapping) > 1 {
+			bar[col] = timelineConflict
+			continue
+		}
+
+		var busy time.Duration
+		for _, iv := range merged {
+			if clipped, ok := clipInterval(iv, colStart, colEnd); ok {
+				busy += clipped.End.Sub(clipped.Start)
+			}
+		}
+		switch colLen := colEnd.Sub(colStart); {
+		case busy == 0:
+			bar[col] = timelineFree
+		case busy >= colLen:
+			bar[col] = timelineFull
+		case 2*busy >= colLen:
+			bar[col] = timelineMostly
+		default:
+			bar[col] = timelinePartial
+		}
+	}
+
+	startLabel, endLabel := dayStart.Format("15:04"), dayEnd.Format("15:04")
+	padding := width + 2 - len(startLabel) - len(endLabel)
+	if padding < 1 {
+		padding = 1
+	}
+	if _, err := fmt.Fprintf(w, "|%s|\n%s%s%s\n", bar, startLabel, strings.Repeat(" ", padding), endLabel); err != nil {
+		return fmt.Errorf("write timeline: %w", err)
+	}
+	return nil
+}
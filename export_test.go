@@ -0,0 +1,436 @@
+package gcal
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestEventsToTSV(t *testing.T) {
+	t.Parallel()
+
+	events := []Event{
+		{
+			ID:             "event1",
+			Title:          "Planning\tQ3\nroadmap",
+			Start:          "2024-01-15T14:00:00Z",
+			End:            "2024-01-15T15:00:00Z",
+			Attendees:      []string{"Alice", "Bob"},
+			AttendeeCount:  2,
+			MeetingURL:     "https://meet.google.com/abc-defg-hij",
+			HasConflict:    true,
+			ResponseStatus: "accepted",
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := EventsToTSV(events, &buf); err != nil {
+		t.Fatalf("EventsToTSV() error = %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("EventsToTSV() wrote %d lines, want 2 (header + 1 event):\n%s", len(lines), buf.String())
+	}
+
+	wantHeader := "id\ttitle\tstart\tend\tattendees\tattendeeCount\tmeetingUrl\thasConflict\tresponseStatus"
+	if diff := cmp.Diff(lines[0], wantHeader); diff != "" {
+		t.Errorf("EventsToTSV() header mismatch (-got +want):\n%s", diff)
+	}
+
+	got := strings.Split(lines[1], "\t")
+	want := []string{
+		"event1",
+		"Planning Q3 roadmap",
+		"2024-01-15T14:00:00Z",
+		"2024-01-15T15:00:00Z",
+		"Alice; Bob",
+		"2",
+		"https://meet.google.com/abc-defg-hij",
+		"true",
+		"accepted",
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("EventsToTSV() row mismatch (-got +want):\n%s", diff)
+	}
+}
+
+func TestEventsToTSV_Empty(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	if err := EventsToTSV(nil, &buf); err != nil {
+		t.Fatalf("EventsToTSV() error = %v", err)
+	}
+	if strings.Count(buf.String(), "\n") != 1 {
+		t.Errorf("EventsToTSV() with no events should write only the header, got:\n%s", buf.String())
+	}
+}
+
+func TestEventsToICS_Reminders(t *testing.T) {
+	t.Parallel()
+
+	events := []Event{
+		{
+			ID:             "event1",
+			Title:          "Standup",
+			Start:          "2024-01-15T09:00:00-05:00",
+			End:            "2024-01-15T09:15:00-05:00",
+			OrganizerEmail: "me@example.com",
+			OrganizedByMe:  true,
+			Reminders: []Reminder{
+				{Method: "popup", Minutes: 10},
+				{Method: "email", Minutes: 24 * 60},
+			},
+		},
+		{
+			ID:    "event2",
+			Title: "No reminders",
+			Start: "2024-01-15T11:00:00Z",
+			End:   "2024-01-15T12:00:00Z",
+		},
+		{
+			// Invited by someone else, so there's no address to email
+			ID:             "event3",
+			Title:          "Review",
+			Start:          "2024-01-15T13:00:00Z",
+			End:            "2024-01-15T14:00:00Z",
+			OrganizerEmail: "alice@example.com",
+			Reminders:      []Reminder{{Method: "email", Minutes: 30}},
+		},
+	}
+
+	var buf bytes.Buffer
+	now := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+	if err := writeICS(events, &buf, now); err != nil {
+		t.Fatalf("writeICS() error = %v", err)
+	}
+
+	want := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//gcal//gcal//EN",
+		"CALSCALE:GREGORIAN",
+		"BEGIN:VEVENT",
+		"UID:event1",
+		"DTSTAMP:20240110T120000Z",
+		"DTSTART:20240115T140000Z",
+		"DTEND:20240115T141500Z",
+		"SUMMARY:Standup",
+		"BEGIN:VALARM",
+		"ACTION:DISPLAY",
+		"DESCRIPTION:Standup",
+		"TRIGGER:-PT10M",
+		"END:VALARM",
+		"BEGIN:VALARM",
+		"ACTION:EMAIL",
+		"SUMMARY:Standup",
+		"ATTENDEE:mailto:me@example.com",
+		"DESCRIPTION:Standup",
+		"TRIGGER:-P1D",
+		"END:VALARM",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:event2",
+		"DTSTAMP:20240110T120000Z",
+		"DTSTART:20240115T110000Z",
+		"DTEND:20240115T120000Z",
+		"SUMMARY:No reminders",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:event3",
+		"DTSTAMP:20240110T120000Z",
+		"DTSTART:20240115T130000Z",
+		"DTEND:20240115T140000Z",
+		"SUMMARY:Review",
+		"BEGIN:VALARM",
+		"ACTION:DISPLAY",
+		"DESCRIPTION:Review",
+		"TRIGGER:-PT30M",
+		"END:VALARM",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n") + "\r\n"
+
+	if diff := cmp.Diff(buf.String(), want); diff != "" {
+		t.Errorf("writeICS() mismatch (-got +want):\n%s", diff)
+	}
+}
+
+func TestFreeBusyToICS(t *testing.T) {
+	t.Parallel()
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("LoadLocation() error = %v", err)
+	}
+	start := time.Date(2024, 1, 15, 9, 0, 0, 0, ny)
+	end := time.Date(2024, 1, 15, 17, 0, 0, 0, ny)
+	at := func(hour, min int) time.Time {
+		return time.Date(2024, 1, 15, hour, min, 0, 0, ny)
+	}
+
+	busy := []BusyInterval{
+		{Start: at(13, 0), End: at(14, 0)},
+		{Start: at(8, 0), End: at(9, 30)},  // clipped to the range start
+		{Start: at(10, 0), End: at(11, 0)}, // merged with the next
+		{Start: at(10, 30), End: at(11, 30)},
+		{Start: at(18, 0), End: at(19, 0)}, // outside the range
+	}
+
+	var buf bytes.Buffer
+	now := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+	if err := writeFreeBusyICS(busy, start, end, &buf, now); err != nil {
+		t.Fatalf("writeFreeBusyICS() error = %v", err)
+	}
+
+	want := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//gcal//gcal//EN",
+		"METHOD:PUBLISH",
+		"BEGIN:VFREEBUSY",
+		"UID:freebusy-20240115T140000Z-20240115T220000Z@gcal",
+		"DTSTAMP:20240110T120000Z",
+		"DTSTART:20240115T140000Z",
+		"DTEND:20240115T220000Z",
+		"FREEBUSY;FBTYPE=BUSY:20240115T140000Z/20240115T143000Z",
+		"FREEBUSY;FBTYPE=BUSY:20240115T150000Z/20240115T163000Z",
+		"FREEBUSY;FBTYPE=BUSY:20240115T180000Z/20240115T190000Z",
+		"END:VFREEBUSY",
+		"END:VCALENDAR",
+	}, "\r\n") + "\r\n"
+
+	if diff := cmp.Diff(buf.String(), want); diff != "" {
+		t.Errorf("writeFreeBusyICS() mismatch (-got +want):\n%s", diff)
+	}
+
+	if _, err := FreeBusyToICS(busy, end, start); err == nil {
+		t.Error("FreeBusyToICS() with end before start error = nil, want error")
+	}
+}
+
+func TestEventsToICS_EscapingAndFolding(t *testing.T) {
+	t.Parallel()
+
+	events := []Event{
+		{
+			ID:    "event1",
+			Title: "Q3 planning; budget, hiring\nand a very long title that keeps going past the folding limit " + strings.Repeat("and then some more ", 10),
+			Start: "2024-01-15",
+			End:   "2024-01-16",
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := EventsToICS(events, &buf); err != nil {
+		t.Fatalf("EventsToICS() error = %v", err)
+	}
+	out := buf.String()
+
+	if !strings.Contains(out, "DTSTART;VALUE=DATE:20240115\r\n") {
+		t.Errorf("EventsToICS() missing all-day DTSTART:\n%s", out)
+	}
+	unfolded := strings.ReplaceAll(out, "\r\n ", "")
+	if !strings.Contains(unfolded, `SUMMARY:Q3 planning\; budget\, hiring\nand a very long title`) {
+		t.Errorf("EventsToICS() SUMMARY not escaped:\n%s", out)
+	}
+	for _, line := range strings.Split(out, "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("EventsToICS() line exceeds 75 octets: %q", line)
+		}
+	}
+}
+
+func TestICSTrigger(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		minutes int
+		want    string
+	}{
+		{minutes: 0, want: "-PT0M"},
+		{minutes: 10, want: "-PT10M"},
+		{minutes: 90, want: "-PT90M"},
+		{minutes: 120, want: "-PT2H"},
+		{minutes: 2 * 24 * 60, want: "-P2D"},
+	}
+
+	for _, tt := range tests {
+		if got := icsTrigger(tt.minutes); got != tt.want {
+			t.Errorf("icsTrigger(%d) = %q, want %q", tt.minutes, got, tt.want)
+		}
+	}
+}
+
+func TestEventsToTable(t *testing.T) {
+	t.Parallel()
+
+	events := []Event{
+		{
+			ID:            "event1",
+			Title:         "Standup",
+			Start:         "2024-01-15T09:00:00Z",
+			End:           "2024-01-15T09:15:00Z",
+			AttendeeCount: 4,
+		},
+		{
+			ID:            "event2",
+			Title:         "Design review",
+			Start:         "2024-01-15T14:00:00Z",
+			End:           "2024-01-15T15:30:00Z",
+			AttendeeCount: 2,
+			HasConflict:   true,
+		},
+	}
+
+	tests := []struct {
+		name string
+		opts TableOptions
+		want string
+	}{
+		{
+			name: "24h by default",
+			opts: TableOptions{Location: time.UTC},
+			want: "" +
+				"TIME           TITLE          ATTENDEES  CONFLICT\n" +
+				"09:00 - 09:15  Standup        4          \n" +
+				"14:00 - 15:30  Design review  2          yes\n",
+		},
+		{
+			name: "12h",
+			opts: TableOptions{TimeFormat: TimeFormat12h, Location: time.UTC},
+			want: "" +
+				"TIME               TITLE          ATTENDEES  CONFLICT\n" +
+				"9:00 AM - 9:15 AM  Standup        4          \n" +
+				"2:00 PM - 3:30 PM  Design review  2          yes\n",
+		},
+		{
+			name: "times shown in the given location",
+			opts: TableOptions{Location: time.FixedZone("EST", -5*60*60)},
+			want: "" +
+				"TIME           TITLE          ATTENDEES  CONFLICT\n" +
+				"04:00 - 04:15  Standup        4          \n" +
+				"09:00 - 10:30  Design review  2          yes\n",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt // Capture loop variable
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			if err := EventsToTable(events, &buf, tt.opts); err != nil {
+				t.Fatalf("EventsToTable() error = %v", err)
+			}
+			if diff := cmp.Diff(buf.String(), tt.want); diff != "" {
+				t.Errorf("EventsToTable() mismatch (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRenderTimeline(t *testing.T) {
+	t.Parallel()
+	dayStart := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+	dayEnd := time.Date(2024, 1, 15, 17, 0, 0, 0, time.UTC)
+
+	at := func(id, start, end string) Event {
+		return Event{ID: id, Start: "2024-01-15T" + start + ":00Z", End: "2024-01-15T" + end + ":00Z"}
+	}
+	conflict := func(e Event) Event {
+		e.HasConflict = true
+		return e
+	}
+
+	tests := []struct {
+		name   string
+		events []Event
+		want   string
+	}{
+		{
+			name: "half-booked day",
+			events: []Event{
+				at("morning", "09:00", "11:00"),
+				at("afternoon", "13:00", "15:00"),
+			},
+			want: "" +
+				"|####    ####    |\n" +
+				"09:00        17:00\n",
+		},
+		{
+			name: "partial columns and conflicts",
+			events: []Event{
+				at("standup", "09:00", "09:15"),
+				conflict(at("review", "10:00", "11:00")),
+				conflict(at("overlap", "10:30", "11:30")),
+				at("quick", "12:00", "12:10"),
+			},
+			want: "" +
+				"|= #X# -         |\n" +
+				"09:00        17:00\n",
+		},
+		{
+			name: "overlap not flagged as a conflict",
+			events: []Event{
+				at("review", "10:00", "11:00"),
+				at("overlap", "10:30", "11:30"),
+			},
+			want: "" +
+				"|  ###           |\n" +
+				"09:00        17:00\n",
+		},
+		{
+			name: "all-day events, out-of-office and markers book nothing",
+			events: []Event{
+				{ID: "holiday", Start: "2024-01-15", End: "2024-01-16", AllDay: true},
+				{ID: "away", Start: "2024-01-15T13:00:00Z", End: "2024-01-15T17:00:00Z", EventType: eventTypeOutOfOffice},
+				{ID: "deploy", Start: "2024-01-15T12:00:00Z", End: "2024-01-15T12:00:00Z", Marker: true},
+				at("meeting", "10:00", "11:00"),
+			},
+			want: "" +
+				"|  ##            |\n" +
+				"09:00        17:00\n",
+		},
+		{
+			name: "empty day",
+			want: "" +
+				"|                |\n" +
+				"09:00        17:00\n",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt // Capture loop variable
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			if err := RenderTimeline(tt.events, dayStart, dayEnd, 16, &buf); err != nil {
+				t.Fatalf("RenderTimeline() error = %v", err)
+			}
+			if diff := cmp.Diff(buf.String(), tt.want); diff != "" {
+				t.Errorf("RenderTimeline() mismatch (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRenderTimeline_InvalidInput(t *testing.T) {
+	t.Parallel()
+	dayStart := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+
+	var buf bytes.Buffer
+	if err := RenderTimeline(nil, dayStart, dayStart.Add(8*time.Hour), 0, &buf); err == nil {
+		t.Error("RenderTimeline() with zero width error = nil, want error")
+	}
+	if err := RenderTimeline(nil, dayStart, dayStart, 16, &buf); err == nil {
+		t.Error("RenderTimeline() with empty day error = nil, want error")
+	}
+	if buf.Len() != 0 {
+		t.Errorf("RenderTimeline() wrote %q on error, want nothing", buf.String())
+	}
+}
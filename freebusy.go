@@ -0,0 +1,138 @@
+// Package gcal provides availability queries for the user and across attendees.
+package gcal
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// nextFreeSlotWindow is how far ahead NextFreeSlot looks for a gap
+const nextFreeSlotWindow = 24 * time.Hour
+
+// NextFreeSlot finds the next free slot using the default client. See
+// Client.NextFreeSlot.
+func NextFreeSlot(ctx context.Context, calendarIDs []string, minDuration time.Duration) (*FreeSlot, error) {
+	c, code, err := newDefaultClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", code, err)
+	}
+	return c.NextFreeSlot(ctx, calendarIDs, minDuration)
+}
+
+// NextFreeSlot returns the first gap of at least minDuration between now
+// and 24 hours from now, given the user's accepted meetings. Events that
+// can't conflict, such as all-day events under AllDayNeverConflicts and
+// out-of-office blocks, don't take up time. The slot starts now if the user
+// is currently free. Returns nil if there is no such gap in the window.
+func (c *Client) NextFreeSlot(ctx context.Context, calendarIDs []string, minDuration time.Duration) (*FreeSlot, error) {
+	return c.nextFreeSlot(ctx, calendarIDs, minDuration, time.Now())
+}
+
+// nextFreeSlot is NextFreeSlot with an injectable clock
+func (c *Client) nextFreeSlot(ctx context.Context, calendarIDs []string, minDuration time.Duration, now time.Time) (*FreeSlot, error) {
+	end := now.Add(nextFreeSlotWindow)
+	resp := c.fetchEvents(ctx, calendarIDs, now, end)
+	if !resp.Success {
+		return nil, fmt.Errorf("%s: %s", resp.Error, resp.Message)
+	}
+
+	blocking := blockingEvents(resp.Events, c.Options)
+	slots := freeSlots(busyIntervals(blocking, c.Options.location()), now, end, minDuration)
+	if len(slots) == 0 {
+		return nil, nil
+	}
+	return &slots[0], nil
+}
+
+// CommonFreeSlots finds common free slots using the default client. See
+// Client.CommonFreeSlots.
+func CommonFreeSlots(ctx context.Context, emails []string, start, end time.Time, dur time.Duration, loc *time.Location) ([]FreeSlot, error) {
+	c, code, err := newDefaultClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", code, err)
+	}
+	return c.CommonFreeSlots(ctx, emails, start, end, dur, loc)
+}
+
+// CommonFreeSlots returns every window between start and end, at least dur
+// long and within default working hours in loc, when none of the attendees
+// are busy according to FreeBusy. Fails if any attendee's availability
+// can't be read, since a slot could then clash with their calendar.
+func (c *Client) CommonFreeSlots(ctx context.Context, emails []string, start, end time.Time, dur time.Duration, loc *time.Location) ([]FreeSlot, error) {
+	if loc == nil {
+		loc = time.Local
+	}
+
+	busy, err := c.queryFreeBusy(ctx, emails, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	wh := DefaultWorkingHours()
+	var slots []FreeSlot
+	day := start.In(loc)
+	day = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, loc)
+	for ; day.Before(end); day = day.AddDate(0, 0, 1) {
+		dayStart, dayEnd, ok := wh.window(day, loc)
+		if !ok {
+			continue
+		}
+		if dayStart.Before(start) {
+			dayStart = start
+		}
+		if dayEnd.After(end) {
+			dayEnd = end
+		}
+		if !dayEnd.After(dayStart) {
+			continue
+		}
+		slots = append(slots, freeSlots(busy, dayStart, dayEnd, dur)...)
+	}
+	return slots, nil
+}
+
+// queryFreeBusy returns the busy intervals of all emails between start and
+// end, combined into one list
+func (c *Client) queryFreeBusy(ctx context.Context, emails []string, start, end time.Time) ([]BusyInterval, error) {
+	req := &calendar.FreeBusyRequest{
+		TimeMin: start.Format(time.RFC3339),
+		TimeMax: end.Format(time.RFC3339),
+	}
+	for _, email := range emails {
+		req.Items = append(req.Items, &calendar.FreeBusyRequestItem{Id: email})
+	}
+
+	var resp *calendar.FreeBusyResponse
+	budget := c.Retry.newBudget(time.Now())
+	err := budget.do(ctx, func() error {
+		var err error
+		resp, err = c.srv.Freebusy.Query(req).Context(ctx).Do()
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to query free/busy: %w", ErrAPIError, err)
+	}
+
+	var busy []BusyInterval
+	for _, email := range emails {
+		cal, ok := resp.Calendars[email]
+		if !ok {
+			return nil, fmt.Errorf("%s: no free/busy information for %s", ErrAPIError, email)
+		}
+		if len(cal.Errors) > 0 {
+			return nil, fmt.Errorf("%s: free/busy unavailable for %s: %s", ErrAPIError, email, cal.Errors[0].Reason)
+		}
+		for _, period := range cal.Busy {
+			s, errStart := time.Parse(time.RFC3339, period.Start)
+			e, errEnd := time.Parse(time.RFC3339, period.End)
+			if errStart != nil || errEnd != nil {
+				continue
+			}
+			busy = append(busy, BusyInterval{Start: s, End: e})
+		}
+	}
+	return busy, nil
+}
@@ -0,0 +1,226 @@
+package gcal
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/api/calendar/v3"
+)
+
+// newTestFreeBusyClient serves FreeBusy queries from busy, keyed by email
+// with "start/end" RFC3339 pairs
+func newTestFreeBusyClient(t *testing.T, busy map[string][][2]string) *Client {
+	t.Helper()
+
+	return newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/freeBusy" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		var req calendar.FreeBusyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+
+		resp := calendar.FreeBusyResponse{Calendars: map[string]calendar.FreeBusyCalendar{}}
+		for _, item := range req.Items {
+			periods, ok := busy[item.Id]
+			if !ok {
+				resp.Calendars[item.Id] = calendar.FreeBusyCalendar{
+					Errors: []*calendar.Error{{Domain: "global", Reason: "notFound"}},
+				}
+				continue
+			}
+			var cal calendar.FreeBusyCalendar
+			for _, p := range periods {
+				cal.Busy = append(cal.Busy, &calendar.TimePeriod{Start: p[0], End: p[1]})
+			}
+			resp.Calendars[item.Id] = cal
+		}
+		writeTestJSON(t, w, &resp)
+	}))
+}
+
+func TestCommonFreeSlots(t *testing.T) {
+	t.Parallel()
+
+	client := newTestFreeBusyClient(t, map[string][][2]string{
+		"alice@example.com": {
+			{"2024-01-15T09:00:00Z", "2024-01-15T10:00:00Z"},
+			{"2024-01-15T13:00:00Z", "2024-01-15T14:00:00Z"},
+		},
+		"bob@example.com": {
+			{"2024-01-15T09:30:00Z", "2024-01-15T11:00:00Z"},
+		},
+		"carol@example.com": {
+			{"2024-01-15T10:30:00Z", "2024-01-15T12:00:00Z"},
+			{"2024-01-15T15:45:00Z", "2024-01-15T16:00:00Z"},
+		},
+	})
+	emails := []string{"alice@example.com", "bob@example.com", "carol@example.com"}
+
+	at := func(day, hour, min int) time.Time {
+		return time.Date(2024, 1, day, hour, min, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name       string
+		start, end time.Time
+		dur        time.Duration
+		want       []FreeSlot
+	}{
+		{
+			name:  "all common windows in a day",
+			start: at(15, 0, 0),
+			end:   at(16, 0, 0),
+			dur:   30 * time.Minute,
+			want: []FreeSlot{
+				{Start: at(15, 12, 0), End: at(15, 13, 0)},
+				{Start: at(15, 14, 0), End: at(15, 15, 45)},
+				{Start: at(15, 16, 0), End: at(15, 17, 0)},
+			},
+		},
+		{
+			name:  "windows shorter than dur are skipped",
+			start: at(15, 0, 0),
+			end:   at(16, 0, 0),
+			dur:   90 * time.Minute,
+			want: []FreeSlot{
+				{Start: at(15, 14, 0), End: at(15, 15, 45)},
+			},
+		},
+		{
+			name:  "weekends skipped and range clips working hours",
+			start: at(13, 0, 0),
+			end:   at(15, 12, 30),
+			dur:   15 * time.Minute,
+			want: []FreeSlot{
+				{Start: at(15, 12, 0), End: at(15, 12, 30)},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt // Capture loop variable
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := client.CommonFreeSlots(context.Background(), emails, tt.start, tt.end, tt.dur, time.UTC)
+			if err != nil {
+				t.Fatalf("CommonFreeSlots() error = %v", err)
+			}
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("CommonFreeSlots() mismatch (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCommonFreeSlots_UnknownAttendee(t *testing.T) {
+	t.Parallel()
+
+	client := newTestFreeBusyClient(t, map[string][][2]string{
+		"alice@example.com": nil,
+	})
+
+	start := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	_, err := client.CommonFreeSlots(context.Background(), []string{"alice@example.com", "ghost@example.com"}, start, start.AddDate(0, 0, 1), time.Hour, time.UTC)
+	if err == nil {
+		t.Error("CommonFreeSlots() error = nil, want error for unreadable attendee")
+	}
+}
+
+func TestNextFreeSlot(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2024, 1, 15, 13, 0, 0, 0, time.UTC)
+	at := func(hour, min int) time.Time {
+		return time.Date(2024, 1, 15, hour, min, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name   string
+		events []*calendar.Event
+		min    time.Duration
+		want   *FreeSlot
+	}{
+		{
+			name: "mid-afternoon gap",
+			events: []*calendar.Event{
+				testAcceptedEvent("design", at(12, 30), at(14, 30)),
+				testAcceptedEvent("1:1", at(14, 45), at(16, 0)),
+				testAcceptedEvent("sync", at(17, 0), at(18, 0)),
+			},
+			min:  30 * time.Minute,
+			want: &FreeSlot{Start: at(16, 0), End: at(17, 0)},
+		},
+		{
+			name: "free right now",
+			events: []*calendar.Event{
+				testAcceptedEvent("later", at(15, 0), at(16, 0)),
+			},
+			min:  time.Hour,
+			want: &FreeSlot{Start: now, End: at(15, 0)},
+		},
+		{
+			name: "fully booked through the window",
+			events: []*calendar.Event{
+				testAcceptedEvent("offsite", at(9, 0), now.Add(nextFreeSlotWindow+time.Hour)),
+			},
+			min:  15 * time.Minute,
+			want: nil,
+		},
+		{
+			name: "all-day event doesn't take up time",
+			events: []*calendar.Event{
+				{
+					Id:      "birthday",
+					Summary: "Birthday",
+					Start:   &calendar.EventDateTime{Date: "2024-01-15"},
+					End:     &calendar.EventDateTime{Date: "2024-01-16"},
+				},
+				testAcceptedEvent("later", at(15, 0), at(16, 0)),
+			},
+			min:  time.Hour,
+			want: &FreeSlot{Start: now, End: at(15, 0)},
+		},
+		{
+			name: "out-of-office block doesn't take up time",
+			events: []*calendar.Event{
+				{
+					Id:        "ooo",
+					Summary:   "Out of office",
+					EventType: eventTypeOutOfOffice,
+					Start:     &calendar.EventDateTime{DateTime: at(9, 0).Format(time.RFC3339)},
+					End:       &calendar.EventDateTime{DateTime: now.Add(nextFreeSlotWindow).Format(time.RFC3339)},
+				},
+				testAcceptedEvent("later", at(15, 0), at(16, 0)),
+			},
+			min:  time.Hour,
+			want: &FreeSlot{Start: now, End: at(15, 0)},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt // Capture loop variable
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				writeTestJSON(t, w, &calendar.Events{Items: tt.events})
+			}))
+			client.Options.IncludeAllDay = true
+			client.Options.IncludeOutOfOffice = true
+
+			got, err := client.nextFreeSlot(context.Background(), nil, tt.min, now)
+			if err != nil {
+				t.Fatalf("nextFreeSlot() error = %v", err)
+			}
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("nextFreeSlot() mismatch (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
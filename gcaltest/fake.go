@@ -0,0 +1,233 @@
+// Package gcaltest provides an in-memory fake of the Google Calendar API for
+// testing code built on gcal without network access or hand-written mocks.
+//
+// A FakeService serves canned events and calendars over a local HTTP server
+// and hands out a real *calendar.Service pointed at it, so it works with
+// gcal.NewClientWithService:
+//
+//	fake := gcaltest.NewFakeService()
+//	defer fake.Close()
+//	fake.AddEvents("primary", event)
+//	srv, _ := fake.Service(ctx)
+//	client := gcal.NewClientWithService(srv)
+package gcaltest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/option"
+)
+
+// calendarListKey is the FailWith key for the calendar list endpoint
+const calendarListKey = "calendarList"
+
+// FakeService is an in-memory Calendar API supporting event and calendar
+// listing, with optional page splitting and error injection. It is safe for
+// concurrent use.
+type FakeService struct {
+	server *httptest.Server
+
+	mu        sync.Mutex
+	events    map[string][]*calendar.Event // by calendar ID
+	calendars []*calendar.CalendarListEntry
+	pageSize  int
+	failures  map[string]int // HTTP status by calendar ID or calendarListKey
+	requests  int
+}
+
+// NewFakeService starts a FakeService. Call Close when done.
+func NewFakeService() *FakeService {
+	f := &FakeService{
+		events:   make(map[string][]*calendar.Event),
+		failures: make(map[string]int),
+	}
+	f.server = httptest.NewServer(http.HandlerFunc(f.serveHTTP))
+	return f
+}
+
+// Close shuts down the fake's server
+func (f *FakeService) Close() {
+	f.server.Close()
+}
+
+// Service returns a Calendar service that talks to the fake
+func (f *FakeService) Service(ctx context.Context) (*calendar.Service, error) {
+	return calendar.NewService(ctx,
+		option.WithHTTPClient(f.server.Client()),
+		option.WithEndpoint(f.server.URL+"/"),
+	)
+}
+
+// AddEvents adds events to a calendar. Events are returned in the order
+// added.
+func (f *FakeService) AddEvents(calendarID string, events ...*calendar.Event) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.events[calendarID] = append(f.events[calendarID], events...)
+}
+
+// AddCalendars adds entries to the calendar list
+func (f *FakeService) AddCalendars(entries ...*calendar.CalendarListEntry) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calendars = append(f.calendars, entries...)
+}
+
+// SetPageSize splits list results into pages of at most n items, linked by
+// page tokens. Zero or less returns everything in one page.
+func (f *FakeService) SetPageSize(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.pageSize = n
+}
+
+// FailCalendar makes requests for calendarID's events fail with the given
+// HTTP status until ClearFailures is called
+func (f *FakeService) FailCalendar(calendarID string, status int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures[calendarID] = status
+}
+
+// FailCalendarList makes calendar list requests fail with the given HTTP
+// status until ClearFailures is called
+func (f *FakeService) FailCalendarList(status int) {
+	f.FailCalendar(calendarListKey, status)
+}
+
+// ClearFailures removes all injected errors
+func (f *FakeService) ClearFailures() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures = make(map[string]int)
+}
+
+// Requests returns the number of requests the fake has served
+func (f *FakeService) Requests() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.requests
+}
+
+// serveHTTP routes a request to the matching endpoint
+func (f *FakeService) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests++
+
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not supported by FakeService")
+		return
+	}
+
+	path := r.URL.Path
+	switch {
+	case path == "/users/me/calendarList":
+		f.listCalendars(w, r)
+	case strings.HasPrefix(path, "/calendars/") && strings.HasSuffix(path, "/events"):
+		f.listEvents(w, r, strings.TrimSuffix(strings.TrimPrefix(path, "/calendars/"), "/events"))
+	default:
+		writeError(w, http.StatusNotFound, "Not Found")
+	}
+}
+
+// listCalendars serves the calendar list endpoint
+func (f *FakeService) listCalendars(w http.ResponseWriter, r *http.Request) {
+	if status, ok := f.failures[calendarListKey]; ok {
+		writeError(w, status, http.StatusText(status))
+		return
+	}
+
+	start, end, next := f.page(r, len(f.calendars))
+	writeJSON(w, &calendar.CalendarList{
+		Items:         f.calendars[start:end],
+		NextPageToken: next,
+	})
+}
+
+// listEvents serves a calendar's events, filtered to the requested window
+func (f *FakeService) listEvents(w http.ResponseWriter, r *http.Request, calendarID string) {
+	if status, ok := f.failures[calendarID]; ok {
+		writeError(w, status, http.StatusText(status))
+		return
+	}
+	events, ok := f.events[calendarID]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+
+	var matching []*calendar.Event
+	for _, e := range events {
+		if inWindow(e, r.URL.Query().Get("timeMin"), r.URL.Query().Get("timeMax")) {
+			matching = append(matching, e)
+		}
+	}
+
+	start, end, next := f.page(r, len(matching))
+	writeJSON(w, &calendar.Events{
+		Items:         matching[start:end],
+		NextPageToken: next,
+	})
+}
+
+// page returns the slice bounds of the requested page of n items and the
+// token of the following page, if any
+func (f *FakeService) page(r *http.Request, n int) (start, end int, next string) {
+	start, _ = strconv.Atoi(r.URL.Query().Get("pageToken"))
+	if start < 0 || start > n {
+		start = n
+	}
+	end = n
+	if f.pageSize > 0 && start+f.pageSize < n {
+		end = start + f.pageSize
+		next = strconv.Itoa(end)
+	}
+	return start, end, next
+}
+
+// inWindow reports whether a timed event overlaps [timeMin, timeMax).
+// Events or bounds that can't be parsed are kept.
+func inWindow(e *calendar.Event, timeMin, timeMax string) bool {
+	if e.Start == nil || e.End == nil {
+		return true
+	}
+	start, errStart := time.Parse(time.RFC3339, e.Start.DateTime)
+	end, errEnd := time.Parse(time.RFC3339, e.End.DateTime)
+	if errStart != nil || errEnd != nil {
+		return true
+	}
+	if min, err := time.Parse(time.RFC3339, timeMin); err == nil && !end.After(min) {
+		return false
+	}
+	if max, err := time.Parse(time.RFC3339, timeMax); err == nil && !start.Before(max) {
+		return false
+	}
+	return true
+}
+
+// writeJSON writes v as a JSON response body
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes a Google API style error response
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":    status,
+			"message": message,
+		},
+	})
+}
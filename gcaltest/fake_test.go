@@ -0,0 +1,222 @@
+package gcaltest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/googleapi"
+
+	"github.com/jima/gcal"
+)
+
+// testEvent builds an accepted, timed API event with one other attendee
+func testEvent(id string, start time.Time) *calendar.Event {
+	return &calendar.Event{
+		Id:      id,
+		Summary: id,
+		Start:   &calendar.EventDateTime{DateTime: start.Format(time.RFC3339)},
+		End:     &calendar.EventDateTime{DateTime: start.Add(30 * time.Minute).Format(time.RFC3339)},
+		Attendees: []*calendar.EventAttendee{
+			{Self: true, ResponseStatus: "accepted"},
+			{Email: "alice@example.com", DisplayName: "Alice"},
+		},
+	}
+}
+
+// newTestFake starts a FakeService and returns it with a service pointed at it
+func newTestFake(t *testing.T) (*FakeService, *calendar.Service) {
+	t.Helper()
+
+	fake := NewFakeService()
+	t.Cleanup(fake.Close)
+
+	srv, err := fake.Service(context.Background())
+	if err != nil {
+		t.Fatalf("Service() error = %v", err)
+	}
+	return fake, srv
+}
+
+func TestFakeService_EventPagination(t *testing.T) {
+	t.Parallel()
+	fake, srv := newTestFake(t)
+
+	start := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+	for _, id := range []string{"a", "b", "c", "d", "e"} {
+		fake.AddEvents("primary", testEvent(id, start))
+		start = start.Add(time.Hour)
+	}
+	fake.SetPageSize(2)
+
+	var gotIDs []string
+	pages := 0
+	err := srv.Events.List("primary").Pages(context.Background(), func(events *calendar.Events) error {
+		pages++
+		for _, e := range events.Items {
+			gotIDs = append(gotIDs, e.Id)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Pages() error = %v", err)
+	}
+
+	if pages != 3 {
+		t.Errorf("pages = %d, want 3", pages)
+	}
+	if diff := cmp.Diff(gotIDs, []string{"a", "b", "c", "d", "e"}); diff != "" {
+		t.Errorf("event IDs mismatch (-got +want):\n%s", diff)
+	}
+	if got := fake.Requests(); got != 3 {
+		t.Errorf("Requests() = %d, want 3", got)
+	}
+}
+
+func TestFakeService_CalendarListPagination(t *testing.T) {
+	t.Parallel()
+	fake, srv := newTestFake(t)
+
+	fake.AddCalendars(
+		&calendar.CalendarListEntry{Id: "primary", Summary: "Me", Primary: true},
+		&calendar.CalendarListEntry{Id: "team@example.com", Summary: "Team"},
+		&calendar.CalendarListEntry{Id: "holidays@example.com", Summary: "Holidays"},
+	)
+	fake.SetPageSize(2)
+
+	first, err := srv.CalendarList.List().Do()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(first.Items) != 2 || first.NextPageToken == "" {
+		t.Fatalf("first page = %d items, token %q; want 2 items and a token", len(first.Items), first.NextPageToken)
+	}
+
+	second, err := srv.CalendarList.List().PageToken(first.NextPageToken).Do()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(second.Items) != 1 || second.Items[0].Id != "holidays@example.com" {
+		t.Errorf("second page = %v, want holidays@example.com only", second.Items)
+	}
+	if second.NextPageToken != "" {
+		t.Errorf("second page token = %q, want none", second.NextPageToken)
+	}
+}
+
+func TestFakeService_TimeWindow(t *testing.T) {
+	t.Parallel()
+	fake, srv := newTestFake(t)
+
+	start := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+	fake.AddEvents("primary",
+		testEvent("before", start.Add(-2*time.Hour)),
+		testEvent("inside", start.Add(time.Hour)),
+		testEvent("after", start.Add(5*time.Hour)),
+	)
+
+	events, err := srv.Events.List("primary").
+		TimeMin(start.Format(time.RFC3339)).
+		TimeMax(start.Add(4 * time.Hour).Format(time.RFC3339)).
+		Do()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(events.Items) != 1 || events.Items[0].Id != "inside" {
+		t.Errorf("List() = %v, want only \"inside\"", events.Items)
+	}
+}
+
+func TestFakeService_ErrorInjection(t *testing.T) {
+	t.Parallel()
+	fake, srv := newTestFake(t)
+
+	fake.AddEvents("primary", testEvent("a", time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)))
+	fake.FailCalendar("primary", http.StatusServiceUnavailable)
+	fake.FailCalendarList(http.StatusForbidden)
+
+	tests := []struct {
+		name     string
+		call     func() error
+		wantCode int
+	}{
+		{
+			name:     "events",
+			call:     func() error { _, err := srv.Events.List("primary").Do(); return err },
+			wantCode: http.StatusServiceUnavailable,
+		},
+		{
+			name:     "calendar list",
+			call:     func() error { _, err := srv.CalendarList.List().Do(); return err },
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "unknown calendar",
+			call:     func() error { _, err := srv.Events.List("missing").Do(); return err },
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		var apiErr *googleapi.Error
+		if err := tt.call(); !errors.As(err, &apiErr) || apiErr.Code != tt.wantCode {
+			t.Errorf("%s: error = %v, want API error %d", tt.name, err, tt.wantCode)
+		}
+	}
+
+	fake.ClearFailures()
+	if _, err := srv.Events.List("primary").Do(); err != nil {
+		t.Errorf("List() after ClearFailures() error = %v", err)
+	}
+}
+
+func TestFakeService_WithClient(t *testing.T) {
+	t.Parallel()
+	fake, srv := newTestFake(t)
+
+	start := time.Now().Add(time.Hour).Truncate(time.Minute)
+	fake.AddEvents("primary", testEvent("standup", start))
+	fake.AddEvents("team@example.com", testEvent("review", start.Add(2*time.Hour)))
+	fake.FailCalendar("team@example.com", http.StatusInternalServerError)
+
+	client := gcal.NewClientWithService(srv)
+	resp := client.FetchUpcomingEvents(context.Background(), []string{"primary"}, 24)
+	if !resp.Success {
+		t.Fatalf("FetchUpcomingEvents() failed: %v", resp.Error)
+	}
+	if len(resp.Events) != 1 || resp.Events[0].Title != "standup" {
+		t.Errorf("FetchUpcomingEvents() events = %v, want standup", resp.Events)
+	}
+
+	resp = client.FetchUpcomingEvents(context.Background(), []string{"team@example.com"}, 24)
+	if resp.Success {
+		t.Error("FetchUpcomingEvents() succeeded, want injected error")
+	}
+}
+
+func TestFakeService_ClientReadsAllPages(t *testing.T) {
+	t.Parallel()
+	fake, srv := newTestFake(t)
+
+	start := time.Now().Add(time.Hour).Truncate(time.Minute)
+	for i := 0; i < 7; i++ {
+		fake.AddEvents("primary", testEvent(fmt.Sprintf("event-%d", i), start.Add(time.Duration(i)*time.Hour)))
+	}
+	fake.SetPageSize(3)
+
+	resp := gcal.NewClientWithService(srv).FetchUpcomingEvents(context.Background(), nil, 24)
+	if !resp.Success {
+		t.Fatalf("FetchUpcomingEvents() failed: %v", resp.Message)
+	}
+	if len(resp.Events) != 7 {
+		t.Errorf("FetchUpcomingEvents() returned %d events, want 7", len(resp.Events))
+	}
+	if got := fake.Requests(); got != 3 {
+		t.Errorf("Requests() = %d, want 3 pages", got)
+	}
+}
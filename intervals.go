@@ -0,0 +1,350 @@
+// Package gcal provides time interval primitives for availability and load analysis.
+package gcal
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// BusyInterval is a span of time occupied by one or more events
+type BusyInterval struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// allDayLayout is the bare date format Google uses for all-day events
+const allDayLayout = "2006-01-02"
+
+// parseEventTime parses an RFC3339 timestamp or a bare all-day date. Bare
+// dates carry no timezone, so they are anchored to midnight in loc rather
+// than UTC; an all-day event on Jan 15 starts at local midnight Jan 15.
+func parseEventTime(value string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if loc == nil {
+		loc = time.Local
+	}
+	return time.ParseInLocation(allDayLayout, value, loc)
+}
+
+// eventInterval parses an event's start and end times, anchoring all-day
+// dates in loc. It reports false if either time is unparseable or the event
+// does not end after it starts.
+func eventInterval(e Event, loc *time.Location) (BusyInterval, bool) {
+	start, err := parseEventTime(e.Start, loc)
+	if err != nil {
+		return BusyInterval{}, false
+	}
+	end, err := parseEventTime(e.End, loc)
+	if err != nil || !end.After(start) {
+		return BusyInterval{}, false
+	}
+	return BusyInterval{Start: start, End: end}, true
+}
+
+// busyIntervals returns the intervals of all events with parseable times
+func busyIntervals(events []Event, loc *time.Location) []BusyInterval {
+	var intervals []BusyInterval
+	for _, e := range events {
+		if iv, ok := eventInterval(e, loc); ok {
+			intervals = append(intervals, iv)
+		}
+	}
+	return intervals
+}
+
+// blockingEvents returns the events that take up time under opts; see
+// blocksTime
+func blockingEvents(events []Event, opts FetchOptions) []Event {
+	blocking := make([]Event, 0, len(events))
+	for _, e := range events {
+		if blocksTime(e, opts) {
+			blocking = append(blocking, e)
+		}
+	}
+	return blocking
+}
+
+// peakConcurrency returns the largest number of intervals in progress at the
+// same instant. Intervals that merely touch are not counted as concurrent.
+func peakConcurrency(intervals []BusyInterval) int {
+	type boundary struct {
+		at    time.Time
+		delta int
+	}
+
+	boundaries := make([]boundary, 0, 2*len(intervals))
+	for _, iv := range intervals {
+		boundaries = append(boundaries, boundary{iv.Start, 1}, boundary{iv.End, -1})
+	}
+
+	// Process ends before starts at the same instant so back-to-back
+	// intervals don't overlap
+	sort.Slice(boundaries, func(i, j int) bool {
+		if boundaries[i].at.Equal(boundaries[j].at) {
+			return boundaries[i].delta < boundaries[j].delta
+		}
+		return boundaries[i].at.Before(boundaries[j].at)
+	})
+
+	active, peak := 0, 0
+	for _, b := range boundaries {
+		active += b.delta
+		if active > peak {
+			peak = active
+		}
+	}
+	return peak
+}
+
+// clipInterval returns the part of iv that falls within [start, end),
+// reporting false if they don't overlap
+func clipInterval(iv BusyInterval, start, end time.Time) (BusyInterval, bool) {
+	if iv.Start.Before(start) {
+		iv.Start = start
+	}
+	if iv.End.After(end) {
+		iv.End = end
+	}
+	return iv, iv.End.After(iv.Start)
+}
+
+// BusiestHour returns the clock hour (0-23, in loc) with the most meetings in
+// progress at once, along with that peak count. Ties go to the earlier hour.
+// Returns (-1, 0) if there are no events with parseable times.
+func BusiestHour(events []Event, loc *time.Location) (hour int, count int) {
+	if loc == nil {
+		loc = time.Local
+	}
+
+	intervals := busyIntervals(events, loc)
+	if len(intervals) == 0 {
+		return -1, 0
+	}
+
+	first, last := intervals[0].Start, intervals[0].End
+	for _, iv := range intervals[1:] {
+		if iv.Start.Before(first) {
+			first = iv.Start
+		}
+		if iv.End.After(last) {
+			last = iv.End
+		}
+	}
+
+	var peaks [24]int
+	first = first.In(loc)
+	windowStart := time.Date(first.Year(), first.Month(), first.Day(), first.Hour(), 0, 0, 0, loc)
+	for ; windowStart.Before(last); windowStart = windowStart.Add(time.Hour) {
+		windowEnd := windowStart.Add(time.Hour)
+
+		var inWindow []BusyInterval
+		for _, iv := range intervals {
+			if clipped, ok := clipInterval(iv, windowStart, windowEnd); ok {
+				inWindow = append(inWindow, clipped)
+			}
+		}
+
+		h := windowStart.In(loc).Hour()
+		if peak := peakConcurrency(inWindow); peak > peaks[h] {
+			peaks[h] = peak
+		}
+	}
+
+	hour, count = -1, 0
+	for h, peak := range peaks {
+		if peak > count {
+			hour, count = h, peak
+		}
+	}
+	return hour, count
+}
+
+// mergeIntervals returns the union of intervals as non-overlapping
+// intervals sorted by start. Touching intervals are merged.
+func mergeIntervals(intervals []BusyInterval) []BusyInterval {
+	if len(intervals) == 0 {
+		return nil
+	}
+
+	sorted := make([]BusyInterval, len(intervals))
+	copy(sorted, intervals)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Start.Before(sorted[j].Start)
+	})
+
+	merged := []BusyInterval{sorted[0]}
+	for _, iv := range sorted[1:] {
+		last := &merged[len(merged)-1]
+		if iv.Start.After(last.End) {
+			merged = append(merged, iv)
+			continue
+		}
+		if iv.End.After(last.End) {
+			last.End = iv.End
+		}
+	}
+	return merged
+}
+
+// busyWithin returns the total time covered by at least one event within
+// [start, end), counting overlapping events once
+func busyWithin(events []Event, start, end time.Time, loc *time.Location) time.Duration {
+	var busy time.Duration
+	for _, iv := range mergeIntervals(busyIntervals(events, loc)) {
+		if clipped, ok := clipInterval(iv, start, end); ok {
+			busy += clipped.End.Sub(clipped.Start)
+		}
+	}
+	return busy
+}
+
+// ScheduleDensity returns the fraction of [dayStart, dayEnd) covered by
+// events, from 0 for an empty day to 1 for a fully booked one. Overlapping
+// events are counted once. All-day events, out-of-office blocks and markers
+// don't take up time, as under DefaultFetchOptions. Returns 0 if dayEnd is
+// not after dayStart.
+func ScheduleDensity(events []Event, dayStart, dayEnd time.Time) float64 {
+	total := dayEnd.Sub(dayStart)
+	if total <= 0 {
+		return 0
+	}
+
+	busy := busyWithin(blockingEvents(events, DefaultFetchOptions()), dayStart, dayEnd, dayStart.Location())
+	density := float64(busy) / float64(total)
+	if density > 1 {
+		density = 1
+	}
+	return density
+}
+
+// FreeSlot is an open span of time with no events
+type FreeSlot struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// Duration returns the length of the slot
+func (s FreeSlot) Duration() time.Duration {
+	return s.End.Sub(s.Start)
+}
+
+// FindFreeSlots returns the gaps between events within [dayStart, dayEnd)
+// that are at least minDuration long. Events with unparseable times are
+// ignored, and all-day events, out-of-office blocks and markers don't take
+// up time, as under DefaultFetchOptions.
+func FindFreeSlots(events []Event, dayStart, dayEnd time.Time, minDuration time.Duration) []FreeSlot {
+	blocking := blockingEvents(events, DefaultFetchOptions())
+	return freeSlots(busyIntervals(blocking, dayStart.Location()), dayStart, dayEnd, minDuration)
+}
+
+// freeSlots returns the gaps between busy intervals within [start, end)
+// that are at least minDuration long
+func freeSlots(busy []BusyInterval, start, end time.Time, minDuration time.Duration) []FreeSlot {
+	var clipped []BusyInterval
+	for _, iv := range busy {
+		if c, ok := clipInterval(iv, start, end); ok {
+			clipped = append(clipped, c)
+		}
+	}
+
+	var slots []FreeSlot
+	cursor := start
+	for _, iv := range mergeIntervals(clipped) {
+		if iv.Start.Sub(cursor) >= minDuration && iv.Start.After(cursor) {
+			slots = append(slots, FreeSlot{Start: cursor, End: iv.Start})
+		}
+		cursor = iv.End
+	}
+	if end.Sub(cursor) >= minDuration && end.After(cursor) {
+		slots = append(slots, FreeSlot{Start: cursor, End: end})
+	}
+
+	return slots
+}
+
+// SuggestReschedule returns free slots within [dayStart, dayEnd) that are
+// long enough to hold target without overlapping any of the other events.
+// The target itself is ignored if it appears in others. Returns nil if the
+// target's times can't be parsed.
+func SuggestReschedule(target Event, others []Event, dayStart, dayEnd time.Time) []FreeSlot {
+	iv, ok := eventInterval(target, dayStart.Location())
+	if !ok {
+		return nil
+	}
+
+	blocking := make([]Event, 0, len(others))
+	for _, e := range others {
+		if target.ID != "" && e.ID == target.ID {
+			continue
+		}
+		blocking = append(blocking, e)
+	}
+
+	return FindFreeSlots(blocking, dayStart, dayEnd, iv.End.Sub(iv.Start))
+}
+
+// WorkingHours is a weekly working schedule in clock time
+type WorkingHours struct {
+	Start time.Duration  // time of day work starts, e.g. 9 * time.Hour
+	End   time.Duration  // time of day work ends
+	Days  []time.Weekday // working days of the week
+}
+
+// DefaultWorkingHours returns 9:00 to 17:00, Monday to Friday
+func DefaultWorkingHours() WorkingHours {
+	return WorkingHours{
+		Start: 9 * time.Hour,
+		End:   17 * time.Hour,
+		Days:  []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+	}
+}
+
+// isWorkday reports whether day is one of the working days
+func (wh WorkingHours) isWorkday(day time.Weekday) bool {
+	for _, d := range wh.Days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// window returns the working hours on date's calendar day in loc, reporting
+// false on non-working days. Clock times are resolved with time.Date so DST
+// transitions don't shift them.
+func (wh WorkingHours) window(date time.Time, loc *time.Location) (time.Time, time.Time, bool) {
+	date = date.In(loc)
+	if !wh.isWorkday(date.Weekday()) {
+		return time.Time{}, time.Time{}, false
+	}
+	return clockTime(date, wh.Start, loc), clockTime(date, wh.End, loc), true
+}
+
+// ValidateBusinessHours returns a descriptive error if e doesn't fall
+// entirely within wh on a single working day in loc, or if its times can't
+// be parsed. A nil loc uses the local timezone.
+func ValidateBusinessHours(e Event, wh WorkingHours, loc *time.Location) error {
+	if loc == nil {
+		loc = time.Local
+	}
+
+	iv, ok := eventInterval(e, loc)
+	if !ok {
+		return fmt.Errorf("event %q has invalid start or end time", e.Title)
+	}
+	start, end := iv.Start.In(loc), iv.End.In(loc)
+
+	workStart, workEnd, ok := wh.window(start, loc)
+	if !ok {
+		return fmt.Errorf("event %q is on %s, which is not a working day", e.Title, start.Weekday())
+	}
+	if start.Before(workStart) || end.After(workEnd) {
+		return fmt.Errorf("event %q runs %s-%s, outside working hours %s-%s",
+			e.Title, start.Format("Mon 15:04"), end.Format("Mon 15:04"),
+			workStart.Format("15:04"), workEnd.Format("15:04"))
+	}
+	return nil
+}
@@ -0,0 +1,556 @@
+package gcal
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+	_ "time/tzdata" // Ensure IANA zones load in minimal test environments
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/api/calendar/v3"
+)
+
+func TestBusiestHour(t *testing.T) {
+	t.Parallel()
+	day := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+
+	at := func(id string, startHour, startMin, durMin int) Event {
+		start := day.Add(time.Duration(startHour)*time.Hour + time.Duration(startMin)*time.Minute)
+		return Event{
+			ID:    id,
+			Start: start.Format(time.RFC3339),
+			End:   start.Add(time.Duration(durMin) * time.Minute).Format(time.RFC3339),
+		}
+	}
+
+	tests := []struct {
+		name      string
+		events    []Event
+		loc       *time.Location
+		wantHour  int
+		wantCount int
+	}{
+		{
+			name: "clustered around 10am",
+			events: []Event{
+				at("standup", 9, 30, 15),
+				at("design", 10, 0, 60),
+				at("1:1", 10, 15, 30),
+				at("interview", 10, 30, 30),
+				at("lunch", 12, 0, 60),
+			},
+			wantHour:  10,
+			wantCount: 3,
+		},
+		{
+			name: "back-to-back meetings don't stack",
+			events: []Event{
+				at("a", 14, 0, 30),
+				at("b", 14, 30, 30),
+			},
+			wantHour:  14,
+			wantCount: 1,
+		},
+		{
+			name: "tie goes to earlier hour",
+			events: []Event{
+				at("afternoon", 15, 0, 30),
+				at("morning", 8, 0, 30),
+			},
+			wantHour:  8,
+			wantCount: 1,
+		},
+		{
+			name: "hour is reported in the given location",
+			events: []Event{
+				at("sync", 15, 0, 30),
+			},
+			loc:       time.FixedZone("EST", -5*60*60),
+			wantHour:  10,
+			wantCount: 1,
+		},
+		{
+			name:      "empty",
+			events:    nil,
+			wantHour:  -1,
+			wantCount: 0,
+		},
+		{
+			name: "only unparseable events",
+			events: []Event{
+				{ID: "bad", Start: "not-a-time", End: "also-not-a-time"},
+			},
+			wantHour:  -1,
+			wantCount: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt // Capture loop variable
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			loc := tt.loc
+			if loc == nil {
+				loc = time.UTC
+			}
+			hour, count := BusiestHour(tt.events, loc)
+			if hour != tt.wantHour || count != tt.wantCount {
+				t.Errorf("BusiestHour() = (%d, %d), want (%d, %d)", hour, count, tt.wantHour, tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestPeakConcurrency(t *testing.T) {
+	t.Parallel()
+	base := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+
+	iv := func(startMin, endMin int) BusyInterval {
+		return BusyInterval{
+			Start: base.Add(time.Duration(startMin) * time.Minute),
+			End:   base.Add(time.Duration(endMin) * time.Minute),
+		}
+	}
+
+	tests := []struct {
+		name      string
+		intervals []BusyInterval
+		want      int
+	}{
+		{name: "empty", intervals: nil, want: 0},
+		{name: "single", intervals: []BusyInterval{iv(0, 30)}, want: 1},
+		{name: "adjacent", intervals: []BusyInterval{iv(0, 30), iv(30, 60)}, want: 1},
+		{name: "nested", intervals: []BusyInterval{iv(0, 60), iv(10, 20), iv(15, 50)}, want: 3},
+	}
+
+	for _, tt := range tests {
+		tt := tt // Capture loop variable
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := peakConcurrency(tt.intervals); got != tt.want {
+				t.Errorf("peakConcurrency() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseEventTime_AllDayAnchoring(t *testing.T) {
+	t.Parallel()
+
+	la, err := time.LoadLocation("America/Los_Angeles")
+	if err != nil {
+		t.Fatalf("Failed to load location: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		value string
+		loc   *time.Location
+		want  time.Time
+	}{
+		{
+			name:  "all-day date anchored to LA midnight",
+			value: "2024-01-15",
+			loc:   la,
+			want:  time.Date(2024, 1, 15, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "all-day date during PDT",
+			value: "2024-07-04",
+			loc:   la,
+			want:  time.Date(2024, 7, 4, 7, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "RFC3339 keeps its own offset",
+			value: "2024-01-15T09:00:00-05:00",
+			loc:   la,
+			want:  time.Date(2024, 1, 15, 14, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt // Capture loop variable
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := parseEventTime(tt.value, tt.loc)
+			if err != nil {
+				t.Fatalf("parseEventTime() error = %v", err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("parseEventTime() = %v, want %v", got.UTC(), tt.want)
+			}
+		})
+	}
+}
+
+func TestEventInterval_AllDayAcrossDST(t *testing.T) {
+	t.Parallel()
+
+	la, err := time.LoadLocation("America/Los_Angeles")
+	if err != nil {
+		t.Fatalf("Failed to load location: %v", err)
+	}
+
+	// Clocks spring forward on March 10, 2024, so the local day is 23 hours
+	iv, ok := eventInterval(Event{Start: "2024-03-10", End: "2024-03-11"}, la)
+	if !ok {
+		t.Fatal("eventInterval() ok = false, want true")
+	}
+	if want := time.Date(2024, 3, 10, 8, 0, 0, 0, time.UTC); !iv.Start.Equal(want) {
+		t.Errorf("eventInterval() Start = %v, want %v", iv.Start.UTC(), want)
+	}
+	if got := iv.End.Sub(iv.Start); got != 23*time.Hour {
+		t.Errorf("eventInterval() duration = %v, want 23h", got)
+	}
+}
+
+func TestFetchTodayEvents_UsesLocation(t *testing.T) {
+	t.Parallel()
+
+	la, err := time.LoadLocation("America/Los_Angeles")
+	if err != nil {
+		t.Fatalf("Failed to load location: %v", err)
+	}
+
+	var gotMin, gotMax string
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMin = r.URL.Query().Get("timeMin")
+		gotMax = r.URL.Query().Get("timeMax")
+		writeTestJSON(t, w, &calendar.Events{})
+	}))
+	client.Options.Location = la
+
+	if got := client.FetchTodayEvents(context.Background(), nil); !got.Success {
+		t.Fatalf("FetchTodayEvents() failed: %s", got.Message)
+	}
+
+	now := time.Now().In(la)
+	wantMin := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, la)
+	start, err := time.Parse(time.RFC3339, gotMin)
+	if err != nil {
+		t.Fatalf("timeMin %q is not RFC3339: %v", gotMin, err)
+	}
+	if !start.Equal(wantMin) {
+		t.Errorf("timeMin = %v, want %v", gotMin, wantMin.Format(time.RFC3339))
+	}
+	end, err := time.Parse(time.RFC3339, gotMax)
+	if err != nil {
+		t.Fatalf("timeMax %q is not RFC3339: %v", gotMax, err)
+	}
+	if !end.Equal(wantMin.AddDate(0, 0, 1)) {
+		t.Errorf("timeMax = %v, want %v", gotMax, wantMin.AddDate(0, 0, 1).Format(time.RFC3339))
+	}
+}
+
+func TestFindFreeSlots(t *testing.T) {
+	t.Parallel()
+	dayStart := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+	dayEnd := time.Date(2024, 1, 15, 17, 0, 0, 0, time.UTC)
+
+	at := func(id string, startHour, startMin, durMin int) Event {
+		start := dayStart.Add(time.Duration(startHour-9)*time.Hour + time.Duration(startMin)*time.Minute)
+		return Event{
+			ID:    id,
+			Start: start.Format(time.RFC3339),
+			End:   start.Add(time.Duration(durMin) * time.Minute).Format(time.RFC3339),
+		}
+	}
+	slot := func(startHour, startMin, endHour, endMin int) FreeSlot {
+		return FreeSlot{
+			Start: time.Date(2024, 1, 15, startHour, startMin, 0, 0, time.UTC),
+			End:   time.Date(2024, 1, 15, endHour, endMin, 0, 0, time.UTC),
+		}
+	}
+
+	tests := []struct {
+		name        string
+		events      []Event
+		minDuration time.Duration
+		want        []FreeSlot
+	}{
+		{
+			name:        "empty day is one slot",
+			minDuration: time.Hour,
+			want:        []FreeSlot{slot(9, 0, 17, 0)},
+		},
+		{
+			name: "overlapping events merge into one busy block",
+			events: []Event{
+				at("a", 10, 0, 60),
+				at("b", 10, 30, 60),
+			},
+			minDuration: 30 * time.Minute,
+			want:        []FreeSlot{slot(9, 0, 10, 0), slot(11, 30, 17, 0)},
+		},
+		{
+			name: "short gaps are dropped",
+			events: []Event{
+				at("a", 9, 0, 60),
+				at("b", 10, 15, 45),
+			},
+			minDuration: 30 * time.Minute,
+			want:        []FreeSlot{slot(11, 0, 17, 0)},
+		},
+		{
+			name: "events outside the day are clipped",
+			events: []Event{
+				at("early", 8, 0, 90),
+				at("late", 16, 30, 120),
+			},
+			minDuration: time.Minute,
+			want:        []FreeSlot{slot(9, 30, 16, 30)},
+		},
+		{
+			name: "fully booked",
+			events: []Event{
+				at("all", 9, 0, 8*60),
+			},
+			minDuration: time.Minute,
+			want:        nil,
+		},
+		{
+			name: "all-day event doesn't take up time",
+			events: []Event{
+				{ID: "sprint", Start: "2024-01-15", End: "2024-01-16", AllDay: true},
+				at("a", 10, 0, 60),
+			},
+			minDuration: time.Hour,
+			want:        []FreeSlot{slot(9, 0, 10, 0), slot(11, 0, 17, 0)},
+		},
+		{
+			name: "out-of-office block and marker don't take up time",
+			events: []Event{
+				{ID: "ooo", Start: "2024-01-15T12:00:00Z", End: "2024-01-15T17:00:00Z", EventType: eventTypeOutOfOffice},
+				{ID: "deadline", Start: "2024-01-15T12:00:00Z", End: "2024-01-15T12:00:00Z", Marker: true},
+				at("a", 10, 0, 60),
+			},
+			minDuration: time.Hour,
+			want:        []FreeSlot{slot(9, 0, 10, 0), slot(11, 0, 17, 0)},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt // Capture loop variable
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := FindFreeSlots(tt.events, dayStart, dayEnd, tt.minDuration)
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("FindFreeSlots() mismatch (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSuggestReschedule(t *testing.T) {
+	t.Parallel()
+	dayStart := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+	dayEnd := time.Date(2024, 1, 15, 17, 0, 0, 0, time.UTC)
+
+	target := Event{
+		ID:    "review",
+		Start: "2024-01-15T12:30:00Z",
+		End:   "2024-01-15T13:30:00Z",
+	}
+	others := []Event{
+		target,
+		{ID: "morning", Start: "2024-01-15T09:00:00Z", End: "2024-01-15T10:30:00Z"},
+		{ID: "midday", Start: "2024-01-15T11:00:00Z", End: "2024-01-15T14:00:00Z"},
+		{ID: "late", Start: "2024-01-15T16:30:00Z", End: "2024-01-15T17:00:00Z"},
+	}
+
+	got := SuggestReschedule(target, others, dayStart, dayEnd)
+	want := []FreeSlot{
+		{
+			Start: time.Date(2024, 1, 15, 14, 0, 0, 0, time.UTC),
+			End:   time.Date(2024, 1, 15, 16, 30, 0, 0, time.UTC),
+		},
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("SuggestReschedule() mismatch (-got +want):\n%s", diff)
+	}
+
+	// With a shorter target the morning gap also fits
+	target.End = "2024-01-15T13:00:00Z"
+	got = SuggestReschedule(target, others, dayStart, dayEnd)
+	want = []FreeSlot{
+		{
+			Start: time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC),
+			End:   time.Date(2024, 1, 15, 11, 0, 0, 0, time.UTC),
+		},
+		{
+			Start: time.Date(2024, 1, 15, 14, 0, 0, 0, time.UTC),
+			End:   time.Date(2024, 1, 15, 16, 30, 0, 0, time.UTC),
+		},
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("SuggestReschedule() short target mismatch (-got +want):\n%s", diff)
+	}
+
+	// An all-day event doesn't block the afternoon
+	sprint := Event{ID: "sprint", Start: "2024-01-15", End: "2024-01-16", AllDay: true}
+	if diff := cmp.Diff(SuggestReschedule(target, append(others, sprint), dayStart, dayEnd), want); diff != "" {
+		t.Errorf("SuggestReschedule() with all-day event mismatch (-got +want):\n%s", diff)
+	}
+
+	if got := SuggestReschedule(Event{Start: "bad", End: "bad"}, others, dayStart, dayEnd); got != nil {
+		t.Errorf("SuggestReschedule() with unparseable target = %v, want nil", got)
+	}
+}
+
+func TestScheduleDensity(t *testing.T) {
+	t.Parallel()
+	dayStart := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+	dayEnd := time.Date(2024, 1, 15, 17, 0, 0, 0, time.UTC)
+
+	at := func(id string, startHour, durMin int) Event {
+		start := dayStart.Add(time.Duration(startHour-9) * time.Hour)
+		return Event{
+			ID:    id,
+			Start: start.Format(time.RFC3339),
+			End:   start.Add(time.Duration(durMin) * time.Minute).Format(time.RFC3339),
+		}
+	}
+
+	tests := []struct {
+		name   string
+		events []Event
+		want   float64
+	}{
+		{
+			name: "empty day",
+			want: 0,
+		},
+		{
+			name:   "fully booked",
+			events: []Event{at("all", 9, 8*60)},
+			want:   1,
+		},
+		{
+			name: "half booked with overlap counted once",
+			events: []Event{
+				at("a", 9, 2*60),
+				at("b", 10, 60),
+				at("c", 13, 2*60),
+			},
+			want: 0.5,
+		},
+		{
+			name:   "spilling past the day is clamped",
+			events: []Event{at("long", 8, 12*60)},
+			want:   1,
+		},
+		{
+			name: "all-day event doesn't take up time",
+			events: []Event{
+				{ID: "sprint", Start: "2024-01-15", End: "2024-01-16", AllDay: true},
+				at("a", 9, 4*60),
+			},
+			want: 0.5,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt // Capture loop variable
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := ScheduleDensity(tt.events, dayStart, dayEnd); got != tt.want {
+				t.Errorf("ScheduleDensity() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if got := ScheduleDensity(nil, dayEnd, dayStart); got != 0 {
+		t.Errorf("ScheduleDensity() with reversed day = %v, want 0", got)
+	}
+}
+
+func TestValidateBusinessHours(t *testing.T) {
+	t.Parallel()
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("Failed to load location: %v", err)
+	}
+
+	// Monday, January 15 2024
+	event := func(startHour, startMin int, d time.Duration) Event {
+		start := time.Date(2024, 1, 15, startHour, startMin, 0, 0, ny)
+		return Event{
+			Title: "Meeting",
+			Start: start.Format(time.RFC3339),
+			End:   start.Add(d).Format(time.RFC3339),
+		}
+	}
+
+	tests := []struct {
+		name    string
+		event   Event
+		wantErr string
+	}{
+		{
+			name:  "within working hours",
+			event: event(9, 0, 8*time.Hour),
+		},
+		{
+			name:    "after hours",
+			event:   event(17, 30, time.Hour),
+			wantErr: "outside working hours 09:00-17:00",
+		},
+		{
+			name:    "runs past end of day",
+			event:   event(16, 30, time.Hour),
+			wantErr: "runs Mon 16:30-Mon 17:30",
+		},
+		{
+			name:    "starts before work",
+			event:   event(8, 45, 30*time.Minute),
+			wantErr: "outside working hours",
+		},
+		{
+			name: "weekend",
+			event: Event{
+				Title: "Meeting",
+				Start: "2024-01-13T10:00:00-05:00",
+				End:   "2024-01-13T11:00:00-05:00",
+			},
+			wantErr: "Saturday, which is not a working day",
+		},
+		{
+			name: "evaluated in loc, not the event's offset",
+			event: Event{
+				Title: "Meeting",
+				Start: "2024-01-15T14:00:00Z", // 09:00 in New York
+				End:   "2024-01-15T15:00:00Z",
+			},
+		},
+		{
+			name:    "unparseable",
+			event:   Event{Title: "Meeting", Start: "soon", End: "later"},
+			wantErr: "invalid start or end time",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt // Capture loop variable
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := ValidateBusinessHours(tt.event, DefaultWorkingHours(), ny)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateBusinessHours() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateBusinessHours() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
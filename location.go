@@ -0,0 +1,64 @@
+// Package gcal provides best-effort parsing of free-text event locations.
+package gcal
+
+import (
+	"regexp"
+	"strings"
+)
+
+// LocationInfo is the structured form of an event's free-text location
+type LocationInfo struct {
+	Room      string `json:"room,omitempty"`
+	Building  string `json:"building,omitempty"`
+	IsVirtual bool   `json:"isVirtual,omitempty"`
+}
+
+var (
+	// locationSeparator splits "Building A - Room 201" style locations
+	locationSeparator = regexp.MustCompile(`\s+[-–|/]\s+|,\s*`)
+
+	// roomPattern and buildingPattern recognize which part is which
+	roomPattern     = regexp.MustCompile(`(?i)\b(room|rm|conf(erence)?|suite|floor|fl)\b`)
+	buildingPattern = regexp.MustCompile(`(?i)\b(building|bldg|hq|campus|tower|office)\b`)
+
+	// virtualPattern matches locations naming a video provider rather than a place
+	virtualPattern = regexp.MustCompile(`(?i)^(https?://|zoom\b|google meet\b|microsoft teams\b|teams\b|webex\b)`)
+)
+
+// ParseLocation splits a free-text location into room and building on a
+// best-effort basis. URLs and video provider names are reported as virtual
+// with no room or building. With several parts, the one that looks like a
+// room ("Room 201", "Conf 3B") becomes Room and the first other part
+// becomes Building; without such hints the first part is the building and
+// the rest the room. A single unrecognized part is taken to be a room name.
+func ParseLocation(loc string) LocationInfo {
+	loc = strings.TrimSpace(loc)
+	if loc == "" {
+		return LocationInfo{}
+	}
+	if virtualPattern.MatchString(loc) {
+		return LocationInfo{IsVirtual: true}
+	}
+
+	var parts []string
+	for _, p := range locationSeparator.Split(loc, -1) {
+		if p = strings.TrimSpace(p); p != "" {
+			parts = append(parts, p)
+		}
+	}
+
+	if len(parts) == 1 {
+		if buildingPattern.MatchString(parts[0]) && !roomPattern.MatchString(parts[0]) {
+			return LocationInfo{Building: parts[0]}
+		}
+		return LocationInfo{Room: parts[0]}
+	}
+
+	for i, p := range parts {
+		if roomPattern.MatchString(p) && !buildingPattern.MatchString(p) {
+			rest := append(append([]string{}, parts[:i]...), parts[i+1:]...)
+			return LocationInfo{Room: p, Building: rest[0]}
+		}
+	}
+	return LocationInfo{Building: parts[0], Room: strings.Join(parts[1:], ", ")}
+}
@@ -0,0 +1,69 @@
+package gcal
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseLocation(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		loc  string
+		want LocationInfo
+	}{
+		{
+			name: "building and room",
+			loc:  "Building A - Room 201",
+			want: LocationInfo{Building: "Building A", Room: "Room 201"},
+		},
+		{
+			name: "room before building",
+			loc:  "Conf Room 3B, HQ Tower",
+			want: LocationInfo{Building: "HQ Tower", Room: "Conf Room 3B"},
+		},
+		{
+			name: "unlabeled parts",
+			loc:  "NYC-9 | Huddle",
+			want: LocationInfo{Building: "NYC-9", Room: "Huddle"},
+		},
+		{
+			name: "single room name",
+			loc:  "Everest",
+			want: LocationInfo{Room: "Everest"},
+		},
+		{
+			name: "single building",
+			loc:  "Main Building",
+			want: LocationInfo{Building: "Main Building"},
+		},
+		{
+			name: "virtual URL",
+			loc:  "https://zoom.us/j/123456789",
+			want: LocationInfo{IsVirtual: true},
+		},
+		{
+			name: "virtual provider name",
+			loc:  "Google Meet",
+			want: LocationInfo{IsVirtual: true},
+		},
+		{
+			name: "empty",
+			loc:  "   ",
+			want: LocationInfo{},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt // Capture loop variable
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if diff := cmp.Diff(ParseLocation(tt.loc), tt.want); diff != "" {
+				t.Errorf("ParseLocation(%q) mismatch (-got +want):\n%s", tt.loc, diff)
+			}
+		})
+	}
+}
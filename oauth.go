@@ -3,18 +3,28 @@ package gcal
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"html"
+	"io"
 	"net"
 	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
 	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/option"
 )
 
 const (
@@ -23,6 +33,18 @@ const (
 	DefaultCallbackPort = 8085
 )
 
+// EphemeralPort, passed as the callback port, has RunAuthFlow listen on
+// any free port the system picks. It is the only negative port accepted.
+const EphemeralPort = -1
+
+// checkCallbackPort rejects negative callback ports other than EphemeralPort
+func checkCallbackPort(port int) error {
+	if port < 0 && port != EphemeralPort {
+		return fmt.Errorf("%s: callback port %d is negative; use 0 for the default or EphemeralPort", ErrInvalidConfig, port)
+	}
+	return nil
+}
+
 // getConfigDir returns ~/.config/gcal
 func getConfigDir() (string, error) {
 	configHome := os.Getenv("XDG_CONFIG_HOME")
@@ -81,25 +103,139 @@ func LoadCredentials() (*Credentials, error) {
 	return &creds, nil
 }
 
-// getOAuthConfig creates OAuth2 config from credentials
-func getOAuthConfig(creds *Credentials, port int) *oauth2.Config {
+// DefaultScopes are the OAuth scopes requested when none are given with
+// WithScopes: read-only calendar access
+var DefaultScopes = []string{calendar.CalendarReadonlyScope}
+
+// defaultScopes returns scopes, or DefaultScopes if empty
+func defaultScopes(scopes []string) []string {
+	if len(scopes) == 0 {
+		return append([]string(nil), DefaultScopes...)
+	}
+	return scopes
+}
+
+// getOAuthConfig creates OAuth2 config from credentials, requesting scopes
+// or DefaultScopes if none are given
+func getOAuthConfig(creds *Credentials, port int, scopes ...string) *oauth2.Config {
 	return &oauth2.Config{
 		ClientID:     creds.ClientID,
 		ClientSecret: creds.ClientSecret,
 		Endpoint:     google.Endpoint,
 		RedirectURL:  fmt.Sprintf("http://localhost:%d/callback", port),
-		Scopes:       []string{calendar.CalendarReadonlyScope},
+		Scopes:       defaultScopes(scopes),
+	}
+}
+
+// PreflightAuth checks, without opening a browser, that RunAuthFlow can
+// run with creds on port (DefaultCallbackPort if 0): the credentials are
+// complete and the callback port is free. It returns the redirect URI the
+// flow will use, which must be registered for the OAuth client in Google
+// Cloud Console unless it is a Desktop app client. The URI is returned even
+// when a check fails, so it can be shown alongside the error. With
+// EphemeralPort the port is only chosen when the flow runs, so there is no
+// port to check and the URI is empty; only Desktop app clients, which
+// accept any loopback port, can use it. Other negative ports are rejected.
+func PreflightAuth(creds *Credentials, port int) (redirectURI string, err error) {
+	if err := checkCallbackPort(port); err != nil {
+		return "", err
+	}
+	if creds == nil {
+		creds = &Credentials{}
+	}
+	if port == EphemeralPort {
+		if creds.ClientID == "" || creds.ClientSecret == "" {
+			return "", fmt.Errorf("%s: credentials missing clientId or clientSecret", ErrNotConfigured)
+		}
+		return "", nil
+	}
+	if port == 0 {
+		port = DefaultCallbackPort
+	}
+	redirectURI = getOAuthConfig(creds, port).RedirectURL
+
+	if creds.ClientID == "" || creds.ClientSecret == "" {
+		return redirectURI, fmt.Errorf("%s: credentials missing clientId or clientSecret", ErrNotConfigured)
+	}
+
+	listener, _, err := listenCallback(port, port)
+	if err != nil {
+		return redirectURI, fmt.Errorf("%w - register the redirect URI of the port you pick", err)
+	}
+	listener.Close()
+
+	return redirectURI, nil
+}
+
+// callbackPortFallbacks is how many ports after DefaultCallbackPort
+// RunAuthFlow tries when the caller doesn't pick a port
+const callbackPortFallbacks = 5
+
+// listenCallback listens for the auth callback on the first free port from
+// first through last and returns the port chosen; port 0 has the system
+// pick one. Errors other than the port being in use stop the search.
+func listenCallback(first, last int) (net.Listener, int, error) {
+	var err error
+	for port := first; port <= last; port++ {
+		var listener net.Listener
+		listener, err = net.Listen("tcp", fmt.Sprintf(":%d", port))
+		if err == nil {
+			return listener, listener.Addr().(*net.TCPAddr).Port, nil
+		}
+		if !errors.Is(err, syscall.EADDRINUSE) {
+			return nil, port, fmt.Errorf("start callback server: %w", err)
+		}
+	}
+	if first == last {
+		return nil, first, fmt.Errorf("callback port %d is already in use - pass a different port: %w", first, err)
 	}
+	return nil, first, fmt.Errorf("callback ports %d-%d are all in use - pass a free port: %w", first, last, err)
 }
 
-// LoadToken loads saved OAuth token from data dir
+// LoadToken loads saved OAuth token from data dir, decrypting it with
+// GCAL_TOKEN_PASSPHRASE if it was saved encrypted. Granted scopes, when
+// recorded, are available as the token's "scope" extra.
 func LoadToken() (*oauth2.Token, error) {
+	return loadTokenFile(tokenFile)
+}
+
+// loadTokenFile loads a token from name in the data dir, returning nil if
+// it doesn't exist
+func loadTokenFile(name string) (*oauth2.Token, error) {
+	store, err := loadTokenStore(name)
+	if err != nil || store == nil {
+		return nil, err
+	}
+
+	return store.oauthToken(), nil
+}
+
+// oauthToken converts the stored token back to an oauth2.Token
+func (s *TokenStore) oauthToken() *oauth2.Token {
+	token := &oauth2.Token{
+		AccessToken:  s.AccessToken,
+		RefreshToken: s.RefreshToken,
+		TokenType:    s.TokenType,
+		Expiry:       s.Expiry,
+	}
+	if len(s.Scopes) > 0 {
+		// Expose scopes the same way a fresh token response does
+		token = token.WithExtra(map[string]interface{}{
+			"scope": strings.Join(s.Scopes, " "),
+		})
+	}
+	return token
+}
+
+// loadTokenStore reads and decrypts the stored token in name, returning nil
+// if it doesn't exist
+func loadTokenStore(name string) (*TokenStore, error) {
 	dataDir, err := getDataDir()
 	if err != nil {
 		return nil, err
 	}
 
-	path := filepath.Join(dataDir, tokenFile)
+	path := filepath.Join(dataDir, name)
 	data, err := os.ReadFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -108,21 +244,26 @@ func LoadToken() (*oauth2.Token, error) {
 		return nil, fmt.Errorf("read token: %w", err)
 	}
 
+	data, err = decryptToken(data, tokenPassphrase())
+	if err != nil {
+		return nil, err
+	}
+
 	var store TokenStore
 	if err := json.Unmarshal(data, &store); err != nil {
 		return nil, fmt.Errorf("parse token: %w", err)
 	}
-
-	return &oauth2.Token{
-		AccessToken:  store.AccessToken,
-		RefreshToken: store.RefreshToken,
-		TokenType:    store.TokenType,
-		Expiry:       store.Expiry,
-	}, nil
+	return &store, nil
 }
 
-// SaveToken saves OAuth token to data dir with 0600 permissions
+// SaveToken saves OAuth token to data dir with 0600 permissions, encrypted
+// with GCAL_TOKEN_PASSPHRASE when it is set
 func SaveToken(token *oauth2.Token) error {
+	return saveTokenFile(tokenFile, token)
+}
+
+// saveTokenFile saves a token to name in the data dir with 0600 permissions
+func saveTokenFile(name string, token *oauth2.Token) error {
 	dataDir, err := getDataDir()
 	if err != nil {
 		return err
@@ -133,6 +274,14 @@ func SaveToken(token *oauth2.Token) error {
 		RefreshToken: token.RefreshToken,
 		TokenType:    token.TokenType,
 		Expiry:       token.Expiry,
+		Scopes:       tokenScopes(token),
+	}
+	if len(store.Scopes) == 0 {
+		// Refresh responses may omit the scope; keep what the original
+		// grant recorded
+		if prev, err := loadTokenStore(name); err == nil && prev != nil {
+			store.Scopes = prev.Scopes
+		}
 	}
 
 	data, err := json.MarshalIndent(store, "", "  ")
@@ -140,7 +289,13 @@ func SaveToken(token *oauth2.Token) error {
 		return fmt.Errorf("marshal token: %w", err)
 	}
 
-	path := filepath.Join(dataDir, tokenFile)
+	if passphrase := tokenPassphrase(); passphrase != "" {
+		if data, err = encryptToken(data, passphrase); err != nil {
+			return fmt.Errorf("encrypt token: %w", err)
+		}
+	}
+
+	path := filepath.Join(dataDir, name)
 	if err := os.WriteFile(path, data, 0600); err != nil {
 		return fmt.Errorf("write token: %w", err)
 	}
@@ -148,92 +303,522 @@ func SaveToken(token *oauth2.Token) error {
 	return nil
 }
 
-// RunAuthFlow performs the OAuth browser flow and saves the token
-func RunAuthFlow(creds *Credentials, port int) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+// PrintToken writes the saved token as plain TokenStore JSON to w, e.g. to
+// copy it into a container with `gcal token > token.json`. The output
+// includes the refresh token in clear text, even when the file is
+// encrypted with GCAL_TOKEN_PASSPHRASE; anyone who can read it has access
+// to the calendar until the token is revoked, so treat it like a password.
+func PrintToken(w io.Writer) error {
+	store, err := loadTokenStore(tokenFile)
+	if err != nil {
+		return err
+	}
+	if store == nil {
+		return fmt.Errorf("%s: no token found - run 'gcal auth' first", ErrNotConfigured)
+	}
+
+	data, err := json.MarshalIndent(store, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal token: %w", err)
+	}
+	if _, err := fmt.Fprintf(w, "%s\n", data); err != nil {
+		return fmt.Errorf("write token: %w", err)
+	}
+	return nil
+}
+
+// ImportToken reads TokenStore JSON, as written by PrintToken, from r and
+// saves it as the current token, replacing any existing one
+func ImportToken(r io.Reader) error {
+	var store TokenStore
+	if err := json.NewDecoder(r).Decode(&store); err != nil {
+		return fmt.Errorf("parse token: %w", err)
+	}
+	if store.AccessToken == "" && store.RefreshToken == "" {
+		return fmt.Errorf("parse token: no access or refresh token")
+	}
+	return SaveToken(store.oauthToken())
+}
+
+// TokenPersister stores OAuth tokens after they are refreshed, letting
+// embedders keep tokens somewhere other than the gcal data directory
+type TokenPersister interface {
+	Save(token *oauth2.Token) error
+}
+
+// TokenPersisterFunc adapts an ordinary function to a TokenPersister
+type TokenPersisterFunc func(token *oauth2.Token) error
+
+// Save calls f(token)
+func (f TokenPersisterFunc) Save(token *oauth2.Token) error {
+	return f(token)
+}
+
+// fileTokenPersister saves tokens to the data dir with SaveToken
+type fileTokenPersister struct{}
+
+// Save writes the token with SaveToken
+func (fileTokenPersister) Save(token *oauth2.Token) error {
+	return SaveToken(token)
+}
+
+// AuthOption configures how clients authenticate
+type AuthOption func(*authOptions)
+
+// authOptions holds the settings applied by AuthOption values
+type authOptions struct {
+	persister TokenPersister
+	endpoint  oauth2.Endpoint // overrides google.Endpoint when set
+	scopes    []string        // empty uses DefaultScopes
+
+	// Pages served by RunAuthFlow's callback; empty uses the defaults
+	successHTML     string
+	errorHTML       string
+	successRedirect string // takes precedence over successHTML
+
+	openBrowser func(url string) // opens the auth URL; replaced in tests
+
+	calendarEndpoint string // overrides the Calendar API endpoint when set, for tests
+}
+
+// WithTokenPersister sets where refreshed tokens are saved. A nil persister
+// keeps refreshed tokens in memory only.
+func WithTokenPersister(p TokenPersister) AuthOption {
+	return func(o *authOptions) {
+		o.persister = p
+	}
+}
+
+// WithScopes sets the OAuth scopes to request, e.g. calendar.CalendarScope
+// to create or modify events. Without it, DefaultScopes are requested.
+// Scopes only take effect when authorizing with RunAuthFlow; a saved
+// token keeps the scopes it was granted, so re-run the flow after
+// changing them.
+func WithScopes(scopes ...string) AuthOption {
+	return func(o *authOptions) {
+		o.scopes = scopes
+	}
+}
+
+// withEndpoint overrides the OAuth endpoint, for tests
+func withEndpoint(endpoint oauth2.Endpoint) AuthOption {
+	return func(o *authOptions) {
+		o.endpoint = endpoint
+	}
+}
+
+// withCalendarEndpoint overrides the Calendar API endpoint RunAuthFlow uses
+// to look up the connected account, for tests
+func withCalendarEndpoint(url string) AuthOption {
+	return func(o *authOptions) {
+		o.calendarEndpoint = url
+	}
+}
+
+// withBrowserOpener replaces how the auth URL is opened, for tests
+func withBrowserOpener(open func(url string)) AuthOption {
+	return func(o *authOptions) {
+		o.openBrowser = open
+	}
+}
+
+// WithSuccessHTML sets the page RunAuthFlow shows in the browser once the
+// authorization completes. The HTML is served as-is, so unlike the default
+// page it doesn't name the connected account.
+func WithSuccessHTML(html string) AuthOption {
+	return func(o *authOptions) {
+		o.successHTML = html
+	}
+}
+
+// WithErrorHTML sets the page RunAuthFlow shows in the browser when the
+// callback carries no authorization code. The HTML is served as-is.
+func WithErrorHTML(html string) AuthOption {
+	return func(o *authOptions) {
+		o.errorHTML = html
+	}
+}
+
+// WithAuthSuccessRedirect makes RunAuthFlow's callback redirect the browser
+// to url with a 302 once the authorization code is received, instead of
+// showing a success page. Useful for sending users to docs or an app link.
+func WithAuthSuccessRedirect(url string) AuthOption {
+	return func(o *authOptions) {
+		o.successRedirect = url
+	}
+}
+
+// newAuthOptions applies opts over the given default persister
+func newAuthOptions(persister TokenPersister, opts []AuthOption) *authOptions {
+	o := &authOptions{persister: persister, openBrowser: openBrowser}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// oauthConfig creates the OAuth2 config, applying any endpoint override
+func (o *authOptions) oauthConfig(creds *Credentials, port int) *oauth2.Config {
+	config := getOAuthConfig(creds, port, o.scopes...)
+	if o.endpoint.TokenURL != "" {
+		config.Endpoint = o.endpoint
+	}
+	return config
+}
+
+// persistingTokenSource saves tokens through a TokenPersister whenever the
+// underlying source hands out a new access token
+type persistingTokenSource struct {
+	base      oauth2.TokenSource
+	persister TokenPersister
+
+	mu   sync.Mutex
+	last string // access token most recently seen
+}
+
+// newPersistingTokenSource wraps config's token source for token so that
+// refreshes are saved through persister
+func newPersistingTokenSource(ctx context.Context, config *oauth2.Config, token *oauth2.Token, persister TokenPersister) *persistingTokenSource {
+	return &persistingTokenSource{
+		base:      config.TokenSource(ctx, token),
+		persister: persister,
+		last:      token.AccessToken,
+	}
+}
+
+// Token returns a valid token, saving it if it was refreshed
+func (s *persistingTokenSource) Token() (*oauth2.Token, error) {
+	token, err := s.base.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if token.AccessToken != s.last {
+		s.last = token.AccessToken
+		if s.persister != nil {
+			if err := s.persister.Save(token); err != nil {
+				// Log but don't fail - we still have a valid token
+				fmt.Fprintf(os.Stderr, "warning: failed to save refreshed token: %v\n", err)
+			}
+		}
+	}
+
+	return token, nil
+}
+
+// authFlowTimeout bounds how long RunAuthFlow waits for the browser callback
+const authFlowTimeout = 5 * time.Minute
+
+// defaultSuccessHTML is the callback page shown after a successful authorization
+const defaultSuccessHTML = `<html><body><h1>Authorization successful!</h1><p>You can close this tab and return to the terminal.</p></body></html>`
+
+// connectedSuccessHTML is defaultSuccessHTML naming the connected account,
+// formatted with the HTML-escaped email
+const connectedSuccessHTML = `<html><body><h1>Authorization successful!</h1><p>Connected as %s.</p><p>You can close this tab and return to the terminal.</p></body></html>`
+
+// accountLookupTimeout bounds the best-effort lookup of the connected
+// account's email after the code exchange
+const accountLookupTimeout = 10 * time.Second
+
+// callbackReplyTimeout bounds how long the callback waits for RunAuthFlow to
+// finish the exchange before showing the success page anyway. It's below
+// callbackTimeout so the page is written before the server gives up.
+const callbackReplyTimeout = 20 * time.Second
+
+// authStateBytes is how much randomness goes into an auth flow's state
+const authStateBytes = 32
+
+// newAuthState returns a random state value for an auth URL
+func newAuthState() (string, error) {
+	b := make([]byte, authStateBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate state: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// authCallback carries an authorization code from the callback to
+// RunAuthFlow, which replies once the code has been exchanged
+type authCallback struct {
+	code  string
+	reply chan<- authResult // buffered, so replies never block
+}
+
+// authResult is the outcome of exchanging a callback's code
+type authResult struct {
+	email string // connected account, empty if unknown
+	err   error
+}
+
+// RunAuthFlow performs the OAuth browser flow and saves the token. The
+// default success page names the connected account, looked up from the
+// primary calendar on a best-effort basis. Use WithSuccessHTML and
+// WithErrorHTML to brand the callback pages, or
+// WithAuthSuccessRedirect to send the browser elsewhere on success, and
+// WithTokenPersister to store the token somewhere other than the data dir.
+// Read-only access is requested unless WithScopes asks for more.
+//
+// With port 0 the callback listens on DefaultCallbackPort, or the next
+// free port up to 5 above it, and with EphemeralPort on any free port the
+// system picks; a Desktop app client accepts any loopback port. An explicit
+// port is used as is, and other negative ports are rejected.
+//
+// The flow waits up to 5 minutes for the browser callback. Cancelling ctx,
+// e.g. on Ctrl-C, stops the callback server and returns ctx's error.
+func RunAuthFlow(ctx context.Context, creds *Credentials, port int, opts ...AuthOption) error {
+	if err := checkCallbackPort(port); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, authFlowTimeout)
 	defer cancel()
 
-	if port <= 0 {
-		port = DefaultCallbackPort
+	o := newAuthOptions(fileTokenPersister{}, opts)
+	first, last := port, port
+	switch {
+	case port == EphemeralPort:
+		first, last = 0, 0
+	case port == 0:
+		first, last = DefaultCallbackPort, DefaultCallbackPort+callbackPortFallbacks
 	}
-	config := getOAuthConfig(creds, port)
 
-	// Create a channel to receive the auth code
-	codeChan := make(chan string, 1)
-	errChan := make(chan error, 1)
+	// A fresh state ties the callback to this flow, and the PKCE verifier
+	// proves to Google that the code is exchanged by whoever requested it
+	state, err := newAuthState()
+	if err != nil {
+		return err
+	}
+	verifier := oauth2.GenerateVerifier()
 
-	// Start local HTTP server for callback
-	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	// Start local HTTP server for callback, before building the config so
+	// the redirect URL names the port actually bound
+	listener, port, err := listenCallback(first, last)
 	if err != nil {
-		return fmt.Errorf("start callback server: %w", err)
+		return err
 	}
+	config := o.oauthConfig(creds, port)
+
+	// Create a channel to receive the auth code
+	codeChan := make(chan authCallback, 1)
+	errChan := make(chan error, 1)
 
 	// Use a new mux to avoid global handler registration
 	mux := http.NewServeMux()
-	mux.HandleFunc("/callback", func(w http.ResponseWriter, r *http.Request) {
-		code := r.URL.Query().Get("code")
-		if code == "" {
-			errChan <- fmt.Errorf("no code in callback")
-			http.Error(w, "No code received", http.StatusBadRequest)
-			return
-		}
-		codeChan <- code
-		w.Header().Set("Content-Type", "text/html")
-		fmt.Fprintf(w, `<html><body><h1>Authorization successful!</h1><p>You can close this tab and return to the terminal.</p></body></html>`)
-	})
+	mux.Handle("/callback", o.callbackHandler(state, codeChan, errChan))
 
-	server := &http.Server{
-		Handler: mux,
-	}
+	server := newCallbackServer(mux)
 
+	serveDone := make(chan struct{})
 	go func() {
+		defer close(serveDone)
 		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
-			errChan <- err
+			select {
+			case errChan <- err:
+			default:
+			}
 		}
 	}()
 
 	// Generate auth URL and open browser
-	authURL := config.AuthCodeURL("state", oauth2.AccessTypeOffline, oauth2.ApprovalForce)
+	authURL := config.AuthCodeURL(state, oauth2.AccessTypeOffline, oauth2.ApprovalForce, oauth2.S256ChallengeOption(verifier))
 	fmt.Printf("Opening browser for authorization...\n")
 	fmt.Printf("If browser doesn't open, visit:\n%s\n\n", authURL)
 
 	// Try to open browser
-	openBrowser(authURL)
+	o.openBrowser(authURL)
 
-	// Wait for callback or timeout
-	var code string
+	// Wait for callback, timeout, or cancellation
+	var callback authCallback
 	select {
-	case code = <-codeChan:
+	case callback = <-codeChan:
 		// Success
 	case err := <-errChan:
-		server.Shutdown(ctx)
+		shutdownCallbackServer(server, serveDone)
 		return err
 	case <-ctx.Done():
-		server.Shutdown(ctx)
-		return fmt.Errorf("authorization timeout - no response received")
+		shutdownCallbackServer(server, serveDone)
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("authorization timeout - no response received: %w", ctx.Err())
+		}
+		return fmt.Errorf("authorization cancelled: %w", ctx.Err())
 	}
 
+	// Finish before shutting down, so the callback can show the outcome
+	result := o.completeAuth(ctx, config, callback.code, verifier)
+	callback.reply <- result
+
 	// Gracefully shutdown server
-	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer shutdownCancel()
-	if err := server.Shutdown(shutdownCtx); err != nil {
-		// Log but don't fail - we already have the code
+	if err := shutdownCallbackServer(server, serveDone); err != nil {
+		// Log but don't fail - we already have the token
 		fmt.Fprintf(os.Stderr, "warning: failed to shutdown server gracefully: %v\n", err)
 	}
 
-	// Exchange code for token
-	token, err := config.Exchange(ctx, code)
+	if result.err != nil {
+		return result.err
+	}
+	if result.email != "" {
+		fmt.Printf("Authorization successful! Connected as %s. Token saved.\n", result.email)
+		return nil
+	}
+	fmt.Println("Authorization successful! Token saved.")
+	return nil
+}
+
+// completeAuth exchanges code, with the PKCE verifier its auth URL was
+// built from, for a token, saves it, and looks up the connected account
+func (o *authOptions) completeAuth(ctx context.Context, config *oauth2.Config, code, verifier string) authResult {
+	token, err := config.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return authResult{err: fmt.Errorf("exchange code: %w", err)}
+	}
+
+	if o.persister != nil {
+		if err := o.persister.Save(token); err != nil {
+			return authResult{err: fmt.Errorf("save token: %w", err)}
+		}
+	}
+
+	return authResult{email: o.accountEmail(ctx, config, token)}
+}
+
+// accountEmail returns the email of the account token belongs to, taken
+// from its primary calendar's ID, or "" if the lookup fails
+func (o *authOptions) accountEmail(ctx context.Context, config *oauth2.Config, token *oauth2.Token) string {
+	ctx, cancel := context.WithTimeout(ctx, accountLookupTimeout)
+	defer cancel()
+
+	srvOpts := []option.ClientOption{option.WithHTTPClient(config.Client(ctx, token))}
+	if o.calendarEndpoint != "" {
+		srvOpts = append(srvOpts, option.WithEndpoint(o.calendarEndpoint))
+	}
+	srv, err := calendar.NewService(ctx, srvOpts...)
 	if err != nil {
-		return fmt.Errorf("exchange code: %w", err)
+		return ""
 	}
 
-	// Save token
-	if err := SaveToken(token); err != nil {
-		return fmt.Errorf("save token: %w", err)
+	entry, err := srv.CalendarList.Get("primary").Context(ctx).Do()
+	if err != nil {
+		return ""
 	}
+	return entry.Id
+}
 
-	fmt.Println("Authorization successful! Token saved.")
-	return nil
+// Callback server timeouts, so a stuck or slow loopback connection can't
+// wedge the auth flow
+const (
+	callbackReadHeaderTimeout = 10 * time.Second
+	callbackTimeout           = 30 * time.Second
+)
+
+// newCallbackServer creates the auth callback server with timeouts set
+func newCallbackServer(handler http.Handler) *http.Server {
+	return &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: callbackReadHeaderTimeout,
+		ReadTimeout:       callbackTimeout,
+		WriteTimeout:      callbackTimeout,
+		IdleTimeout:       callbackTimeout,
+	}
+}
+
+// shutdownCallbackServer stops the callback server, giving in-flight
+// responses a few seconds to finish, and waits for Serve to return so the
+// listener is closed. It uses its own context so it still works after the
+// flow's context is done.
+func shutdownCallbackServer(server *http.Server, serveDone <-chan struct{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := server.Shutdown(ctx)
+	<-serveDone
+	return err
+}
+
+// callbackHandler handles the OAuth redirect, sending the authorization code
+// or an error on the channels and showing the configured page. Sends never
+// block, so repeated callbacks can't wedge the handler. The success page
+// waits for the reply to the code, up to callbackReplyTimeout, so it can
+// report a failed exchange or name the connected account. Callbacks not
+// carrying state, or carrying neither a code nor an error, are refused
+// while the flow keeps waiting.
+func (o *authOptions) callbackHandler(state string, codeChan chan<- authCallback, errChan chan<- error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// A callback that doesn't carry our state wasn't started by this
+		// flow, so it is refused without ending the flow: a stray request
+		// mustn't cancel the real one
+		query := r.URL.Query()
+		if subtle.ConstantTimeCompare([]byte(query.Get("state")), []byte(state)) != 1 {
+			o.writeErrorPage(w, "Invalid state", http.StatusBadRequest)
+			return
+		}
+
+		code := query.Get("code")
+		if code == "" {
+			// Only Google's own answer, e.g. the user denying access, ends
+			// the flow
+			if reason := query.Get("error"); reason != "" {
+				select {
+				case errChan <- fmt.Errorf("authorization failed: %s", reason):
+				default:
+				}
+			}
+			o.writeErrorPage(w, "No code received", http.StatusBadRequest)
+			return
+		}
+
+		reply := make(chan authResult, 1)
+		select {
+		case codeChan <- authCallback{code: code, reply: reply}:
+		default:
+			// A code was already received; don't wait on a reply that
+			// will never come
+			reply <- authResult{}
+		}
+		if o.successRedirect != "" {
+			http.Redirect(w, r, o.successRedirect, http.StatusFound)
+			return
+		}
+
+		var result authResult
+		select {
+		case result = <-reply:
+		case <-r.Context().Done():
+			return
+		case <-time.After(callbackReplyTimeout):
+		}
+
+		if result.err != nil {
+			o.writeErrorPage(w, "Authorization failed", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		io.WriteString(w, o.successPage(result.email))
+	})
+}
+
+// writeErrorPage writes the configured error page, or message as plain text
+// if none is set
+func (o *authOptions) writeErrorPage(w http.ResponseWriter, message string, status int) {
+	if o.errorHTML == "" {
+		http.Error(w, message, status)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html")
+	w.WriteHeader(status)
+	io.WriteString(w, o.errorHTML)
+}
+
+// successPage returns the page shown after a successful authorization,
+// naming the connected account on the default page when email is known
+func (o *authOptions) successPage(email string) string {
+	if o.successHTML != "" {
+		return o.successHTML
+	}
+	if email == "" {
+		return defaultSuccessHTML
+	}
+	return fmt.Sprintf(connectedSuccessHTML, html.EscapeString(email))
 }
 
 // openBrowser opens URL in default browser
@@ -274,8 +859,11 @@ func openBrowser(url string) {
 	}()
 }
 
-// GetClient returns an authenticated HTTP client, refreshing token if needed
-func GetClient(ctx context.Context) (*http.Client, error) {
+// GetClient returns an authenticated HTTP client, refreshing token if needed.
+// Refreshed tokens are saved to the data dir unless WithTokenPersister is given.
+func GetClient(ctx context.Context, opts ...AuthOption) (*http.Client, error) {
+	o := newAuthOptions(fileTokenPersister{}, opts)
+
 	creds, err := LoadCredentials()
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", ErrNotConfigured, err)
@@ -289,32 +877,18 @@ func GetClient(ctx context.Context) (*http.Client, error) {
 		return nil, fmt.Errorf("%s: no token found - run 'gcal auth' first", ErrNotConfigured)
 	}
 
-	config := getOAuthConfig(creds, DefaultCallbackPort)
-	tokenSource := config.TokenSource(ctx, token)
+	config := o.oauthConfig(creds, DefaultCallbackPort)
+	tokenSource := newPersistingTokenSource(ctx, config, token, o.persister)
 
-	// Get potentially refreshed token
-	newToken, err := tokenSource.Token()
-	if err != nil {
+	// Get potentially refreshed token, saving it if it was refreshed
+	if _, err := tokenSource.Token(); err != nil {
 		return nil, fmt.Errorf("%s: %w", ErrTokenExpired, err)
 	}
 
-	// Save if token was refreshed
-	if newToken.AccessToken != token.AccessToken {
-		if err := SaveToken(newToken); err != nil {
-			// Log but don't fail - we still have a valid token
-			fmt.Fprintf(os.Stderr, "warning: failed to save refreshed token: %v\n", err)
-		}
-	}
-
 	return oauth2.NewClient(ctx, tokenSource), nil
 }
 
 // IsConfigured checks if credentials and token are available
 func IsConfigured() bool {
-	creds, err := LoadCredentials()
-	if err != nil || creds == nil {
-		return false
-	}
-	token, err := LoadToken()
-	return err == nil && token != nil
+	return IsConfiguredForScopes(nil)
 }
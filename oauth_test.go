@@ -1,14 +1,31 @@
 package gcal
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"testing"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"golang.org/x/oauth2"
+	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/option"
 )
 
 func TestLoadCredentials(t *testing.T) {
@@ -384,3 +401,958 @@ func TestGetOAuthConfig(t *testing.T) {
 		t.Errorf("getOAuthConfig() Scopes length = %v, want 1", len(config.Scopes))
 	}
 }
+
+// recordingPersister records every token it is asked to save
+type recordingPersister struct {
+	mu    sync.Mutex
+	saved []string
+}
+
+func (p *recordingPersister) Save(token *oauth2.Token) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.saved = append(p.saved, token.AccessToken)
+	return nil
+}
+
+func TestNewClientWithToken_PersistsRefreshedToken(t *testing.T) {
+	t.Parallel()
+
+	tokenServer, refreshes := newTestTokenServer(t, "refreshed-access-token")
+
+	var gotAuth string
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		writeTestJSON(t, w, &calendar.Events{})
+	}))
+	defer apiServer.Close()
+
+	creds := &Credentials{ClientID: "test-id", ClientSecret: "test-secret"}
+	expired := &oauth2.Token{
+		AccessToken:  "expired-access-token",
+		RefreshToken: "refresh-token",
+		TokenType:    "Bearer",
+		Expiry:       time.Now().Add(-time.Hour),
+	}
+	persister := &recordingPersister{}
+
+	client, err := newClientWithToken(context.Background(), creds, expired,
+		[]AuthOption{
+			WithTokenPersister(persister),
+			withEndpoint(oauth2.Endpoint{TokenURL: tokenServer.URL}),
+		},
+		option.WithEndpoint(apiServer.URL+"/"),
+	)
+	if err != nil {
+		t.Fatalf("newClientWithToken() error = %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if got := client.FetchUpcomingEvents(context.Background(), nil, 24); !got.Success {
+			t.Fatalf("FetchUpcomingEvents() failed: %s", got.Message)
+		}
+	}
+
+	if gotAuth != "Bearer refreshed-access-token" {
+		t.Errorf("Authorization header = %q, want refreshed token", gotAuth)
+	}
+	if n := atomic.LoadInt32(refreshes); n != 1 {
+		t.Errorf("token endpoint called %d times, want 1", n)
+	}
+	if diff := cmp.Diff(persister.saved, []string{"refreshed-access-token"}); diff != "" {
+		t.Errorf("persisted tokens mismatch (-got +want):\n%s", diff)
+	}
+}
+
+func TestGetClient_CustomPersister(t *testing.T) {
+	// Not parallel: GetClient reads credentials and token from the XDG dirs
+	configDir, dataDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	tokenServer, _ := newTestTokenServer(t, "refreshed-access-token")
+
+	createTestCredentials(t, configDir, Credentials{ClientID: "test-id", ClientSecret: "test-secret"})
+	createTestToken(t, dataDir, TokenStore{
+		AccessToken:  "expired-access-token",
+		RefreshToken: "refresh-token",
+		TokenType:    "Bearer",
+		Expiry:       time.Now().Add(-time.Hour),
+	})
+
+	persister := &recordingPersister{}
+	_, err := GetClient(context.Background(),
+		WithTokenPersister(persister),
+		withEndpoint(oauth2.Endpoint{TokenURL: tokenServer.URL}),
+	)
+	if err != nil {
+		t.Fatalf("GetClient() error = %v", err)
+	}
+
+	if diff := cmp.Diff(persister.saved, []string{"refreshed-access-token"}); diff != "" {
+		t.Errorf("persisted tokens mismatch (-got +want):\n%s", diff)
+	}
+
+	// The custom persister replaces the file store, so the saved token is untouched
+	token, err := LoadToken()
+	if err != nil {
+		t.Fatalf("LoadToken() error = %v", err)
+	}
+	if token.AccessToken != "expired-access-token" {
+		t.Errorf("on-disk AccessToken = %q, want unchanged expired-access-token", token.AccessToken)
+	}
+}
+
+func TestGetClient_DefaultPersisterSavesToDisk(t *testing.T) {
+	// Not parallel: GetClient reads credentials and token from the XDG dirs
+	configDir, dataDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	tokenServer, _ := newTestTokenServer(t, "refreshed-access-token")
+
+	createTestCredentials(t, configDir, Credentials{ClientID: "test-id", ClientSecret: "test-secret"})
+	createTestToken(t, dataDir, TokenStore{
+		AccessToken:  "expired-access-token",
+		RefreshToken: "refresh-token",
+		TokenType:    "Bearer",
+		Expiry:       time.Now().Add(-time.Hour),
+	})
+
+	if _, err := GetClient(context.Background(), withEndpoint(oauth2.Endpoint{TokenURL: tokenServer.URL})); err != nil {
+		t.Fatalf("GetClient() error = %v", err)
+	}
+
+	token, err := LoadToken()
+	if err != nil {
+		t.Fatalf("LoadToken() error = %v", err)
+	}
+	if token.AccessToken != "refreshed-access-token" {
+		t.Errorf("on-disk AccessToken = %q, want refreshed-access-token", token.AccessToken)
+	}
+}
+
+func TestCallbackHandler(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		opts         []AuthOption
+		query        string
+		reply        authResult // RunAuthFlow's reply to a received code
+		wantStatus   int
+		wantBody     string
+		wantLocation string
+		wantCode     string
+		wantAbort    bool // the flow is ended with an error
+	}{
+		{
+			name:       "default success page",
+			query:      "?code=abc&state=test-state",
+			wantStatus: http.StatusOK,
+			wantBody:   defaultSuccessHTML,
+			wantCode:   "abc",
+		},
+		{
+			name:       "default success page names account",
+			query:      "?code=abc&state=test-state",
+			reply:      authResult{email: "you+<cal>@example.com"},
+			wantStatus: http.StatusOK,
+			wantBody:   fmt.Sprintf(connectedSuccessHTML, "you+&lt;cal&gt;@example.com"),
+			wantCode:   "abc",
+		},
+		{
+			name:       "custom success page",
+			opts:       []AuthOption{WithSuccessHTML("<h1>Welcome to Acme</h1>")},
+			query:      "?code=abc&state=test-state",
+			reply:      authResult{email: "you@example.com"},
+			wantStatus: http.StatusOK,
+			wantBody:   "<h1>Welcome to Acme</h1>",
+			wantCode:   "abc",
+		},
+		{
+			name:       "failed exchange",
+			query:      "?code=abc&state=test-state",
+			reply:      authResult{err: errors.New("exchange code: invalid_grant")},
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   "Authorization failed\n",
+			wantCode:   "abc",
+		},
+		{
+			name:       "failed exchange with custom error page",
+			opts:       []AuthOption{WithErrorHTML("<h1>Acme sign-in failed</h1>")},
+			query:      "?code=abc&state=test-state",
+			reply:      authResult{err: errors.New("exchange code: invalid_grant")},
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   "<h1>Acme sign-in failed</h1>",
+			wantCode:   "abc",
+		},
+		{
+			name:         "redirect after success",
+			opts:         []AuthOption{WithSuccessHTML("<h1>unused</h1>"), WithAuthSuccessRedirect("https://example.com/welcome")},
+			query:        "?code=abc&state=test-state",
+			wantStatus:   http.StatusFound,
+			wantLocation: "https://example.com/welcome",
+			wantCode:     "abc",
+		},
+		{
+			name:       "error page shown despite redirect",
+			opts:       []AuthOption{WithAuthSuccessRedirect("https://example.com/welcome")},
+			query:      "?error=access_denied&state=test-state",
+			wantStatus: http.StatusBadRequest,
+			wantAbort:  true,
+			wantBody:   "No code received\n",
+		},
+		{
+			name:       "default error page",
+			query:      "?error=access_denied&state=test-state",
+			wantStatus: http.StatusBadRequest,
+			wantAbort:  true,
+			wantBody:   "No code received\n",
+		},
+		{
+			name:       "state mismatch",
+			query:      "?code=abc&state=forged",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "Invalid state\n",
+		},
+		{
+			name:       "missing state",
+			query:      "?code=abc",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "Invalid state\n",
+		},
+		{
+			name:       "error with mismatched state",
+			query:      "?error=access_denied&state=forged",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "Invalid state\n",
+		},
+		{
+			name:       "neither code nor error",
+			query:      "?state=test-state",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "No code received\n",
+		},
+		{
+			name:       "state mismatch with custom error page",
+			opts:       []AuthOption{WithErrorHTML("<h1>Acme sign-in failed</h1>")},
+			query:      "?code=abc&state=forged",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "<h1>Acme sign-in failed</h1>",
+		},
+		{
+			name:       "custom error page",
+			opts:       []AuthOption{WithErrorHTML("<h1>Acme sign-in failed</h1>")},
+			query:      "?error=access_denied&state=test-state",
+			wantStatus: http.StatusBadRequest,
+			wantAbort:  true,
+			wantBody:   "<h1>Acme sign-in failed</h1>",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt // Capture loop variable
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			codeChan := make(chan authCallback, 1)
+			errChan := make(chan error, 1)
+			handler := newAuthOptions(nil, tt.opts).callbackHandler("test-state", codeChan, errChan)
+
+			// Play RunAuthFlow's part, replying to the code
+			gotCode := make(chan string, 1)
+			done := make(chan struct{})
+			defer close(done)
+			go func() {
+				select {
+				case callback := <-codeChan:
+					gotCode <- callback.code
+					callback.reply <- tt.reply
+				case <-done:
+				}
+			}()
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/callback"+tt.query, nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("callback status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			// Redirect bodies are generated by net/http, so only check the header
+			if tt.wantLocation == "" {
+				if diff := cmp.Diff(rec.Body.String(), tt.wantBody); diff != "" {
+					t.Errorf("callback body mismatch (-got +want):\n%s", diff)
+				}
+			}
+			if got := rec.Header().Get("Location"); got != tt.wantLocation {
+				t.Errorf("callback Location = %q, want %q", got, tt.wantLocation)
+			}
+
+			if tt.wantCode == "" {
+				select {
+				case err := <-errChan:
+					if !tt.wantAbort {
+						t.Errorf("callback sent error %v, want the flow to keep waiting", err)
+					}
+				default:
+					if tt.wantAbort {
+						t.Error("callback sent no error")
+					}
+				}
+				return
+			}
+			// A redirect doesn't wait for the reply, so the code may still
+			// be in flight
+			select {
+			case code := <-gotCode:
+				if code != tt.wantCode {
+					t.Errorf("callback code = %q, want %q", code, tt.wantCode)
+				}
+			case err := <-errChan:
+				t.Errorf("callback error = %v, want code %q", err, tt.wantCode)
+			case <-time.After(time.Second):
+				t.Error("callback sent neither a code nor an error")
+			}
+		})
+	}
+}
+
+// freeTCPPort returns a port that was free a moment ago
+func freeTCPPort(t *testing.T) int {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to find a free port: %v", err)
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+func TestRunAuthFlow_ContextCancelled(t *testing.T) {
+	t.Parallel()
+
+	port := freeTCPPort(t)
+	creds := &Credentials{ClientID: "test-id", ClientSecret: "test-secret"}
+
+	// Cancel once the flow is waiting for the browser, before any callback
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	opener := withBrowserOpener(func(string) { cancel() })
+
+	done := make(chan error, 1)
+	go func() {
+		done <- RunAuthFlow(ctx, creds, port, opener, WithTokenPersister(nil))
+	}()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("RunAuthFlow() error = %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("RunAuthFlow() did not return promptly after cancellation")
+	}
+
+	// The callback server must be shut down, freeing the port
+	l, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		t.Fatalf("callback port still in use after cancellation: %v", err)
+	}
+	l.Close()
+}
+
+func TestRunAuthFlow_ParentDeadline(t *testing.T) {
+	t.Parallel()
+
+	port := freeTCPPort(t)
+	creds := &Credentials{ClientID: "test-id", ClientSecret: "test-secret"}
+
+	// A caller deadline shorter than the flow's own timeout wins
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := RunAuthFlow(ctx, creds, port, withBrowserOpener(func(string) {}), WithTokenPersister(nil))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("RunAuthFlow() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("RunAuthFlow() returned after %v, want soon after the caller's deadline", elapsed)
+	}
+
+	l, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		t.Fatalf("callback port still in use after the deadline: %v", err)
+	}
+	l.Close()
+}
+
+func TestRunAuthFlow_ShowsConnectedAccount(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		calendar http.HandlerFunc
+		wantBody string
+	}{
+		{
+			name: "primary calendar email shown",
+			calendar: func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/users/me/calendarList/primary" {
+					t.Errorf("unexpected path %s", r.URL.Path)
+				}
+				writeTestJSON(t, w, &calendar.CalendarListEntry{Id: "you@example.com", Primary: true})
+			},
+			wantBody: fmt.Sprintf(connectedSuccessHTML, "you@example.com"),
+		},
+		{
+			name: "lookup failure is best-effort",
+			calendar: func(w http.ResponseWriter, r *http.Request) {
+				writeTestAPIError(w, http.StatusForbidden, "insufficient scope")
+			},
+			wantBody: defaultSuccessHTML,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt // Capture loop variable
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tokenServer, _ := newTestTokenServer(t, "new-access-token")
+			calendarServer := httptest.NewServer(tt.calendar)
+			t.Cleanup(calendarServer.Close)
+
+			port := freeTCPPort(t)
+			creds := &Credentials{ClientID: "test-id", ClientSecret: "test-secret"}
+
+			// Complete the browser's side of the flow
+			page := make(chan string, 1)
+			opener := withBrowserOpener(func(authURL string) {
+				state := authURLParam(t, authURL, "state")
+				go func() {
+					resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/callback?code=abc&state=%s", port, url.QueryEscape(state)))
+					if err != nil {
+						t.Errorf("callback request failed: %v", err)
+						page <- ""
+						return
+					}
+					defer resp.Body.Close()
+					body, _ := io.ReadAll(resp.Body)
+					page <- string(body)
+				}()
+			})
+
+			err := RunAuthFlow(context.Background(), creds, port, opener,
+				withEndpoint(oauth2.Endpoint{TokenURL: tokenServer.URL}),
+				withCalendarEndpoint(calendarServer.URL+"/"),
+				WithTokenPersister(nil),
+			)
+			if err != nil {
+				t.Fatalf("RunAuthFlow() error = %v", err)
+			}
+
+			if diff := cmp.Diff(<-page, tt.wantBody); diff != "" {
+				t.Errorf("success page mismatch (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+// authURLParam returns the named query parameter of an auth URL
+func authURLParam(t *testing.T, authURL, name string) string {
+	t.Helper()
+
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Errorf("auth URL %q: %v", authURL, err)
+		return ""
+	}
+	return u.Query().Get(name)
+}
+
+func TestRunAuthFlow_PKCE(t *testing.T) {
+	t.Parallel()
+
+	verifiers := make(chan string, 1)
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		verifiers <- r.FormValue("code_verifier")
+		writeTestJSON(t, w, map[string]interface{}{
+			"access_token": "new-access-token",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+		})
+	}))
+	t.Cleanup(tokenServer.Close)
+
+	port := freeTCPPort(t)
+	creds := &Credentials{ClientID: "test-id", ClientSecret: "test-secret"}
+
+	var challenge, method string
+	var states []string
+	opener := withBrowserOpener(func(authURL string) {
+		challenge = authURLParam(t, authURL, "code_challenge")
+		method = authURLParam(t, authURL, "code_challenge_method")
+		state := authURLParam(t, authURL, "state")
+		states = append(states, state)
+		go func() {
+			resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/callback?code=abc&state=%s", port, url.QueryEscape(state)))
+			if err != nil {
+				t.Errorf("callback request failed: %v", err)
+				return
+			}
+			resp.Body.Close()
+		}()
+	})
+
+	err := RunAuthFlow(context.Background(), creds, port, opener,
+		withEndpoint(oauth2.Endpoint{TokenURL: tokenServer.URL}),
+		withCalendarEndpoint("http://127.0.0.1:0/"),
+		WithTokenPersister(nil),
+	)
+	if err != nil {
+		t.Fatalf("RunAuthFlow() error = %v", err)
+	}
+
+	if method != "S256" {
+		t.Errorf("code_challenge_method = %q, want S256", method)
+	}
+	// The exchange must send the verifier the challenge was derived from
+	verifier := <-verifiers
+	if verifier == "" {
+		t.Fatal("token exchange sent no code_verifier")
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	if want := base64.RawURLEncoding.EncodeToString(sum[:]); challenge != want {
+		t.Errorf("code_challenge = %q, want %q derived from the exchanged verifier", challenge, want)
+	}
+
+	// Each flow gets its own unguessable state
+	if err := RunAuthFlow(context.Background(), creds, port, opener,
+		withEndpoint(oauth2.Endpoint{TokenURL: tokenServer.URL}),
+		withCalendarEndpoint("http://127.0.0.1:0/"),
+		WithTokenPersister(nil),
+	); err != nil {
+		t.Fatalf("second RunAuthFlow() error = %v", err)
+	}
+	<-verifiers
+	if len(states) != 2 || states[0] == "" || states[0] == states[1] {
+		t.Errorf("auth URL states = %q, want two distinct random values", states)
+	}
+}
+
+func TestRunAuthFlow_StateMismatch(t *testing.T) {
+	t.Parallel()
+
+	tokenServer, requests := newTestTokenServer(t, "new-access-token")
+	port := freeTCPPort(t)
+	creds := &Credentials{ClientID: "test-id", ClientSecret: "test-secret"}
+
+	forgedStatus := make(chan int, 1)
+	opener := withBrowserOpener(func(authURL string) {
+		state := authURLParam(t, authURL, "state")
+		go func() {
+			// A stray request is refused, and must not end the flow
+			resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/callback?code=forged&state=forged", port))
+			if err != nil {
+				t.Errorf("forged callback request failed: %v", err)
+				forgedStatus <- 0
+				return
+			}
+			resp.Body.Close()
+			forgedStatus <- resp.StatusCode
+
+			resp, err = http.Get(fmt.Sprintf("http://127.0.0.1:%d/callback?code=abc&state=%s", port, url.QueryEscape(state)))
+			if err != nil {
+				t.Errorf("callback request failed: %v", err)
+				return
+			}
+			resp.Body.Close()
+		}()
+	})
+
+	err := RunAuthFlow(context.Background(), creds, port, opener,
+		withEndpoint(oauth2.Endpoint{TokenURL: tokenServer.URL}),
+		withCalendarEndpoint("http://127.0.0.1:0/"),
+		WithTokenPersister(nil),
+	)
+	if err != nil {
+		t.Fatalf("RunAuthFlow() error = %v, want the real callback to complete the flow", err)
+	}
+	if status := <-forgedStatus; status != http.StatusBadRequest {
+		t.Errorf("forged callback status = %d, want %d", status, http.StatusBadRequest)
+	}
+	if n := atomic.LoadInt32(requests); n != 1 {
+		t.Errorf("token endpoint called %d times, want 1", n)
+	}
+}
+
+func TestRunAuthFlow_AuthorizationDenied(t *testing.T) {
+	t.Parallel()
+
+	port := freeTCPPort(t)
+	creds := &Credentials{ClientID: "test-id", ClientSecret: "test-secret"}
+
+	opener := withBrowserOpener(func(authURL string) {
+		state := authURLParam(t, authURL, "state")
+		go func() {
+			resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/callback?error=access_denied&state=%s", port, url.QueryEscape(state)))
+			if err != nil {
+				t.Errorf("callback request failed: %v", err)
+				return
+			}
+			resp.Body.Close()
+		}()
+	})
+
+	err := RunAuthFlow(context.Background(), creds, port, opener, WithTokenPersister(nil))
+	if err == nil || !strings.Contains(err.Error(), "access_denied") {
+		t.Errorf("RunAuthFlow() error = %v, want access_denied", err)
+	}
+}
+
+func TestOAuthConfig_Scopes(t *testing.T) {
+	t.Parallel()
+	creds := &Credentials{ClientID: "test-client-id", ClientSecret: "test-client-secret"}
+
+	tests := []struct {
+		name string
+		opts []AuthOption
+		want []string
+	}{
+		{
+			name: "read-only by default",
+			want: []string{calendar.CalendarReadonlyScope},
+		},
+		{
+			name: "write access",
+			opts: []AuthOption{WithScopes(calendar.CalendarScope)},
+			want: []string{calendar.CalendarScope},
+		},
+		{
+			name: "multiple scopes",
+			opts: []AuthOption{WithScopes(calendar.CalendarEventsScope, calendar.CalendarReadonlyScope)},
+			want: []string{calendar.CalendarEventsScope, calendar.CalendarReadonlyScope},
+		},
+		{
+			name: "empty falls back to default",
+			opts: []AuthOption{WithScopes()},
+			want: []string{calendar.CalendarReadonlyScope},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt // Capture loop variable
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			config := newAuthOptions(nil, tt.opts).oauthConfig(creds, 8085)
+			if diff := cmp.Diff(config.Scopes, tt.want); diff != "" {
+				t.Errorf("oauthConfig() Scopes mismatch (-got +want):\n%s", diff)
+			}
+		})
+	}
+
+	// Callers can't modify the default through a returned config
+	getOAuthConfig(creds, 8085).Scopes[0] = "modified"
+	if DefaultScopes[0] != calendar.CalendarReadonlyScope {
+		t.Errorf("DefaultScopes modified through getOAuthConfig() to %v", DefaultScopes)
+	}
+}
+
+func TestPreflightAuth(t *testing.T) {
+	t.Parallel()
+	creds := &Credentials{ClientID: "test-id", ClientSecret: "test-secret"}
+
+	port := freeTCPPort(t)
+	uri, err := PreflightAuth(creds, port)
+	if err != nil {
+		t.Fatalf("PreflightAuth() error = %v", err)
+	}
+	if want := fmt.Sprintf("http://localhost:%d/callback", port); uri != want {
+		t.Errorf("PreflightAuth() redirect URI = %q, want %q", uri, want)
+	}
+
+	// Port 0 means the default port; it may be busy, but the URI is reported
+	uri, _ = PreflightAuth(creds, 0)
+	if want := fmt.Sprintf("http://localhost:%d/callback", DefaultCallbackPort); uri != want {
+		t.Errorf("PreflightAuth() with port 0 redirect URI = %q, want %q", uri, want)
+	}
+
+	// A port in use fails but still reports the URI to register
+	listener, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+	busy := listener.Addr().(*net.TCPAddr).Port
+
+	uri, err = PreflightAuth(creds, busy)
+	if err == nil {
+		t.Error("PreflightAuth() on a busy port error = nil, want error")
+	}
+	if want := fmt.Sprintf("http://localhost:%d/callback", busy); uri != want {
+		t.Errorf("PreflightAuth() redirect URI = %q, want %q", uri, want)
+	}
+
+	if _, err := PreflightAuth(&Credentials{ClientID: "test-id"}, port); err == nil {
+		t.Error("PreflightAuth() without a client secret error = nil, want error")
+	}
+}
+
+func TestRunAuthFlow_RequestsScopes(t *testing.T) {
+	t.Parallel()
+
+	port := freeTCPPort(t)
+	creds := &Credentials{ClientID: "test-id", ClientSecret: "test-secret"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var authURL string
+	opener := withBrowserOpener(func(url string) {
+		authURL = url
+		cancel()
+	})
+
+	err := RunAuthFlow(ctx, creds, port, opener, WithScopes(calendar.CalendarScope), WithTokenPersister(nil))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("RunAuthFlow() error = %v, want %v", err, context.Canceled)
+	}
+
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("auth URL %q: %v", authURL, err)
+	}
+	if got := u.Query().Get("scope"); got != calendar.CalendarScope {
+		t.Errorf("auth URL scope = %q, want %q", got, calendar.CalendarScope)
+	}
+}
+
+func TestRunAuthFlow_PortInUse(t *testing.T) {
+	t.Parallel()
+
+	listener, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+	busy := listener.Addr().(*net.TCPAddr).Port
+	creds := &Credentials{ClientID: "test-id", ClientSecret: "test-secret"}
+
+	// An explicit port isn't swapped for another
+	err = RunAuthFlow(context.Background(), creds, busy, withBrowserOpener(func(string) {}), WithTokenPersister(nil))
+	if !errors.Is(err, syscall.EADDRINUSE) {
+		t.Errorf("RunAuthFlow() error = %v, want %v", err, syscall.EADDRINUSE)
+	}
+	if err != nil && !strings.Contains(err.Error(), "pass a different port") {
+		t.Errorf("RunAuthFlow() error = %q, want a hint to pass a different port", err)
+	}
+}
+
+func TestRunAuthFlow_DefaultPortFallback(t *testing.T) {
+	// Not parallel: binds DefaultCallbackPort
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", DefaultCallbackPort))
+	if err == nil {
+		defer listener.Close()
+	}
+	creds := &Credentials{ClientID: "test-id", ClientSecret: "test-secret"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var authURL string
+	opener := withBrowserOpener(func(url string) {
+		authURL = url
+		cancel()
+	})
+
+	err = RunAuthFlow(ctx, creds, 0, opener, WithTokenPersister(nil))
+	if errors.Is(err, syscall.EADDRINUSE) {
+		// Every fallback port is taken too; the error must say so
+		if !strings.Contains(err.Error(), "all in use") {
+			t.Errorf("RunAuthFlow() error = %q, want it to report the ports in use", err)
+		}
+		return
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("RunAuthFlow() error = %v, want %v", err, context.Canceled)
+	}
+
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("auth URL %q: %v", authURL, err)
+	}
+	redirect, err := url.Parse(u.Query().Get("redirect_uri"))
+	if err != nil {
+		t.Fatalf("redirect URI: %v", err)
+	}
+	var port int
+	if _, err := fmt.Sscan(redirect.Port(), &port); err != nil {
+		t.Fatalf("redirect URI %q has no port: %v", redirect, err)
+	}
+	if port <= DefaultCallbackPort || port > DefaultCallbackPort+callbackPortFallbacks {
+		t.Errorf("redirect URI port = %d, want a fallback in %d-%d", port, DefaultCallbackPort+1, DefaultCallbackPort+callbackPortFallbacks)
+	}
+}
+
+func TestRunAuthFlow_EphemeralPort(t *testing.T) {
+	t.Parallel()
+
+	creds := &Credentials{ClientID: "test-id", ClientSecret: "test-secret"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var redirectURI string
+	var dialErr error
+	opener := withBrowserOpener(func(authURL string) {
+		defer cancel()
+		u, err := url.Parse(authURL)
+		if err != nil {
+			dialErr = err
+			return
+		}
+		redirectURI = u.Query().Get("redirect_uri")
+		redirect, err := url.Parse(redirectURI)
+		if err != nil {
+			dialErr = err
+			return
+		}
+
+		// The callback server must already be listening on that port
+		conn, err := net.Dial("tcp", redirect.Host)
+		if err != nil {
+			dialErr = err
+			return
+		}
+		conn.Close()
+	})
+
+	err := RunAuthFlow(ctx, creds, EphemeralPort, opener, WithTokenPersister(nil))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("RunAuthFlow() error = %v, want %v", err, context.Canceled)
+	}
+	if dialErr != nil {
+		t.Fatalf("redirect URI %q doesn't reach the callback server: %v", redirectURI, dialErr)
+	}
+	if strings.Contains(redirectURI, "localhost:0/") || strings.Contains(redirectURI, "localhost:-1/") {
+		t.Errorf("redirect URI = %q, want the bound port", redirectURI)
+	}
+
+	// The port isn't known ahead of the flow, so there's no URI to report
+	if uri, err := PreflightAuth(creds, EphemeralPort); err != nil || uri != "" {
+		t.Errorf("PreflightAuth(EphemeralPort) = %q, %v, want no URI and no error", uri, err)
+	}
+	if _, err := PreflightAuth(&Credentials{ClientID: "test-id"}, EphemeralPort); err == nil {
+		t.Error("PreflightAuth(EphemeralPort) without a client secret error = nil, want error")
+	}
+}
+
+func TestRunAuthFlow_NegativePort(t *testing.T) {
+	t.Parallel()
+	creds := &Credentials{ClientID: "test-id", ClientSecret: "test-secret"}
+	opener := withBrowserOpener(func(authURL string) {
+		t.Errorf("browser opened for %s", authURL)
+	})
+
+	for _, port := range []int{-2, -8085} {
+		err := RunAuthFlow(context.Background(), creds, port, opener, WithTokenPersister(nil))
+		if err == nil || !strings.HasPrefix(err.Error(), ErrInvalidConfig) {
+			t.Errorf("RunAuthFlow() with port %d error = %v, want %s", port, err, ErrInvalidConfig)
+		}
+		if _, err := PreflightAuth(creds, port); err == nil || !strings.HasPrefix(err.Error(), ErrInvalidConfig) {
+			t.Errorf("PreflightAuth() with port %d error = %v, want %s", port, err, ErrInvalidConfig)
+		}
+	}
+}
+
+func TestNewCallbackServer_Timeouts(t *testing.T) {
+	t.Parallel()
+
+	server := newCallbackServer(http.NotFoundHandler())
+
+	timeouts := map[string]time.Duration{
+		"ReadHeaderTimeout": server.ReadHeaderTimeout,
+		"ReadTimeout":       server.ReadTimeout,
+		"WriteTimeout":      server.WriteTimeout,
+		"IdleTimeout":       server.IdleTimeout,
+	}
+	for name, d := range timeouts {
+		if d <= 0 {
+			t.Errorf("callback server %s = %v, want > 0", name, d)
+		}
+	}
+	if server.ReadHeaderTimeout > server.ReadTimeout {
+		t.Errorf("ReadHeaderTimeout %v exceeds ReadTimeout %v", server.ReadHeaderTimeout, server.ReadTimeout)
+	}
+}
+
+func TestPrintToken_ImportToken_RoundTrip(t *testing.T) {
+	// Not parallel: sets GCAL_TOKEN_PASSPHRASE and the XDG dirs
+	_, _, cleanup := setupTestEnv(t)
+	defer cleanup()
+	t.Setenv(TokenPassphraseEnv, "exporting machine")
+
+	want := TokenStore{
+		AccessToken:  "access",
+		RefreshToken: "refresh",
+		TokenType:    "Bearer",
+		Expiry:       time.Date(2024, 1, 15, 14, 0, 0, 0, time.UTC),
+		Scopes:       []string{calendar.CalendarReadonlyScope},
+	}
+	if err := SaveToken(want.oauthToken()); err != nil {
+		t.Fatalf("SaveToken() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := PrintToken(&buf); err != nil {
+		t.Fatalf("PrintToken() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), `"refresh"`) {
+		t.Errorf("PrintToken() should write plain JSON even when encrypted, got:\n%s", buf.String())
+	}
+
+	// Import on a fresh "machine" with no passphrase
+	_, _, cleanup2 := setupTestEnv(t)
+	defer cleanup2()
+	t.Setenv(TokenPassphraseEnv, "")
+
+	if err := ImportToken(&buf); err != nil {
+		t.Fatalf("ImportToken() error = %v", err)
+	}
+	got, err := loadTokenStore(tokenFile)
+	if err != nil || got == nil {
+		t.Fatalf("loadTokenStore() = %v, %v; want imported token", got, err)
+	}
+	if diff := cmp.Diff(*got, want); diff != "" {
+		t.Errorf("imported token mismatch (-got +want):\n%s", diff)
+	}
+}
+
+func TestPrintToken_NoToken(t *testing.T) {
+	// Not parallel: sets the XDG dirs
+	_, _, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	var buf bytes.Buffer
+	err := PrintToken(&buf)
+	if err == nil || !strings.Contains(err.Error(), ErrNotConfigured) {
+		t.Errorf("PrintToken() error = %v, want %s", err, ErrNotConfigured)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("PrintToken() wrote %q, want nothing", buf.String())
+	}
+}
+
+func TestImportToken_Invalid(t *testing.T) {
+	// Not parallel: sets the XDG dirs
+	_, dataDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	for _, input := range []string{"", "not json", `{"tokenType": "Bearer"}`} {
+		if err := ImportToken(strings.NewReader(input)); err == nil {
+			t.Errorf("ImportToken(%q) error = nil, want error", input)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dataDir, tokenFile)); !os.IsNotExist(err) {
+		t.Errorf("ImportToken() wrote a token file for invalid input")
+	}
+}
@@ -0,0 +1,110 @@
+// Package gcal provides named token profiles and token revocation.
+package gcal
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"golang.org/x/oauth2"
+)
+
+// googleRevokeURL is Google's OAuth token revocation endpoint
+const googleRevokeURL = "https://oauth2.googleapis.com/revoke"
+
+// profileNamePattern restricts profile names to safe file name characters
+var profileNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// profileTokenFile returns the data dir file holding a profile's token
+func profileTokenFile(name string) (string, error) {
+	if !profileNamePattern.MatchString(name) {
+		return "", fmt.Errorf("invalid profile name %q: use letters, digits, '-' or '_'", name)
+	}
+	return "gcal-tokens-" + name + ".json", nil
+}
+
+// LoadTokenForProfile loads the token saved for the named profile, returning
+// nil if the profile has no token
+func LoadTokenForProfile(name string) (*oauth2.Token, error) {
+	file, err := profileTokenFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return loadTokenFile(file)
+}
+
+// SaveTokenForProfile saves a token for the named profile, separately from
+// the default token and other profiles
+func SaveTokenForProfile(name string, token *oauth2.Token) error {
+	file, err := profileTokenFile(name)
+	if err != nil {
+		return err
+	}
+	return saveTokenFile(file, token)
+}
+
+// RevokeToken revokes the default token with Google and deletes it. Profile
+// tokens are left intact. It's not an error if no token is saved.
+func RevokeToken(ctx context.Context) error {
+	return revokeTokenFile(ctx, http.DefaultClient, googleRevokeURL, tokenFile)
+}
+
+// RevokeTokenForProfile revokes the named profile's token with Google and
+// deletes it, leaving the default token and other profiles intact. It's not
+// an error if the profile has no token.
+func RevokeTokenForProfile(ctx context.Context, name string) error {
+	file, err := profileTokenFile(name)
+	if err != nil {
+		return err
+	}
+	return revokeTokenFile(ctx, http.DefaultClient, googleRevokeURL, file)
+}
+
+// revokeTokenFile revokes the token stored in file at revokeURL, then
+// deletes the file. A token Google no longer recognizes is treated as
+// already revoked, so the local copy is still removed.
+func revokeTokenFile(ctx context.Context, client *http.Client, revokeURL, file string) error {
+	token, err := loadTokenFile(file)
+	if err != nil {
+		return err
+	}
+	if token == nil {
+		return nil
+	}
+
+	// Revoking the refresh token also invalidates its access tokens
+	value := token.RefreshToken
+	if value == "" {
+		value = token.AccessToken
+	}
+
+	form := url.Values{"token": {value}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, revokeURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("revoke token: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s: revoke token: %w", ErrNetworkError, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusBadRequest {
+		return fmt.Errorf("%s: revoke token: unexpected status %s", ErrAPIError, resp.Status)
+	}
+
+	dataDir, err := getDataDir()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(filepath.Join(dataDir, file)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("delete token: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package gcal
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/oauth2"
+)
+
+// newTestRevokeServer starts a revocation endpoint that records the tokens
+// it receives and answers with status
+func newTestRevokeServer(t *testing.T, status int) (*httptest.Server, func() []string) {
+	t.Helper()
+
+	var mu sync.Mutex
+	var revoked []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("Failed to parse revoke request: %v", err)
+		}
+		mu.Lock()
+		revoked = append(revoked, r.PostForm.Get("token"))
+		mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), revoked...)
+	}
+}
+
+func TestRevokeTokenForProfile(t *testing.T) {
+	// Not parallel: profiles are stored in the XDG data dir
+	_, _, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	for _, name := range []string{"work", "personal"} {
+		if err := SaveTokenForProfile(name, &oauth2.Token{AccessToken: name + "-access", RefreshToken: name + "-refresh"}); err != nil {
+			t.Fatalf("SaveTokenForProfile(%q) error = %v", name, err)
+		}
+	}
+	if err := SaveToken(&oauth2.Token{AccessToken: "default-access", RefreshToken: "default-refresh"}); err != nil {
+		t.Fatalf("SaveToken() error = %v", err)
+	}
+
+	server, revoked := newTestRevokeServer(t, http.StatusOK)
+	file, err := profileTokenFile("work")
+	if err != nil {
+		t.Fatalf("profileTokenFile() error = %v", err)
+	}
+	if err := revokeTokenFile(context.Background(), server.Client(), server.URL, file); err != nil {
+		t.Fatalf("revokeTokenFile() error = %v", err)
+	}
+
+	if diff := cmp.Diff(revoked(), []string{"work-refresh"}); diff != "" {
+		t.Errorf("revoked tokens mismatch (-got +want):\n%s", diff)
+	}
+
+	if token, err := LoadTokenForProfile("work"); err != nil || token != nil {
+		t.Errorf("LoadTokenForProfile(work) = %v, %v, want nil token after revoke", token, err)
+	}
+	if token, err := LoadTokenForProfile("personal"); err != nil || token == nil || token.AccessToken != "personal-access" {
+		t.Errorf("LoadTokenForProfile(personal) = %v, %v, want untouched token", token, err)
+	}
+	if token, err := LoadToken(); err != nil || token == nil || token.AccessToken != "default-access" {
+		t.Errorf("LoadToken() = %v, %v, want untouched default token", token, err)
+	}
+}
+
+func TestRevokeTokenFile_AlreadyRevoked(t *testing.T) {
+	// Not parallel: profiles are stored in the XDG data dir
+	_, _, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	if err := SaveTokenForProfile("old", &oauth2.Token{AccessToken: "stale"}); err != nil {
+		t.Fatalf("SaveTokenForProfile() error = %v", err)
+	}
+
+	// Google answers 400 for tokens it no longer recognizes
+	server, revoked := newTestRevokeServer(t, http.StatusBadRequest)
+	file, _ := profileTokenFile("old")
+	if err := revokeTokenFile(context.Background(), server.Client(), server.URL, file); err != nil {
+		t.Fatalf("revokeTokenFile() error = %v", err)
+	}
+
+	if diff := cmp.Diff(revoked(), []string{"stale"}); diff != "" {
+		t.Errorf("revoked tokens mismatch (-got +want):\n%s", diff)
+	}
+	if token, _ := LoadTokenForProfile("old"); token != nil {
+		t.Error("LoadTokenForProfile() after revoke returned a token, want nil")
+	}
+
+	// Revoking again is a no-op
+	if err := revokeTokenFile(context.Background(), server.Client(), server.URL, file); err != nil {
+		t.Errorf("revokeTokenFile() on missing token error = %v", err)
+	}
+	if got := len(revoked()); got != 1 {
+		t.Errorf("revoke endpoint called %d times, want 1", got)
+	}
+}
+
+func TestProfileTokenFile_InvalidName(t *testing.T) {
+	t.Parallel()
+
+	for _, name := range []string{"", "../escape", "a/b", "with space"} {
+		if _, err := profileTokenFile(name); err == nil {
+			t.Errorf("profileTokenFile(%q) error = nil, want error", name)
+		}
+	}
+}
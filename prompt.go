@@ -0,0 +1,93 @@
+// Package gcal provides compact event summaries for shell prompts.
+package gcal
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// nextMeetingWindow is how far ahead NextMeetingLine looks for a meeting
+const nextMeetingWindow = 24 * time.Hour
+
+// maxPromptTitleLength caps the title in NextMeetingLine, in characters,
+// so the line fits in a prompt
+const maxPromptTitleLength = 30
+
+// NextMeetingLine returns the next meeting line using the default client.
+// See Client.NextMeetingLine.
+func NextMeetingLine(ctx context.Context, calendarIDs []string) (string, error) {
+	c, code, err := newDefaultClient(ctx)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", code, err)
+	}
+	return c.NextMeetingLine(ctx, calendarIDs)
+}
+
+// NextMeetingLine returns a one-line summary of the next meeting starting
+// within 24 hours, such as "09:30 Standup (in 12m)", for shell prompts.
+// The start time is shown in the configured timezone and long titles are
+// shortened. Returns "" if no meeting is coming up.
+func (c *Client) NextMeetingLine(ctx context.Context, calendarIDs []string) (string, error) {
+	return c.nextMeetingLine(ctx, calendarIDs, time.Now())
+}
+
+// nextMeetingLine is NextMeetingLine with an injectable clock
+func (c *Client) nextMeetingLine(ctx context.Context, calendarIDs []string, now time.Time) (string, error) {
+	resp := c.fetchEvents(ctx, calendarIDs, now, now.Add(nextMeetingWindow))
+	if !resp.Success {
+		return "", fmt.Errorf("%s: %s", resp.Error, resp.Message)
+	}
+
+	next := GetNextEvent(resp.Events, now, 0)
+	if next == nil {
+		return "", nil
+	}
+	start, err := time.Parse(time.RFC3339, next.Start)
+	if err != nil {
+		return "", nil
+	}
+	return formatMeetingLine(start.In(c.Options.location()), next.Title, start.Sub(now)), nil
+}
+
+// formatMeetingLine renders "15:04 Title (in 1h5m)" in a single allocation
+// for the common case
+func formatMeetingLine(start time.Time, title string, until time.Duration) string {
+	if utf8.RuneCountInString(title) > maxPromptTitleLength {
+		cut, n := 0, 0
+		for i := range title {
+			if n == maxPromptTitleLength-1 {
+				cut = i
+				break
+			}
+			n++
+		}
+		title = title[:cut] + "…"
+	}
+
+	b := make([]byte, 0, len("15:04 ")+len(title)+len(" (in 00h00m)"))
+	b = start.AppendFormat(b, "15:04")
+	b = append(b, ' ')
+	b = append(b, title...)
+
+	minutes := int(until / time.Minute)
+	if minutes <= 0 {
+		b = append(b, " (now)"...)
+		return string(b)
+	}
+	b = append(b, " (in "...)
+	if hours := minutes / 60; hours > 0 {
+		b = strconv.AppendInt(b, int64(hours), 10)
+		b = append(b, 'h')
+		minutes %= 60
+		if minutes == 0 {
+			b = append(b, ')')
+			return string(b)
+		}
+	}
+	b = strconv.AppendInt(b, int64(minutes), 10)
+	b = append(b, "m)"...)
+	return string(b)
+}
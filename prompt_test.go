@@ -0,0 +1,103 @@
+package gcal
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+func TestNextMeetingLine(t *testing.T) {
+	t.Parallel()
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("LoadLocation() error = %v", err)
+	}
+	at := func(hour, min int) time.Time {
+		return time.Date(2024, 1, 15, hour, min, 0, 0, ny)
+	}
+	now := at(9, 18)
+
+	tests := []struct {
+		name   string
+		events []*calendar.Event
+		want   string
+	}{
+		{
+			name: "upcoming meeting",
+			events: []*calendar.Event{
+				// Listed in UTC, shown in the configured timezone
+				testAcceptedEvent("standup", at(9, 30).UTC(), at(9, 45).UTC()),
+				testAcceptedEvent("later", at(11, 0), at(12, 0)),
+			},
+			want: "09:30 standup (in 12m)",
+		},
+		{
+			name: "meeting in progress is skipped",
+			events: []*calendar.Event{
+				testAcceptedEvent("design", at(9, 0), at(10, 0)),
+				testAcceptedEvent("sync", at(10, 23), at(11, 0)),
+			},
+			want: "10:23 sync (in 1h5m)",
+		},
+		{
+			name: "whole hours",
+			events: []*calendar.Event{
+				testAcceptedEvent("review", at(11, 18), at(12, 0)),
+			},
+			want: "11:18 review (in 2h)",
+		},
+		{
+			name: "starting now",
+			events: []*calendar.Event{
+				testAcceptedEvent("retro", now, at(10, 0)),
+			},
+			want: "09:18 retro (now)",
+		},
+		{
+			name: "long title shortened",
+			events: []*calendar.Event{
+				testAcceptedEvent("quarterly-planning-with-the-whole-org", at(9, 30), at(10, 0)),
+			},
+			want: "09:30 quarterly-planning-with-the-w… (in 12m)",
+		},
+		{
+			name: "empty schedule",
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt // Capture loop variable
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				writeTestJSON(t, w, &calendar.Events{Items: tt.events})
+			}))
+			client.Options.Location = ny
+
+			got, err := client.nextMeetingLine(context.Background(), nil, now)
+			if err != nil {
+				t.Fatalf("nextMeetingLine() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("nextMeetingLine() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextMeetingLine_FetchError(t *testing.T) {
+	t.Parallel()
+
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeTestAPIError(w, http.StatusForbidden, "forbidden")
+	}))
+
+	if _, err := client.nextMeetingLine(context.Background(), nil, time.Now()); err == nil {
+		t.Error("nextMeetingLine() error = nil, want error")
+	}
+}
@@ -0,0 +1,116 @@
+// Package gcal provides retry handling for transient Calendar API failures.
+package gcal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"google.golang.org/api/googleapi"
+)
+
+// defaultRetryBackoff is the wait before the first retry when
+// RetryPolicy.Backoff is unset
+const defaultRetryBackoff = 200 * time.Millisecond
+
+// RetryPolicy controls retries of transient API failures (rate limits and
+// server errors). The budget is shared by every call in a single fetch, so
+// fetching many calendars can't multiply retries into a long total latency.
+// The zero value disables retries.
+type RetryPolicy struct {
+	// MaxRetries is the total number of retries allowed across one fetch
+	MaxRetries int
+
+	// MaxElapsed stops further retries once this much time has passed since
+	// the fetch began. Zero means no time limit.
+	MaxElapsed time.Duration
+
+	// Backoff is the wait before the first retry, doubling for each retry of
+	// the same call. Defaults to 200ms.
+	Backoff time.Duration
+}
+
+// retryBudget tracks the retries and time left for one fetch operation.
+// It is safe for concurrent use.
+type retryBudget struct {
+	backoff time.Duration
+
+	mu       sync.Mutex
+	retries  int
+	deadline time.Time // zero means no time limit
+}
+
+// newBudget starts a retry budget for a fetch beginning now
+func (p RetryPolicy) newBudget(now time.Time) *retryBudget {
+	b := &retryBudget{
+		backoff: p.Backoff,
+		retries: p.MaxRetries,
+	}
+	if b.backoff <= 0 {
+		b.backoff = defaultRetryBackoff
+	}
+	if p.MaxElapsed > 0 {
+		b.deadline = now.Add(p.MaxElapsed)
+	}
+	return b
+}
+
+// take consumes one retry, reporting false once the budget is spent
+func (b *retryBudget) take(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.retries <= 0 {
+		return false
+	}
+	if !b.deadline.IsZero() && !now.Before(b.deadline) {
+		b.retries = 0
+		return false
+	}
+	b.retries--
+	return true
+}
+
+// wait returns how long to sleep before the given retry (0-based), never
+// running past the budget's deadline
+func (b *retryBudget) wait(retry int, now time.Time) time.Duration {
+	d := b.backoff << retry
+	if !b.deadline.IsZero() {
+		if remaining := b.deadline.Sub(now); d > remaining {
+			d = remaining
+		}
+	}
+	return d
+}
+
+// do calls fn, retrying transient failures while the budget allows. If ctx
+// ends during a backoff, the error wraps both ctx's error and the last
+// failure.
+func (b *retryBudget) do(ctx context.Context, fn func() error) error {
+	for retry := 0; ; retry++ {
+		err := fn()
+		if err == nil || !isRetryable(err) || !b.take(time.Now()) {
+			return err
+		}
+
+		timer := time.NewTimer(b.wait(retry, time.Now()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%w while retrying: %w", ctx.Err(), err)
+		case <-timer.C:
+		}
+	}
+}
+
+// isRetryable reports whether err is a rate limit or server error
+func isRetryable(err error) bool {
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Code == http.StatusTooManyRequests || apiErr.Code >= http.StatusInternalServerError
+	}
+	return false
+}
@@ -0,0 +1,147 @@
+package gcal
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/googleapi"
+)
+
+func TestFetchEvents_RetryBudgetCapsAttempts(t *testing.T) {
+	t.Parallel()
+	start := time.Date(2024, 1, 15, 14, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		calendars    []string
+		policy       RetryPolicy
+		wantAttempts int32
+		wantEvents   int
+		wantSuccess  bool
+	}{
+		{
+			name:         "no retries by default",
+			calendars:    []string{"slow1", "slow2", "slow3"},
+			policy:       RetryPolicy{},
+			wantAttempts: 3,
+		},
+		{
+			name:         "budget shared across calendars",
+			calendars:    []string{"slow1", "slow2", "slow3"},
+			policy:       RetryPolicy{MaxRetries: 2, Backoff: time.Millisecond},
+			wantAttempts: 5,
+		},
+		{
+			name:         "partial results once budget is spent",
+			calendars:    []string{"ok", "slow1", "slow2"},
+			policy:       RetryPolicy{MaxRetries: 1, Backoff: time.Millisecond},
+			wantAttempts: 4,
+			wantEvents:   1,
+			wantSuccess:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt // Capture loop variable
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var attempts int32
+			client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&attempts, 1)
+				if strings.HasPrefix(r.URL.Path, "/calendars/ok/") {
+					writeTestJSON(t, w, &calendar.Events{
+						Items: []*calendar.Event{testAcceptedEvent("event1", start, start.Add(time.Hour))},
+					})
+					return
+				}
+				writeTestAPIError(w, http.StatusServiceUnavailable, "Backend Error")
+			}))
+			client.Retry = tt.policy
+
+			got := client.FetchUpcomingEvents(context.Background(), tt.calendars, 24)
+
+			if n := atomic.LoadInt32(&attempts); n != tt.wantAttempts {
+				t.Errorf("API attempts = %d, want %d", n, tt.wantAttempts)
+			}
+			if got.Success != tt.wantSuccess {
+				t.Errorf("FetchUpcomingEvents() Success = %v, want %v (%s)", got.Success, tt.wantSuccess, got.Message)
+			}
+			if len(got.Events) != tt.wantEvents {
+				t.Errorf("FetchUpcomingEvents() returned %d events, want %d", len(got.Events), tt.wantEvents)
+			}
+		})
+	}
+}
+
+func TestFetchEvents_RetryTimeBudget(t *testing.T) {
+	t.Parallel()
+
+	var attempts int32
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		time.Sleep(30 * time.Millisecond)
+		writeTestAPIError(w, http.StatusServiceUnavailable, "Backend Error")
+	}))
+	client.Retry = RetryPolicy{
+		MaxRetries: 100,
+		MaxElapsed: 50 * time.Millisecond,
+		Backoff:    time.Millisecond,
+	}
+	// One calendar at a time, so attempts don't overlap and the count
+	// below holds
+	client.FetchConcurrency = 1
+
+	got := client.FetchUpcomingEvents(context.Background(), []string{"slow1", "slow2", "slow3"}, 24)
+	if got.Success {
+		t.Fatal("FetchUpcomingEvents() Success = true, want false")
+	}
+
+	// Each attempt takes at least 30ms, so a 50ms budget allows at most two
+	// retries in total on top of one attempt per calendar
+	if n := atomic.LoadInt32(&attempts); n > 5 {
+		t.Errorf("API attempts = %d, want at most 5 with a 50ms budget", n)
+	}
+}
+
+func TestFetchEvents_DoesNotRetryClientErrors(t *testing.T) {
+	t.Parallel()
+
+	var attempts int32
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		writeTestAPIError(w, http.StatusForbidden, "Forbidden")
+	}))
+	client.Retry = RetryPolicy{MaxRetries: 5, Backoff: time.Millisecond}
+
+	client.FetchUpcomingEvents(context.Background(), []string{"cal1"}, 24)
+
+	if n := atomic.LoadInt32(&attempts); n != 1 {
+		t.Errorf("API attempts = %d, want 1 for a non-retryable error", n)
+	}
+}
+
+func TestRetryBudget_CancelledDuringBackoff(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	budget := RetryPolicy{MaxRetries: 5, Backoff: time.Hour}.newBudget(time.Now())
+	err := budget.do(ctx, func() error {
+		cancel()
+		return &googleapi.Error{Code: http.StatusServiceUnavailable}
+	})
+
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("do() error = %v, want %v", err, context.Canceled)
+	}
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) || apiErr.Code != http.StatusServiceUnavailable {
+		t.Errorf("do() error = %v, want it to wrap the last API error", err)
+	}
+}
@@ -0,0 +1,123 @@
+// Package gcal provides checks of the OAuth scopes granted to the saved token.
+package gcal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/oauth2"
+	"google.golang.org/api/calendar/v3"
+)
+
+// googleTokenInfoURL is Google's endpoint describing an access token
+const googleTokenInfoURL = "https://oauth2.googleapis.com/tokeninfo"
+
+// tokenScopes returns the scopes granted to token, from the space-separated
+// "scope" field of the token response
+func tokenScopes(token *oauth2.Token) []string {
+	scope, _ := token.Extra("scope").(string)
+	return strings.Fields(scope)
+}
+
+// scopeGranted reports whether granted includes scope. The full calendar
+// scope also covers the narrower calendar scopes, such as read-only or
+// events-only access.
+func scopeGranted(granted []string, scope string) bool {
+	for _, g := range granted {
+		if g == scope {
+			return true
+		}
+		if g == calendar.CalendarScope && strings.HasPrefix(scope, calendar.CalendarScope+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// TokenHasScope reports whether the saved token was granted scope, so write
+// operations can fail fast with a reauth message instead of a 403. Scopes
+// recorded when the token was saved are used when present; older tokens are
+// checked with Google's tokeninfo endpoint, which needs an unexpired access
+// token.
+func TokenHasScope(scope string) (bool, error) {
+	return tokenHasScope(context.Background(), http.DefaultClient, googleTokenInfoURL, scope)
+}
+
+// IsConfiguredForScopes checks that credentials and a token are available
+// and that the token was granted every one of scopes, e.g.
+// calendar.CalendarEventsScope for a build that creates events. Only the
+// scopes recorded when the token was saved are consulted, so a token saved
+// before scopes were recorded doesn't qualify for any scope; re-run
+// 'gcal auth' to record them. With no scopes it is IsConfigured.
+func IsConfiguredForScopes(scopes []string) bool {
+	creds, err := LoadCredentials()
+	if err != nil || creds == nil {
+		return false
+	}
+	token, err := LoadToken()
+	if err != nil || token == nil {
+		return false
+	}
+
+	granted := tokenScopes(token)
+	for _, scope := range scopes {
+		if !scopeGranted(granted, scope) {
+			return false
+		}
+	}
+	return true
+}
+
+// tokenHasScope is TokenHasScope with an injectable tokeninfo endpoint
+func tokenHasScope(ctx context.Context, client *http.Client, tokenInfoURL, scope string) (bool, error) {
+	store, err := loadTokenStore(tokenFile)
+	if err != nil {
+		return false, fmt.Errorf("load token: %w", err)
+	}
+	if store == nil {
+		return false, fmt.Errorf("%s: no token found - run 'gcal auth' first", ErrNotConfigured)
+	}
+
+	if len(store.Scopes) > 0 {
+		return scopeGranted(store.Scopes, scope), nil
+	}
+
+	granted, err := fetchTokenInfoScopes(ctx, client, tokenInfoURL, store.AccessToken)
+	if err != nil {
+		return false, err
+	}
+	return scopeGranted(granted, scope), nil
+}
+
+// fetchTokenInfoScopes asks the tokeninfo endpoint which scopes an access
+// token carries
+func fetchTokenInfoScopes(ctx context.Context, client *http.Client, tokenInfoURL, accessToken string) ([]string, error) {
+	u := tokenInfoURL + "?" + url.Values{"access_token": {accessToken}}.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("tokeninfo: %w", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s: tokeninfo: %w", ErrNetworkError, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		// Google answers 400 for expired or revoked access tokens
+		return nil, fmt.Errorf("%s: tokeninfo: unexpected status %s - run 'gcal auth' to re-authorize", ErrTokenExpired, resp.Status)
+	}
+
+	var info struct {
+		Scope string `json:"scope"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return nil, fmt.Errorf("tokeninfo: parse response: %w", err)
+	}
+	return strings.Fields(info.Scope), nil
+}
@@ -0,0 +1,221 @@
+package gcal
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/oauth2"
+	"google.golang.org/api/calendar/v3"
+)
+
+func TestTokenHasScope_StoredScopes(t *testing.T) {
+	tests := []struct {
+		name    string
+		granted []string
+		scope   string
+		want    bool
+	}{
+		{
+			name:    "read-only token lacks write scope",
+			granted: []string{calendar.CalendarReadonlyScope},
+			scope:   calendar.CalendarEventsScope,
+			want:    false,
+		},
+		{
+			name:    "token with write scope",
+			granted: []string{calendar.CalendarReadonlyScope, calendar.CalendarEventsScope},
+			scope:   calendar.CalendarEventsScope,
+			want:    true,
+		},
+		{
+			name:    "full calendar scope covers narrower scopes",
+			granted: []string{calendar.CalendarScope},
+			scope:   calendar.CalendarEventsScope,
+			want:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Not parallel: reads the token from the XDG data dir
+			_, dataDir, cleanup := setupTestEnv(t)
+			defer cleanup()
+			createTestToken(t, dataDir, TokenStore{AccessToken: "access", Scopes: tt.granted})
+
+			// Stored scopes must answer without calling tokeninfo
+			got, err := tokenHasScope(context.Background(), http.DefaultClient, "http://127.0.0.1:0/unreachable", tt.scope)
+			if err != nil {
+				t.Fatalf("tokenHasScope() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("tokenHasScope(%q) = %v, want %v", tt.scope, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsConfiguredForScopes(t *testing.T) {
+	writeScopes := []string{calendar.CalendarEventsScope}
+
+	tests := []struct {
+		name    string
+		granted []string
+		scopes  []string
+		want    bool
+	}{
+		{
+			name:    "read-only token against write scopes",
+			granted: []string{calendar.CalendarReadonlyScope},
+			scopes:  writeScopes,
+			want:    false,
+		},
+		{
+			name:    "read-only token against read-only scope",
+			granted: []string{calendar.CalendarReadonlyScope},
+			scopes:  []string{calendar.CalendarReadonlyScope},
+			want:    true,
+		},
+		{
+			name:    "token with write scope",
+			granted: []string{calendar.CalendarReadonlyScope, calendar.CalendarEventsScope},
+			scopes:  writeScopes,
+			want:    true,
+		},
+		{
+			name:    "every scope must be granted",
+			granted: []string{calendar.CalendarEventsScope},
+			scopes:  []string{calendar.CalendarEventsScope, calendar.CalendarSettingsReadonlyScope},
+			want:    false,
+		},
+		{
+			name:    "no scopes recorded",
+			granted: nil,
+			scopes:  writeScopes,
+			want:    false,
+		},
+		{
+			name:    "no scopes required",
+			granted: nil,
+			scopes:  nil,
+			want:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Not parallel: reads credentials and token from the XDG dirs
+			configDir, dataDir, cleanup := setupTestEnv(t)
+			defer cleanup()
+			createTestCredentials(t, configDir, Credentials{ClientID: "test-id", ClientSecret: "test-secret"})
+			createTestToken(t, dataDir, TokenStore{AccessToken: "access", RefreshToken: "refresh", Scopes: tt.granted})
+
+			if got := IsConfiguredForScopes(tt.scopes); got != tt.want {
+				t.Errorf("IsConfiguredForScopes(%q) = %v, want %v", tt.scopes, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenHasScope_TokenInfoFallback(t *testing.T) {
+	// Not parallel: reads the token from the XDG data dir
+	_, dataDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+	createTestToken(t, dataDir, TokenStore{AccessToken: "legacy-access"})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("access_token"); got != "legacy-access" {
+			t.Errorf("tokeninfo access_token = %q, want legacy-access", got)
+		}
+		writeTestJSON(t, w, map[string]string{"scope": calendar.CalendarReadonlyScope})
+	}))
+	defer server.Close()
+
+	for scope, want := range map[string]bool{
+		calendar.CalendarReadonlyScope: true,
+		calendar.CalendarEventsScope:   false,
+	} {
+		got, err := tokenHasScope(context.Background(), server.Client(), server.URL, scope)
+		if err != nil {
+			t.Fatalf("tokenHasScope() error = %v", err)
+		}
+		if got != want {
+			t.Errorf("tokenHasScope(%q) = %v, want %v", scope, got, want)
+		}
+	}
+}
+
+func TestTokenHasScope_NoToken(t *testing.T) {
+	// Not parallel: reads the token from the XDG data dir
+	_, _, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	if _, err := TokenHasScope(calendar.CalendarEventsScope); err == nil {
+		t.Error("TokenHasScope() without a token error = nil, want error")
+	}
+}
+
+func TestSaveToken_KeepsScopesAcrossRefresh(t *testing.T) {
+	// Not parallel: writes the token to the XDG data dir
+	_, _, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	granted := (&oauth2.Token{AccessToken: "first"}).WithExtra(map[string]interface{}{
+		"scope": calendar.CalendarEventsScope,
+	})
+	if err := SaveToken(granted); err != nil {
+		t.Fatalf("SaveToken() error = %v", err)
+	}
+	// Refresh responses may not repeat the scope
+	if err := SaveToken(&oauth2.Token{AccessToken: "refreshed"}); err != nil {
+		t.Fatalf("SaveToken() error = %v", err)
+	}
+
+	got, err := TokenHasScope(calendar.CalendarEventsScope)
+	if err != nil {
+		t.Fatalf("TokenHasScope() error = %v", err)
+	}
+	if !got {
+		t.Error("TokenHasScope() = false after refresh, want scopes kept")
+	}
+}
+
+func TestTokenScopes_RoundTrip(t *testing.T) {
+	// Not parallel: writes the token to the XDG data dir
+	_, _, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	want := []string{calendar.CalendarReadonlyScope, calendar.CalendarEventsScope}
+	token := (&oauth2.Token{AccessToken: "access", RefreshToken: "refresh"}).WithExtra(map[string]interface{}{
+		"scope": strings.Join(want, " "),
+	})
+	if err := SaveToken(token); err != nil {
+		t.Fatalf("SaveToken() error = %v", err)
+	}
+
+	got, err := LoadToken()
+	if err != nil {
+		t.Fatalf("LoadToken() error = %v", err)
+	}
+	if diff := cmp.Diff(tokenScopes(got), want); diff != "" {
+		t.Errorf("LoadToken() scopes mismatch (-got +want):\n%s", diff)
+	}
+}
+
+func TestLoadToken_NoScopesRecorded(t *testing.T) {
+	// Not parallel: reads the token from the XDG data dir
+	_, dataDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+	createTestToken(t, dataDir, TokenStore{AccessToken: "legacy"})
+
+	got, err := LoadToken()
+	if err != nil {
+		t.Fatalf("LoadToken() error = %v", err)
+	}
+	if scopes := tokenScopes(got); len(scopes) != 0 {
+		t.Errorf("LoadToken() scopes = %v, want none", scopes)
+	}
+}
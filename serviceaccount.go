@@ -0,0 +1,87 @@
+// Package gcal provides service account authentication for server-side access to Google Calendar.
+package gcal
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+
+	"golang.org/x/oauth2/google"
+	"golang.org/x/oauth2/jwt"
+	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/option"
+)
+
+// NewClientWithServiceAccountJSON creates a Client authenticated as a service
+// account using the standard JSON key file downloaded from Google Cloud
+// Console. Scopes default to read-only calendar access.
+func NewClientWithServiceAccountJSON(ctx context.Context, jsonPath string, scopes []string) (*Client, error) {
+	data, err := os.ReadFile(jsonPath)
+	if err != nil {
+		return nil, fmt.Errorf("read service account key: %w", err)
+	}
+
+	config, err := google.JWTConfigFromJSON(data, defaultScopes(scopes)...)
+	if err != nil {
+		return nil, fmt.Errorf("parse service account key: %w", err)
+	}
+
+	return newServiceAccountClient(ctx, config)
+}
+
+// NewClientWithServiceAccountKey creates a Client authenticated as the
+// service account email using a PEM-encoded RSA private key. Scopes default
+// to read-only calendar access.
+func NewClientWithServiceAccountKey(ctx context.Context, email, pemPath string, scopes []string) (*Client, error) {
+	data, err := os.ReadFile(pemPath)
+	if err != nil {
+		return nil, fmt.Errorf("read service account key: %w", err)
+	}
+
+	config, err := serviceAccountConfigFromPEM(email, data, scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	return newServiceAccountClient(ctx, config)
+}
+
+// serviceAccountConfigFromPEM builds a JWT config for email, validating that
+// pemData holds an RSA private key in PKCS#8 or PKCS#1 form
+func serviceAccountConfigFromPEM(email string, pemData []byte, scopes []string) (*jwt.Config, error) {
+	if email == "" {
+		return nil, fmt.Errorf("service account email is required")
+	}
+
+	block, _ := pem.Decode(pemData)
+	if block == nil {
+		return nil, fmt.Errorf("parse service account key: no PEM block found")
+	}
+
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		if _, ok := key.(*rsa.PrivateKey); !ok {
+			return nil, fmt.Errorf("parse service account key: want an RSA private key, got %T", key)
+		}
+	} else if _, err := x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+		return nil, fmt.Errorf("parse service account key: not a PKCS#8 or PKCS#1 private key")
+	}
+
+	return &jwt.Config{
+		Email:      email,
+		PrivateKey: pemData,
+		Scopes:     defaultScopes(scopes),
+		TokenURL:   google.JWTTokenURL,
+	}, nil
+}
+
+// newServiceAccountClient creates a Client using a service account JWT config
+func newServiceAccountClient(ctx context.Context, config *jwt.Config) (*Client, error) {
+	srv, err := calendar.NewService(ctx, option.WithHTTPClient(config.Client(ctx)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create calendar service: %w", err)
+	}
+	return NewClientWithService(srv), nil
+}
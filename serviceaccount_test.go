@@ -0,0 +1,180 @@
+package gcal
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/calendar/v3"
+)
+
+// generateTestKeyPEM returns a freshly generated RSA key encoded as PEM
+func generateTestKeyPEM(t *testing.T, pkcs8 bool) []byte {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	if !pkcs8 {
+		return pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+func TestServiceAccountConfigFromPEM(t *testing.T) {
+	t.Parallel()
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate EC key: %v", err)
+	}
+	ecDER, err := x509.MarshalPKCS8PrivateKey(ecKey)
+	if err != nil {
+		t.Fatalf("Failed to marshal EC key: %v", err)
+	}
+	ecPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: ecDER})
+
+	tests := []struct {
+		name    string
+		email   string
+		pem     []byte
+		scopes  []string
+		wantErr bool
+	}{
+		{
+			name:  "PKCS#8 key",
+			email: "robot@project.iam.gserviceaccount.com",
+			pem:   generateTestKeyPEM(t, true),
+		},
+		{
+			name:   "PKCS#1 key with explicit scope",
+			email:  "robot@project.iam.gserviceaccount.com",
+			pem:    generateTestKeyPEM(t, false),
+			scopes: []string{calendar.CalendarScope},
+		},
+		{
+			name:    "missing email",
+			pem:     generateTestKeyPEM(t, true),
+			wantErr: true,
+		},
+		{
+			name:    "not PEM",
+			email:   "robot@project.iam.gserviceaccount.com",
+			pem:     []byte("not a key"),
+			wantErr: true,
+		},
+		{
+			name:    "garbage inside PEM block",
+			email:   "robot@project.iam.gserviceaccount.com",
+			pem:     pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte("garbage")}),
+			wantErr: true,
+		},
+		{
+			name:    "non-RSA key",
+			email:   "robot@project.iam.gserviceaccount.com",
+			pem:     ecPEM,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt // Capture loop variable
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := serviceAccountConfigFromPEM(tt.email, tt.pem, tt.scopes)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("serviceAccountConfigFromPEM() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if got.Email != tt.email {
+				t.Errorf("serviceAccountConfigFromPEM() Email = %v, want %v", got.Email, tt.email)
+			}
+			if got.TokenURL != google.JWTTokenURL {
+				t.Errorf("serviceAccountConfigFromPEM() TokenURL = %v, want %v", got.TokenURL, google.JWTTokenURL)
+			}
+			if diff := cmp.Diff(got.Scopes, defaultScopes(tt.scopes)); diff != "" {
+				t.Errorf("serviceAccountConfigFromPEM() Scopes mismatch (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNewClientWithServiceAccountKey(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(path, generateTestKeyPEM(t, true), 0600); err != nil {
+		t.Fatalf("Failed to write key: %v", err)
+	}
+
+	// Building the client doesn't contact Google; tokens are fetched lazily
+	client, err := NewClientWithServiceAccountKey(context.Background(), "robot@project.iam.gserviceaccount.com", path, nil)
+	if err != nil {
+		t.Fatalf("NewClientWithServiceAccountKey() error = %v", err)
+	}
+	if client == nil {
+		t.Fatal("NewClientWithServiceAccountKey() returned nil client")
+	}
+
+	if _, err := NewClientWithServiceAccountKey(context.Background(), "robot@project.iam.gserviceaccount.com", filepath.Join(t.TempDir(), "missing.pem"), nil); err == nil {
+		t.Error("NewClientWithServiceAccountKey() with missing file error = nil, want error")
+	}
+}
+
+func TestNewClientWithServiceAccountJSON(t *testing.T) {
+	t.Parallel()
+
+	key := map[string]string{
+		"type":           "service_account",
+		"project_id":     "test-project",
+		"private_key_id": "key-id",
+		"private_key":    string(generateTestKeyPEM(t, true)),
+		"client_email":   "robot@project.iam.gserviceaccount.com",
+		"client_id":      "1234567890",
+		"token_uri":      "https://oauth2.googleapis.com/token",
+	}
+	data, err := json.Marshal(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "key.json")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("Failed to write key: %v", err)
+	}
+
+	client, err := NewClientWithServiceAccountJSON(context.Background(), path, nil)
+	if err != nil {
+		t.Fatalf("NewClientWithServiceAccountJSON() error = %v", err)
+	}
+	if client == nil {
+		t.Fatal("NewClientWithServiceAccountJSON() returned nil client")
+	}
+
+	badPath := filepath.Join(t.TempDir(), "bad.json")
+	if err := os.WriteFile(badPath, []byte("{"), 0600); err != nil {
+		t.Fatalf("Failed to write key: %v", err)
+	}
+	if _, err := NewClientWithServiceAccountJSON(context.Background(), badPath, nil); err == nil {
+		t.Error("NewClientWithServiceAccountJSON() with invalid JSON error = nil, want error")
+	}
+}
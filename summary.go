@@ -0,0 +1,87 @@
+// Package gcal provides per-day summaries of calendar load.
+package gcal
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Summary aggregates one day's meetings
+type Summary struct {
+	MeetingCount int `json:"meetingCount"`
+	BusyMinutes  int `json:"busyMinutes"` // overlapping meetings counted once
+	Conflicts    int `json:"conflicts"`   // meetings that overlap another
+}
+
+// WeeklySummary summarizes the seven days starting at weekStart using the
+// default client. See Client.WeeklySummary.
+func WeeklySummary(ctx context.Context, calendarIDs []string, weekStart time.Time) (map[string]Summary, error) {
+	c, code, err := newDefaultClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", code, err)
+	}
+	return c.WeeklySummary(ctx, calendarIDs, weekStart)
+}
+
+// WeeklySummary returns a Summary for each of the seven days starting on
+// weekStart's date, keyed by date ("2006-01-02") in the configured
+// timezone. Days without meetings have a zero Summary. The days are fetched
+// concurrently; if any fetch fails, the first failure is returned.
+func (c *Client) WeeklySummary(ctx context.Context, calendarIDs []string, weekStart time.Time) (map[string]Summary, error) {
+	loc := c.Options.location()
+	start := weekStart.In(loc)
+	start = time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, loc)
+
+	summaries := make([]Summary, 7)
+	errs := make([]error, 7)
+	var wg sync.WaitGroup
+	for i := 0; i < 7; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			dayStart := start.AddDate(0, 0, i)
+			dayEnd := dayStart.AddDate(0, 0, 1)
+			resp := c.fetchEvents(ctx, calendarIDs, dayStart, dayEnd)
+			if !resp.Success {
+				errs[i] = fmt.Errorf("%s: %s: %s", resp.Error, dayStart.Format(allDayLayout), resp.Message)
+				return
+			}
+			summaries[i] = summarizeDay(resp.Events, dayStart, dayEnd, loc)
+		}(i)
+	}
+	wg.Wait()
+
+	week := make(map[string]Summary, 7)
+	for i, s := range summaries {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		week[start.AddDate(0, 0, i).Format(allDayLayout)] = s
+	}
+	return week, nil
+}
+
+// summarizeDay builds the Summary of events within [dayStart, dayEnd).
+// Only timed meetings count: all-day events, out-of-office blocks and deleted
+// tombstones are skipped, as in conflict and gap detection. Busy time outside
+// the day, from meetings spanning midnight, is excluded.
+func summarizeDay(events []Event, dayStart, dayEnd time.Time, loc *time.Location) Summary {
+	var s Summary
+	var meetings []Event
+	for _, e := range events {
+		if e.Deleted || isAllDay(e) || isOutOfOffice(e) {
+			continue
+		}
+		meetings = append(meetings, e)
+		if e.HasConflict {
+			s.Conflicts++
+		}
+	}
+
+	s.MeetingCount = len(meetings)
+	s.BusyMinutes = int(busyWithin(meetings, dayStart, dayEnd, loc) / time.Minute)
+	return s
+}
@@ -0,0 +1,125 @@
+package gcal
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/api/calendar/v3"
+)
+
+func TestWeeklySummary(t *testing.T) {
+	t.Parallel()
+	weekStart := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	monday := weekStart.Add(9 * time.Hour)
+	wednesday := weekStart.AddDate(0, 0, 2).Add(14 * time.Hour)
+
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		timeMin, err := time.Parse(time.RFC3339, r.URL.Query().Get("timeMin"))
+		if err != nil {
+			t.Errorf("invalid timeMin: %v", err)
+		}
+
+		var items []*calendar.Event
+		switch timeMin.Format(allDayLayout) {
+		case "2024-01-15":
+			// Two overlapping meetings: 9:00-10:00 and 9:30-10:30
+			items = []*calendar.Event{
+				testAcceptedEvent("standup", monday, monday.Add(time.Hour)),
+				testAcceptedEvent("sync", monday.Add(30*time.Minute), monday.Add(90*time.Minute)),
+			}
+		case "2024-01-17":
+			items = []*calendar.Event{
+				testAcceptedEvent("review", wednesday, wednesday.Add(45*time.Minute)),
+			}
+		}
+		writeTestJSON(t, w, &calendar.Events{Items: items})
+	}))
+	client.Options.Location = time.UTC
+
+	got, err := client.WeeklySummary(context.Background(), nil, weekStart.Add(15*time.Hour))
+	if err != nil {
+		t.Fatalf("WeeklySummary() error = %v", err)
+	}
+
+	want := map[string]Summary{
+		"2024-01-15": {MeetingCount: 2, BusyMinutes: 90, Conflicts: 2},
+		"2024-01-16": {},
+		"2024-01-17": {MeetingCount: 1, BusyMinutes: 45},
+		"2024-01-18": {},
+		"2024-01-19": {},
+		"2024-01-20": {},
+		"2024-01-21": {},
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("WeeklySummary() mismatch (-got +want):\n%s", diff)
+	}
+}
+
+func TestWeeklySummary_Error(t *testing.T) {
+	t.Parallel()
+
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeTestAPIError(w, http.StatusInternalServerError, "backend error")
+	}))
+
+	if _, err := client.WeeklySummary(context.Background(), []string{"team@example.com"}, time.Now()); err == nil {
+		t.Error("WeeklySummary() error = nil, want error")
+	}
+}
+
+func TestSummarizeDay(t *testing.T) {
+	t.Parallel()
+	dayStart := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	dayEnd := dayStart.AddDate(0, 0, 1)
+	at := func(h int) string { return dayStart.Add(time.Duration(h) * time.Hour).Format(time.RFC3339) }
+
+	tests := []struct {
+		name   string
+		events []Event
+		want   Summary
+	}{
+		{
+			name:   "timed meeting",
+			events: []Event{{ID: "1", Start: at(9), End: at(10)}},
+			want:   Summary{MeetingCount: 1, BusyMinutes: 60},
+		},
+		{
+			name: "all-day event skipped",
+			events: []Event{
+				{ID: "1", Start: "2024-01-15", End: "2024-01-16", AllDay: true},
+				{ID: "2", Start: at(9), End: at(10)},
+			},
+			want: Summary{MeetingCount: 1, BusyMinutes: 60},
+		},
+		{
+			name: "out-of-office block skipped",
+			events: []Event{
+				{ID: "1", Start: at(12), End: at(17), EventType: eventTypeOutOfOffice},
+				{ID: "2", Start: at(9), End: at(10)},
+			},
+			want: Summary{MeetingCount: 1, BusyMinutes: 60},
+		},
+		{
+			name: "deleted tombstone skipped",
+			events: []Event{
+				{ID: "1", Deleted: true},
+				{ID: "2", Start: at(9), End: at(10)},
+			},
+			want: Summary{MeetingCount: 1, BusyMinutes: 60},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt // Capture loop variable
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got := summarizeDay(tt.events, dayStart, dayEnd, time.UTC)
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("summarizeDay() mismatch (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
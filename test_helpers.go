@@ -3,10 +3,18 @@
 package gcal
 
 import (
+	"context"
 	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/option"
 )
 
 // setupTestEnv configures XDG environment variables for testing
@@ -88,3 +96,78 @@ func createTestToken(t *testing.T, dataDir string, store TokenStore) string {
 
 	return path
 }
+
+// newTestClient creates a Client whose Calendar service talks to an
+// httptest server running handler
+func newTestClient(t *testing.T, handler http.Handler) *Client {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	srv, err := calendar.NewService(context.Background(),
+		option.WithHTTPClient(server.Client()),
+		option.WithEndpoint(server.URL+"/"),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create calendar service: %v", err)
+	}
+
+	return NewClientWithService(srv)
+}
+
+// writeTestJSON writes v as a JSON response body
+func writeTestJSON(t *testing.T, w http.ResponseWriter, v interface{}) {
+	t.Helper()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		t.Errorf("Failed to write JSON response: %v", err)
+	}
+}
+
+// writeTestAPIError writes a Google API style error response
+func writeTestAPIError(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":    code,
+			"message": message,
+		},
+	})
+}
+
+// testAcceptedEvent builds an API event that convertEvent keeps: timed,
+// accepted by the user, with one other attendee
+func testAcceptedEvent(id string, start, end time.Time) *calendar.Event {
+	return &calendar.Event{
+		Id:      id,
+		Summary: id,
+		Start:   &calendar.EventDateTime{DateTime: start.Format(time.RFC3339)},
+		End:     &calendar.EventDateTime{DateTime: end.Format(time.RFC3339)},
+		Attendees: []*calendar.EventAttendee{
+			{Self: true, ResponseStatus: responseStatusAccepted},
+			{Email: "alice@example.com", DisplayName: "Alice"},
+		},
+	}
+}
+
+// newTestTokenServer starts an OAuth token endpoint that answers every
+// refresh with accessToken, counting the requests it receives
+func newTestTokenServer(t *testing.T, accessToken string) (*httptest.Server, *int32) {
+	t.Helper()
+
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		writeTestJSON(t, w, map[string]interface{}{
+			"access_token": accessToken,
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &requests
+}
@@ -0,0 +1,110 @@
+// Package gcal provides passphrase encryption of the saved token file.
+package gcal
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/scrypt"
+)
+
+// TokenPassphraseEnv names the environment variable holding the passphrase
+// used to encrypt token files. When unset, tokens are stored as plain JSON.
+const TokenPassphraseEnv = "GCAL_TOKEN_PASSPHRASE"
+
+// encryptedTokenFormat identifies encrypted token files
+const encryptedTokenFormat = "gcal-secretbox-v1"
+
+// scrypt parameters recommended for interactive logins
+const (
+	scryptN      = 1 << 15
+	scryptR      = 8
+	scryptP      = 1
+	scryptKeyLen = 32
+	saltLen      = 16
+	nonceLen     = 24
+)
+
+// encryptedToken is the on-disk form of an encrypted token
+type encryptedToken struct {
+	Format     string `json:"format"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// tokenPassphrase returns the configured passphrase, or "" for plain files
+func tokenPassphrase() string {
+	return os.Getenv(TokenPassphraseEnv)
+}
+
+// deriveTokenKey derives a secretbox key from passphrase and salt
+func deriveTokenKey(passphrase string, salt []byte) (*[32]byte, error) {
+	derived, err := scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, scryptKeyLen)
+	if err != nil {
+		return nil, fmt.Errorf("derive token key: %w", err)
+	}
+	var key [32]byte
+	copy(key[:], derived)
+	return &key, nil
+}
+
+// encryptToken seals plaintext under passphrase with a fresh salt and nonce
+func encryptToken(plaintext []byte, passphrase string) ([]byte, error) {
+	salt := make([]byte, saltLen)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, fmt.Errorf("generate salt: %w", err)
+	}
+	var nonce [nonceLen]byte
+	if _, err := io.ReadFull(rand.Reader, nonce[:]); err != nil {
+		return nil, fmt.Errorf("generate nonce: %w", err)
+	}
+
+	key, err := deriveTokenKey(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.MarshalIndent(encryptedToken{
+		Format:     encryptedTokenFormat,
+		Salt:       salt,
+		Nonce:      nonce[:],
+		Ciphertext: secretbox.Seal(nil, plaintext, &nonce, key),
+	}, "", "  ")
+}
+
+// decryptToken returns the plaintext of a token file. Plain files are
+// returned unchanged, so setting a passphrase doesn't lock out an existing
+// token; it is encrypted on the next save.
+func decryptToken(data []byte, passphrase string) ([]byte, error) {
+	var enc encryptedToken
+	if err := json.Unmarshal(data, &enc); err != nil || enc.Format == "" {
+		return data, nil
+	}
+	if enc.Format != encryptedTokenFormat {
+		return nil, fmt.Errorf("unsupported token format %q", enc.Format)
+	}
+	if passphrase == "" {
+		return nil, fmt.Errorf("token is encrypted - set %s to decrypt it", TokenPassphraseEnv)
+	}
+	if len(enc.Nonce) != nonceLen {
+		return nil, fmt.Errorf("decrypt token: invalid nonce")
+	}
+
+	key, err := deriveTokenKey(passphrase, enc.Salt)
+	if err != nil {
+		return nil, err
+	}
+	var nonce [nonceLen]byte
+	copy(nonce[:], enc.Nonce)
+
+	plaintext, ok := secretbox.Open(nil, enc.Ciphertext, &nonce, key)
+	if !ok {
+		return nil, fmt.Errorf("decrypt token: wrong passphrase or corrupted file")
+	}
+	return plaintext, nil
+}
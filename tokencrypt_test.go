@@ -0,0 +1,95 @@
+package gcal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+func TestSaveToken_EncryptedRoundTrip(t *testing.T) {
+	// Not parallel: sets GCAL_TOKEN_PASSPHRASE and the XDG dirs
+	_, dataDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+	t.Setenv(TokenPassphraseEnv, "correct horse battery staple")
+
+	want := &oauth2.Token{
+		AccessToken:  "secret-access-token",
+		RefreshToken: "secret-refresh-token",
+		TokenType:    "Bearer",
+		Expiry:       time.Date(2024, 1, 15, 14, 0, 0, 0, time.UTC),
+	}
+	if err := SaveToken(want); err != nil {
+		t.Fatalf("SaveToken() error = %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dataDir, tokenFile))
+	if err != nil {
+		t.Fatalf("Failed to read token file: %v", err)
+	}
+	if strings.Contains(string(data), "secret-refresh-token") {
+		t.Errorf("token file contains the refresh token in plain text:\n%s", data)
+	}
+
+	got, err := LoadToken()
+	if err != nil {
+		t.Fatalf("LoadToken() error = %v", err)
+	}
+	if got.AccessToken != want.AccessToken || got.RefreshToken != want.RefreshToken || !got.Expiry.Equal(want.Expiry) {
+		t.Errorf("LoadToken() = %+v, want %+v", got, want)
+	}
+}
+
+func TestLoadToken_EncryptedFailures(t *testing.T) {
+	tests := []struct {
+		name       string
+		passphrase string
+		wantErr    string
+	}{
+		{name: "wrong passphrase", passphrase: "wrong", wantErr: "wrong passphrase"},
+		{name: "no passphrase", passphrase: "", wantErr: TokenPassphraseEnv},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Not parallel: sets GCAL_TOKEN_PASSPHRASE and the XDG dirs
+			_, _, cleanup := setupTestEnv(t)
+			defer cleanup()
+
+			t.Setenv(TokenPassphraseEnv, "right")
+			if err := SaveToken(&oauth2.Token{AccessToken: "access", RefreshToken: "refresh"}); err != nil {
+				t.Fatalf("SaveToken() error = %v", err)
+			}
+
+			t.Setenv(TokenPassphraseEnv, tt.passphrase)
+			token, err := LoadToken()
+			if err == nil {
+				t.Fatalf("LoadToken() = %+v, want error", token)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("LoadToken() error = %v, want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadToken_PlainFileWithPassphrase(t *testing.T) {
+	// Not parallel: sets GCAL_TOKEN_PASSPHRASE and the XDG dirs
+	_, dataDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	createTestToken(t, dataDir, TokenStore{AccessToken: "plain-access", RefreshToken: "plain-refresh"})
+	t.Setenv(TokenPassphraseEnv, "new passphrase")
+
+	// Existing plain tokens stay readable after a passphrase is configured
+	got, err := LoadToken()
+	if err != nil {
+		t.Fatalf("LoadToken() error = %v", err)
+	}
+	if got.AccessToken != "plain-access" {
+		t.Errorf("LoadToken() AccessToken = %q, want plain-access", got.AccessToken)
+	}
+}
@@ -1,19 +1,392 @@
 // Package gcal defines types and response structures for the Google Calendar CLI.
 package gcal
 
-import "time"
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
 
 // Event represents a calendar event for JSON output
 type Event struct {
 	ID             string   `json:"id"`
+	RawID          string   `json:"rawId,omitempty"` // Google's event ID; ID holds the same value today
 	Title          string   `json:"title"`
+	Description    string   `json:"description,omitempty"`
+	Location       string   `json:"location,omitempty"`
 	Start          string   `json:"start"` // ISO8601
 	End            string   `json:"end"`   // ISO8601
 	Attendees      []string `json:"attendees"`
 	AttendeeCount  int      `json:"attendeeCount"`
 	MeetingURL     string   `json:"meetingUrl,omitempty"`
+	Provider       string   `json:"provider,omitempty"` // meeting provider, see MeetingProvider
 	HasConflict    bool     `json:"hasConflict"`
 	ResponseStatus string   `json:"responseStatus"`
+	Status         string   `json:"status,omitempty"`       // "confirmed" or "tentative", the event's own status rather than the RSVP
+	CalendarID     string   `json:"calendarId,omitempty"`   // source calendar
+	CalendarName   string   `json:"calendarName,omitempty"` // see FetchOptions.IncludeCalendarNames
+	ICalUID        string   `json:"iCalUID,omitempty"`      // stable across calendars and recurrences
+	Deleted        bool     `json:"deleted,omitempty"`      // tombstone, see FetchOptions.ShowDeleted
+
+	RecurringEventID string `json:"recurringEventId,omitempty"` // series ID, empty for one-off events
+	OccurrenceCount  int    `json:"occurrenceCount,omitempty"`  // see FetchOptions.CollapseRecurring
+
+	AllDay bool `json:"allDay,omitempty"` // Start and End are bare dates, see FetchOptions.IncludeAllDay
+	Marker bool `json:"marker,omitempty"` // starts and ends at the same instant, see FetchOptions.ZeroDurationMarkers
+
+	// BusyPlaceholder marks a busy block from a calendar shared with
+	// free/busy access only. Only its times are known; Title is "Busy".
+	BusyPlaceholder bool `json:"busyPlaceholder,omitempty"`
+
+	EventType string `json:"eventType,omitempty"` // "outOfOffice" for out-of-office blocks, otherwise empty
+	DuringOOO bool   `json:"duringOOO,omitempty"` // overlaps an out-of-office block, see FetchOptions.IncludeOutOfOffice
+
+	ConflictsWith []string `json:"conflictsWith,omitempty"` // IDs of the events that set HasConflict, in start order
+
+	// GapBeforeMinutes is the free time since the previous event ended:
+	// 0 when back-to-back or overlapping. It is -1 for the first timed
+	// event, and for all-day events and events whose times can't be parsed
+	// or end before they start, which are also skipped when measuring the
+	// gaps of later events.
+	GapBeforeMinutes int `json:"gapBeforeMinutes"`
+
+	SharedProperties map[string]string `json:"sharedProperties,omitempty"` // extended properties shared with attendees
+
+	AttendeeDetails []Attendee `json:"attendeeDetails,omitempty"` // other attendees, parallel to Attendees
+	SelfComment     string     `json:"selfComment,omitempty"`     // note left with the user's own RSVP
+
+	Reminders []Reminder `json:"reminders,omitempty"` // reminder overrides; empty when the calendar default applies
+
+	LocationInfo *LocationInfo `json:"locationInfo,omitempty"` // parsed from the event's location, see ParseLocation
+
+	RemovedFromMeeting bool `json:"removedFromMeeting,omitempty"` // see FetchOptions.IncludeRemovedMeetings
+
+	InternalMeeting bool `json:"internalMeeting,omitempty"` // meeting URL needs internal access, see FetchOptions.InternalMeetingDomains
+
+	AllMeetingURLs []string `json:"allMeetingUrls,omitempty"` // every distinct meeting URL, MeetingURL first
+
+	Organizer      string `json:"organizer,omitempty"`      // organizer's display name, or email if unnamed
+	OrganizerEmail string `json:"organizerEmail,omitempty"` // empty for events without an organizer
+	OrganizedByMe  bool   `json:"organizedByMe,omitempty"`
+
+	DuringLunch bool `json:"duringLunch,omitempty"` // overlaps the lunch window, see FetchOptions.FlagLunchMeetings
+
+	// Set by GetNextEvent
+	MinutesUntilStart int  `json:"minutesUntilStart,omitempty"`
+	IsSoon            bool `json:"isSoon,omitempty"`
+}
+
+// Attendee holds the details of one event attendee
+type Attendee struct {
+	Name           string `json:"name,omitempty"`
+	Email          string `json:"email,omitempty"`
+	ResponseStatus string `json:"responseStatus,omitempty"`
+	Comment        string `json:"comment,omitempty"` // note left with the RSVP, e.g. why they declined
+}
+
+// Reminder is an event reminder override
+type Reminder struct {
+	Method  string `json:"method"`  // "popup" or "email"
+	Minutes int    `json:"minutes"` // minutes before the event starts
+}
+
+// FetchOptions controls how fetched events are filtered and annotated
+type FetchOptions struct {
+	// ConflictsWithinCalendarOnly only flags conflicts between events from the
+	// same calendar, so mirrored events across calendars don't alarm
+	ConflictsWithinCalendarOnly bool
+
+	// Location is the timezone used for day boundaries and for anchoring
+	// all-day dates. Defaults to the local timezone.
+	Location *time.Location
+
+	// SharedExtendedPropertyFilters restricts results to events with matching
+	// shared extended properties, each formatted as "name=value"
+	SharedExtendedPropertyFilters []string
+
+	// AnonymizeCalendarIDs replaces each event's CalendarID with a stable
+	// hash like "cal-1a2b3c4d", so debug output can be shared without
+	// exposing calendar email addresses
+	AnonymizeCalendarIDs bool
+
+	// ModifiedBy keeps only events last modified by this email address
+	// (case-insensitive). The Calendar API doesn't report an event's last
+	// modifier, so this matches the organizer as an approximation; edits by
+	// attendees with modify rights are not detected.
+	ModifiedBy string
+
+	// AllDayNeverConflicts stops all-day events (birthdays, "Sprint week")
+	// from marking overlapping events as conflicts. DefaultFetchOptions
+	// enables it.
+	AllDayNeverConflicts bool
+
+	// MaxAttendeesFetched asks the API to return at most this many attendees
+	// per event, shrinking responses for events with huge guest lists. Zero
+	// means no limit. The API doesn't report the full total, so Attendees
+	// and AttendeeCount reflect the trimmed list; leave this unset, or set
+	// it high, if accurate counts matter.
+	MaxAttendeesFetched int
+
+	// ShowDeleted includes cancelled events as tombstones: Events with
+	// Deleted set and only ID, RawID, ICalUID and CalendarID populated, so
+	// a local mirror can remove them
+	ShowDeleted bool
+
+	// IncludeRemovedMeetings keeps events the organizer removed the user
+	// from, which can linger on the calendar, flagging them with
+	// RemovedFromMeeting. Detection relies on the user's own attendee entry,
+	// so on calendars other than the user's every meeting is flagged; use it
+	// with the primary calendar.
+	IncludeRemovedMeetings bool
+
+	// ICalUIDFilter restricts results to events with this iCalendar UID,
+	// finding the same meeting across calendars
+	ICalUIDFilter string
+
+	// StrictCalendarIDs makes fetches fail with ErrNotConfigured when no
+	// calendar IDs are given, instead of silently using the primary calendar
+	StrictCalendarIDs bool
+
+	// ExcludeTitlePatterns drops events whose title matches any pattern,
+	// hiding recurring noise such as lunch or focus blocks. Nil patterns
+	// are ignored.
+	ExcludeTitlePatterns []*regexp.Regexp
+
+	// IncludeTitlePatterns, when non-empty, keeps only events whose title
+	// matches at least one pattern, e.g. to show only interviews.
+	// ExcludeTitlePatterns still wins when both match. Nil patterns are
+	// ignored.
+	IncludeTitlePatterns []*regexp.Regexp
+
+	// CollapseRecurring keeps only the earliest fetched instance of each
+	// recurring series, setting its OccurrenceCount to the number of
+	// instances in the window, so a week of standups shows up once.
+	// Conflicts and gaps are still computed across every instance.
+	CollapseRecurring bool
+
+	// IncludeOutOfOffice keeps out-of-office blocks, with EventType
+	// "outOfOffice", and sets DuringOOO on timed events that overlap one,
+	// since meetings booked while away are likely mistakes. Such overlaps
+	// don't count as conflicts.
+	IncludeOutOfOffice bool
+
+	// RequireMeetingURL drops meetings without a detected meeting URL, such
+	// as in-person meetings, leaving only ones that can be joined remotely.
+	// Out-of-office blocks kept by IncludeOutOfOffice are unaffected.
+	RequireMeetingURL bool
+
+	// Descending returns events latest first, for history views.
+	// GapBeforeMinutes still measures from the chronologically previous
+	// event.
+	Descending bool
+
+	// IncludeCalendarNames sets each event's CalendarName to its source
+	// calendar's display name, looked up with one calendar list request per
+	// fetch. Names are left empty if the lookup fails.
+	IncludeCalendarNames bool
+
+	// RecurringMasters lists each recurring series once, as its master
+	// event, instead of expanding it into instances (the API's
+	// singleEvents=false). It can't be combined with OrderByStartTime.
+	RecurringMasters bool
+
+	// OrderBy is the order the API returns events in: OrderByStartTime or
+	// OrderByUpdated. Empty uses OrderByStartTime, or the API's unspecified
+	// order with RecurringMasters. Results are still sorted by start time
+	// before they're returned.
+	OrderBy string
+
+	// InternalMeetingDomains lists domains, such as "vc.corp.example.com",
+	// whose meeting links are only reachable on the internal network.
+	// Events whose MeetingURL host is one of them, or a subdomain, get
+	// InternalMeeting set so remote users know to connect to the VPN. A
+	// leading "*." is accepted and ignored.
+	InternalMeetingDomains []string
+
+	// IncludeAllDay keeps all-day events, such as vacations and holidays,
+	// with AllDay set and Start and End as bare dates ("2006-01-02", End
+	// exclusive). Unlike timed events they are kept without attendees. They
+	// are anchored to midnight in Location when compared with timed events,
+	// and don't count toward GapBeforeMinutes.
+	IncludeAllDay bool
+
+	// IncludeDeclined, IncludeTentative and IncludeNeedsAction keep
+	// meetings the user declined, RSVPed maybe to, or hasn't answered,
+	// alongside accepted ones. ResponseStatus tells them apart. By default
+	// only accepted meetings are kept.
+	IncludeDeclined    bool
+	IncludeTentative   bool
+	IncludeNeedsAction bool
+
+	// IncludePersonal keeps events without other attendees, such as focus
+	// time, reminders and holds, which are otherwise dropped. They need no
+	// RSVP, but ones the user declined are still dropped unless
+	// IncludeDeclined is set.
+	IncludePersonal bool
+
+	// TreatAsResourceCalendar keeps every booking on the fetched calendars,
+	// with or without attendees and whatever the user's RSVP, for room and
+	// other resource calendars (IDs ending "@resource.calendar.google.com")
+	// where the user isn't a guest. It applies to every calendar in the
+	// fetch, so fetch resource calendars with a Client of their own.
+	TreatAsResourceCalendar bool
+
+	// ExcludeTentative drops events whose own Status is "tentative", as
+	// opposed to ones the user RSVPed maybe to, keeping only confirmed
+	// events
+	ExcludeTentative bool
+
+	// ZeroDurationMarkers treats timed events that start and end at the
+	// same instant, which some integrations create as reminders, as
+	// point-in-time markers: they get Marker set and never conflict with
+	// other events
+	ZeroDurationMarkers bool
+
+	// FlagLunchMeetings sets DuringLunch on timed events overlapping the
+	// lunch window on any day they span, in Location, as a nudge to keep
+	// lunch free
+	FlagLunchMeetings bool
+
+	// LunchStart and LunchEnd are the lunch window's clock times, e.g.
+	// 12 * time.Hour. Leaving both zero uses 12:00 to 13:00, and leaving
+	// one zero makes the window an hour long. A window that doesn't end
+	// after it starts fails the fetch with ErrInvalidConfig.
+	LunchStart time.Duration
+	LunchEnd   time.Duration
+}
+
+// Default lunch window for FetchOptions.FlagLunchMeetings
+const (
+	defaultLunchStart = 12 * time.Hour
+	defaultLunchEnd   = 13 * time.Hour
+)
+
+// Event orderings for FetchOptions.OrderBy
+const (
+	OrderByStartTime = "startTime"
+	OrderByUpdated   = "updated"
+)
+
+// DefaultFetchOptions returns the options used by new Clients
+func DefaultFetchOptions() FetchOptions {
+	return FetchOptions{AllDayNeverConflicts: true}
+}
+
+// listOrder returns the orderBy and singleEvents parameters for event list
+// calls, or an error if the options ask for a combination the API rejects
+func (o FetchOptions) listOrder() (orderBy string, singleEvents bool, err error) {
+	singleEvents = !o.RecurringMasters
+	switch o.OrderBy {
+	case "":
+		if singleEvents {
+			orderBy = OrderByStartTime
+		}
+	case OrderByStartTime:
+		if !singleEvents {
+			// The API only orders expanded instances by start time, and
+			// otherwise fails with a bare "Bad Request"
+			return "", false, fmt.Errorf("OrderBy %q requires expanded instances - unset RecurringMasters or use %q",
+				OrderByStartTime, OrderByUpdated)
+		}
+		orderBy = OrderByStartTime
+	case OrderByUpdated:
+		orderBy = OrderByUpdated
+	default:
+		return "", false, fmt.Errorf("unknown OrderBy %q, want %q or %q",
+			o.OrderBy, OrderByStartTime, OrderByUpdated)
+	}
+	return orderBy, singleEvents, nil
+}
+
+// isInternalMeetingURL reports whether rawURL's host is in one of the
+// InternalMeetingDomains
+func (o FetchOptions) isInternalMeetingURL(rawURL string) bool {
+	if rawURL == "" || len(o.InternalMeetingDomains) == 0 {
+		return false
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, domain := range o.InternalMeetingDomains {
+		domain = strings.ToLower(strings.TrimPrefix(domain, "*."))
+		if domain != "" && (host == domain || strings.HasSuffix(host, "."+domain)) {
+			return true
+		}
+	}
+	return false
+}
+
+// keepResponseStatus reports whether a meeting with the user's RSVP status
+// passes the response filters
+func (o FetchOptions) keepResponseStatus(status string) bool {
+	switch status {
+	case responseStatusAccepted:
+		return true
+	case responseStatusDeclined:
+		return o.IncludeDeclined
+	case responseStatusTentative:
+		return o.IncludeTentative
+	case responseStatusNeedsAction:
+		return o.IncludeNeedsAction
+	}
+	return false
+}
+
+// keepTitle reports whether an event with title passes the title filters
+func (o FetchOptions) keepTitle(title string) bool {
+	for _, re := range o.ExcludeTitlePatterns {
+		if re != nil && re.MatchString(title) {
+			return false
+		}
+	}
+	if len(o.IncludeTitlePatterns) == 0 {
+		return true
+	}
+	for _, re := range o.IncludeTitlePatterns {
+		if re != nil && re.MatchString(title) {
+			return true
+		}
+	}
+	return false
+}
+
+// lunchWindow returns the lunch window's clock times, defaulting to
+// 12:00 to 13:00 when neither is set and to an hour-long window when only
+// one is
+func (o FetchOptions) lunchWindow() (start, end time.Duration) {
+	switch {
+	case o.LunchStart == 0 && o.LunchEnd == 0:
+		return defaultLunchStart, defaultLunchEnd
+	case o.LunchEnd == 0:
+		return o.LunchStart, o.LunchStart + defaultLunchEnd - defaultLunchStart
+	case o.LunchStart == 0:
+		return o.LunchEnd - (defaultLunchEnd - defaultLunchStart), o.LunchEnd
+	}
+	return o.LunchStart, o.LunchEnd
+}
+
+// validateLunchWindow checks the lunch window, when flagging is on, ends
+// after it starts
+func (o FetchOptions) validateLunchWindow() error {
+	if !o.FlagLunchMeetings {
+		return nil
+	}
+	if start, end := o.lunchWindow(); end <= start {
+		return fmt.Errorf("lunch window must end after it starts, got %v to %v", start, end)
+	}
+	return nil
+}
+
+// location returns the configured timezone, defaulting to local time
+func (o FetchOptions) location() *time.Location {
+	if o.Location == nil {
+		return time.Local
+	}
+	return o.Location
 }
 
 // Response is the JSON output for gcal events
@@ -31,6 +404,7 @@ type TokenStore struct {
 	RefreshToken string    `json:"refresh_token"`
 	TokenType    string    `json:"token_type"`
 	Expiry       time.Time `json:"expiry"`
+	Scopes       []string  `json:"scopes,omitempty"` // granted scopes, when known
 }
 
 // Credentials holds OAuth client credentials
@@ -41,9 +415,45 @@ type Credentials struct {
 
 // CalendarInfo represents a calendar for listing
 type CalendarInfo struct {
-	ID      string `json:"id"`
-	Summary string `json:"summary"`
-	Primary bool   `json:"primary"`
+	ID              string `json:"id"`
+	Summary         string `json:"summary"`                   // canonical calendar name
+	SummaryOverride string `json:"summaryOverride,omitempty"` // user's custom name for a shared calendar
+	Description     string `json:"description,omitempty"`
+	Primary         bool   `json:"primary"`
+}
+
+// CalendarSort selects the order ListCalendars returns calendars in
+type CalendarSort int
+
+const (
+	// CalendarSortAPI keeps the order returned by the Calendar API
+	CalendarSortAPI CalendarSort = iota
+	// CalendarSortName orders calendars by DisplayName, ignoring case
+	CalendarSortName
+)
+
+// CalendarListOptions controls the calendars returned by ListCalendars
+type CalendarListOptions struct {
+	// SortBy orders the calendars
+	SortBy CalendarSort
+
+	// PrimaryFirst moves the primary calendar to the top regardless of SortBy
+	PrimaryFirst bool
+}
+
+// DefaultCalendarListOptions returns the options used by new Clients: the
+// primary calendar first, then the rest alphabetically
+func DefaultCalendarListOptions() CalendarListOptions {
+	return CalendarListOptions{SortBy: CalendarSortName, PrimaryFirst: true}
+}
+
+// DisplayName returns the name to show for the calendar, preferring the
+// user's override over the canonical summary
+func (c CalendarInfo) DisplayName() string {
+	if c.SummaryOverride != "" {
+		return c.SummaryOverride
+	}
+	return c.Summary
 }
 
 // CalendarsResponse is the JSON output for gcal calendars
@@ -60,6 +470,8 @@ const (
 	ErrTokenExpired  = "token_expired"
 	ErrNetworkError  = "network_error"
 	ErrAPIError      = "api_error"
+	ErrInvalidConfig = "invalid_config"
+	ErrEventNotFound = "event_not_found"
 )
 
 // NewErrorResponse creates a structured error response